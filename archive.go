@@ -0,0 +1,598 @@
+package rbxfetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anaminus/iofl"
+)
+
+// ArchiveIndexFile is the name of the file at the root of an archive that
+// lists the builds within the archive.
+const ArchiveIndexFile = "index.json"
+
+// ArchiveBuildsDir is the directory of an archive that contains a directory
+// for each build.
+const ArchiveBuildsDir = "builds"
+
+// ArchiveEntry describes a build within an archive.
+type ArchiveEntry struct {
+	Build Build
+	// Path is the directory of the build, relative to the root of the
+	// archive, with slash separators.
+	Path string
+	// Artifacts lists the file names of the artifacts stored for the build,
+	// in sorted order.
+	Artifacts []string
+}
+
+// ArchiveIndex is the content of the index file of an archive.
+type ArchiveIndex struct {
+	// Builds lists each build in the archive, ordered by date, then GUID.
+	Builds []ArchiveEntry
+}
+
+// Entry returns the entry of the given GUID. GUIDs are compared
+// case-insensitively.
+func (index ArchiveIndex) Entry(guid string) (entry ArchiveEntry, ok bool) {
+	for _, e := range index.Builds {
+		if strings.EqualFold(e.Build.GUID, guid) {
+			return e, true
+		}
+	}
+	return entry, false
+}
+
+// archivePath returns the directory of build within an archive, relative to
+// the root, with slash separators. The directory is named
+// "<version>-<guid>", or only the GUID if the version is unknown.
+func archivePath(build Build) string {
+	name := build.GUID
+	if !build.Version.Empty() {
+		name = build.Version.String() + "-" + build.GUID
+	}
+	return ArchiveBuildsDir + "/" + name
+}
+
+// ArchiveWriter saves the artifacts of builds into a directory with a
+// standard layout:
+//
+//	index.json
+//	builds/<version>-<guid>/API-Dump.json
+//	builds/<version>-<guid>/ReflectionMetadata.xml
+//	builds/<version>-<guid>/ClassImages.png
+//
+// The index lists each build and its artifacts, as an ArchiveIndex encoded as
+// JSON. Files are written atomically, so that an interrupted write does not
+// corrupt the archive. An ArchiveWriter may be used concurrently.
+type ArchiveWriter struct {
+	// Client fetches the artifacts.
+	Client *Client
+	// Dir is the root directory of the archive.
+	Dir string
+	// Artifacts specifies the artifacts that are saved. If nil, then
+	// DefaultArtifacts is used.
+	Artifacts []Artifact
+
+	mu    sync.Mutex
+	index map[string]*ArchiveEntry
+}
+
+// NewArchiveWriter returns an ArchiveWriter that saves the artifacts fetched
+// by client into dir.
+func NewArchiveWriter(client *Client, dir string) *ArchiveWriter {
+	return &ArchiveWriter{Client: client, Dir: dir}
+}
+
+// ReadArchiveIndex reads the index of the archive in dir. Returns an empty
+// index if the archive has no index.
+func ReadArchiveIndex(dir string) (index ArchiveIndex, err error) {
+	b, err := os.ReadFile(filepath.Join(dir, ArchiveIndexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return index, err
+	}
+	if err := json.Unmarshal(b, &index); err != nil {
+		return index, fmt.Errorf("archive index: %w", err)
+	}
+	return index, nil
+}
+
+// load reads the index of the archive, if it has not been read. Must be
+// called with the lock held.
+func (w *ArchiveWriter) load() error {
+	if w.index != nil {
+		return nil
+	}
+	index, err := ReadArchiveIndex(w.Dir)
+	if err != nil {
+		return err
+	}
+	w.index = make(map[string]*ArchiveEntry, len(index.Builds))
+	for i := range index.Builds {
+		entry := index.Builds[i]
+		w.index[strings.ToLower(entry.Build.GUID)] = &entry
+	}
+	return nil
+}
+
+// Index returns the index of the archive.
+func (w *ArchiveWriter) Index() (index ArchiveIndex, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.load(); err != nil {
+		return index, err
+	}
+	return w.snapshot(), nil
+}
+
+// snapshot returns the index in order. Must be called with the lock held.
+func (w *ArchiveWriter) snapshot() ArchiveIndex {
+	var index ArchiveIndex
+	for _, entry := range w.index {
+		e := *entry
+		e.Artifacts = append([]string(nil), entry.Artifacts...)
+		index.Builds = append(index.Builds, e)
+	}
+	sort.Slice(index.Builds, func(i, j int) bool {
+		a, b := index.Builds[i].Build, index.Builds[j].Build
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.GUID < b.GUID
+	})
+	return index
+}
+
+// writeIndex writes the index to the archive. Must be called with the lock
+// held.
+func (w *ArchiveWriter) writeIndex() error {
+	b, err := json.MarshalIndent(w.snapshot(), "", "\t")
+	if err != nil {
+		return err
+	}
+	return saveFile(filepath.Join(w.Dir, ArchiveIndexFile), bytes.NewReader(append(b, '\n')))
+}
+
+// entry returns the entry of build, adding it to the index if needed. Known
+// details of the build are retained. Must be called with the lock held.
+func (w *ArchiveWriter) entry(build Build) *ArchiveEntry {
+	key := strings.ToLower(build.GUID)
+	entry, ok := w.index[key]
+	if !ok {
+		entry = &ArchiveEntry{Build: build, Path: archivePath(build)}
+		w.index[key] = entry
+	} else if entry.Build.Version.Empty() && !build.Version.Empty() {
+		// Details of the build became known; the path is retained.
+		path := entry.Path
+		entry.Build = build
+		entry.Path = path
+	}
+	return entry
+}
+
+// Save fetches each artifact of build that is not already in the archive,
+// and writes it to the directory of the build. Artifacts of methods that are
+// not configured by the client are skipped, as are artifacts that are not
+// available, such as those of expired builds, which fail with ErrStatus. The
+// index is updated with the build. Returns the paths of the written files.
+func (w *ArchiveWriter) Save(build Build) (paths []string, err error) {
+	if build.GUID == "" {
+		return nil, errors.New("archive: build has no GUID")
+	}
+	w.mu.Lock()
+	if err := w.load(); err != nil {
+		w.mu.Unlock()
+		return nil, err
+	}
+	entry := *w.entry(build)
+	w.mu.Unlock()
+
+	artifacts := w.Artifacts
+	if artifacts == nil {
+		artifacts = DefaultArtifacts
+	}
+	dir := filepath.Join(w.Dir, filepath.FromSlash(entry.Path))
+	var saved []string
+	for _, artifact := range artifacts {
+		if _, ok := w.Client.snapshot().methods[artifact.Method]; !ok {
+			continue
+		}
+		path := filepath.Join(dir, artifact.Name)
+		if _, err := os.Stat(path); err == nil {
+			saved = append(saved, artifact.Name)
+			continue
+		}
+		rc, e := w.Client.Method(artifact.Method, build.GUID)
+		if e == nil {
+			e = saveFile(path, rc)
+			rc.Close()
+		}
+		if e != nil {
+			if errors.Is(e, ErrStatus) {
+				continue
+			}
+			err = fmt.Errorf("archive %s: %s: %w", build.GUID, artifact.Name, e)
+			break
+		}
+		saved = append(saved, artifact.Name)
+		paths = append(paths, path)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	e := w.entry(build)
+	for _, name := range saved {
+		if !containsString(e.Artifacts, name) {
+			e.Artifacts = append(e.Artifacts, name)
+		}
+	}
+	sort.Strings(e.Artifacts)
+	if ierr := w.writeIndex(); ierr != nil && err == nil {
+		err = ierr
+	}
+	return paths, err
+}
+
+// Sync saves the missing artifacts of each of the given GUIDs, as with Save.
+// The details of each build, such as its version, are retrieved from the
+// archive's index, or from the client's Builds. If guids is empty, then every
+// build listed by Builds is synchronized. Failure to save a build does not
+// prevent the remaining builds from being saved; the first error is returned.
+func (w *ArchiveWriter) Sync(guids ...string) (paths []string, err error) {
+	builds, berr := w.Client.Builds()
+	if berr != nil && len(guids) == 0 {
+		return nil, berr
+	}
+	known := make(map[string]Build, len(builds))
+	for _, build := range builds {
+		known[strings.ToLower(build.GUID)] = build
+	}
+	if len(guids) == 0 {
+		for _, build := range builds {
+			guids = append(guids, build.GUID)
+		}
+	}
+	index, err := w.Index()
+	if err != nil {
+		return nil, err
+	}
+	for _, guid := range guids {
+		build, ok := known[strings.ToLower(guid)]
+		if !ok {
+			if entry, ok := index.Entry(guid); ok {
+				build = entry.Build
+			} else {
+				build = Build{GUID: guid}
+			}
+		}
+		p, e := w.Save(build)
+		paths = append(paths, p...)
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return paths, err
+}
+
+// containsString returns whether s contains v.
+func containsString(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterArchive is an iofl.Filter that fetches an artifact of a build from an
+// archive written by ArchiveWriter. The build is located through the index of
+// the archive, so the filter fails if the archive does not contain the build,
+// or the build does not have the artifact.
+//
+// Location is the root of the archive, which is either a local directory, or
+// the base URL of an archive served over HTTP, such as a repository of
+// archived builds. Artifact is the file name of the artifact, such as
+// "API-Dump.json". Both may contain variables such as $GUID.
+//
+// Remote archives are requested in the same manner as FilterURL, with
+// Client, which may be nil to use http.DefaultClient, Retry, Queue, Context,
+// Timeout, DefaultHeader, Credentials, Rewrite, Gate, and Hooks. Timeout
+// bounds each request separately.
+//
+// If Indexes is not nil, then the index of each location is retained by it,
+// so that the index is not fetched again for every artifact. A retained index
+// is fetched again when it does not list the requested artifact.
+type FilterArchive struct {
+	Location string
+	Artifact string
+	GUID     string
+	Vars     map[string]string
+	Client   *http.Client
+	Retry    RetryPolicy
+	Queue    func(ctx context.Context) (release func(), err error)
+	Context  context.Context
+	Timeout  time.Duration
+	Indexes  *ArchiveIndexCache
+
+	DefaultHeader http.Header
+	Credentials   CredentialProvider
+	Rewrite       func(u *neturl.URL) error
+	Gate          func(url string) error
+	Hooks         RequestHooks
+
+	r   io.ReadCloser
+	err error
+	// remote lists the requests made to a remote archive.
+	remote []*FilterURL
+}
+
+// ArchiveIndexCache retains the indexes of archives by location. It may be
+// used concurrently.
+type ArchiveIndexCache struct {
+	mu      sync.Mutex
+	indexes map[string]ArchiveIndex
+}
+
+// get returns the index retained for location.
+func (c *ArchiveIndexCache) get(location string) (index ArchiveIndex, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	index, ok = c.indexes[location]
+	return index, ok
+}
+
+// put retains index for location.
+func (c *ArchiveIndexCache) put(location string, index ArchiveIndex) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.indexes == nil {
+		c.indexes = map[string]ArchiveIndex{}
+	}
+	c.indexes[location] = index
+}
+
+// NewFilterArchive is an iofl.NewFilter that returns a FilterArchive.
+func NewFilterArchive(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error) {
+	return &FilterArchive{r: r,
+		Location: params.GetString("Location"),
+		Artifact: params.GetString("Artifact"),
+	}, nil
+}
+
+func (f *FilterArchive) SetGUID(guid string) {
+	f.GUID = guid
+}
+
+func (f *FilterArchive) SetVars(vars map[string]string) {
+	f.Vars = vars
+}
+
+func (f *FilterArchive) SetClient(client *http.Client) {
+	f.Client = client
+}
+
+func (f *FilterArchive) SetContext(ctx context.Context) {
+	f.Context = ctx
+}
+
+func (f *FilterArchive) SetDefaultHeader(header http.Header) {
+	f.DefaultHeader = header
+}
+
+func (f *FilterArchive) SetRetry(policy RetryPolicy) {
+	f.Retry = policy
+}
+
+func (f *FilterArchive) SetQueue(acquire func(ctx context.Context) (release func(), err error)) {
+	f.Queue = acquire
+}
+
+func (f *FilterArchive) SetTimeout(d time.Duration) {
+	f.Timeout = d
+}
+
+func (f *FilterArchive) SetCredentials(provider CredentialProvider) {
+	f.Credentials = provider
+}
+
+func (f *FilterArchive) SetRewrite(rewrite func(u *neturl.URL) error) {
+	f.Rewrite = rewrite
+}
+
+func (f *FilterArchive) SetGate(gate func(url string) error) {
+	f.Gate = gate
+}
+
+func (f *FilterArchive) SetHooks(hooks RequestHooks) {
+	f.Hooks = hooks
+}
+
+func (f *FilterArchive) SetArchiveIndexes(indexes *ArchiveIndexCache) {
+	f.Indexes = indexes
+}
+
+// NetworkStats returns the number of requests made to a remote archive, and
+// the number of bytes read from responses.
+func (f *FilterArchive) NetworkStats() (requests int, bytes int64) {
+	for _, u := range f.remote {
+		n, b := u.NetworkStats()
+		requests += n
+		bytes += b
+	}
+	return requests, bytes
+}
+
+func (f *FilterArchive) Source() io.ReadCloser {
+	return f.r
+}
+
+func (f *FilterArchive) Failure() error {
+	return failure(f.err)
+}
+
+func (f *FilterArchive) Close() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.r == nil {
+		// Nothing was opened.
+		f.err = iofl.Closed
+		return nil
+	}
+	if f.err = f.r.Close(); f.err == nil {
+		f.err = iofl.Closed
+		return nil
+	}
+	return f.err
+}
+
+// remoteArchive returns whether the location refers to a remote archive.
+func remoteArchive(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// get opens the file at the given slash-separated path within the archive.
+func (f *FilterArchive) get(location, path string) (io.ReadCloser, error) {
+	if !remoteArchive(location) {
+		return os.Open(filepath.Join(location, filepath.FromSlash(path)))
+	}
+	u := &FilterURL{
+		URL:     strings.TrimSuffix(location, "/") + "/" + path,
+		Client:  f.Client,
+		Retry:   f.Retry,
+		Queue:   f.Queue,
+		Context: f.Context,
+		Timeout: f.Timeout,
+
+		DefaultHeader: f.DefaultHeader,
+		Credentials:   f.Credentials,
+		Rewrite:       f.Rewrite,
+		Gate:          f.Gate,
+		Hooks:         f.Hooks,
+	}
+	f.remote = append(f.remote, u)
+	// Begin the request, so that a failure is returned immediately.
+	r := bufio.NewReader(u)
+	if _, err := r.Peek(1); err != nil && err != io.EOF {
+		u.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, u}, nil
+}
+
+// index returns the index of the archive at location. A retained index is
+// used if it lists artifact for the build.
+func (f *FilterArchive) index(location, artifact string) (index ArchiveIndex, err error) {
+	if f.Indexes != nil {
+		if index, ok := f.Indexes.get(location); ok {
+			if entry, ok := index.Entry(f.GUID); ok && containsString(entry.Artifacts, artifact) {
+				return index, nil
+			}
+		}
+	}
+	rc, err := f.get(location, ArchiveIndexFile)
+	if err != nil {
+		return index, fmt.Errorf("archive index: %w", err)
+	}
+	err = json.NewDecoder(rc).Decode(&index)
+	rc.Close()
+	if err != nil {
+		return index, classify(ErrDecode, fmt.Errorf("archive index: %w", err))
+	}
+	if f.Indexes != nil {
+		f.Indexes.put(location, index)
+	}
+	return index, nil
+}
+
+// open locates the artifact within the archive.
+func (f *FilterArchive) open() (io.ReadCloser, error) {
+	location := expandVars(f.Location, f.GUID, f.Vars)
+	artifact := expandVars(f.Artifact, f.GUID, f.Vars)
+	index, err := f.index(location, artifact)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := index.Entry(f.GUID)
+	if !ok {
+		return nil, fmt.Errorf("archive: build %s: %w", f.GUID, os.ErrNotExist)
+	}
+	if !containsString(entry.Artifacts, artifact) {
+		return nil, fmt.Errorf("archive: build %s: %s: %w", f.GUID, artifact, os.ErrNotExist)
+	}
+	return f.get(location, entry.Path+"/"+artifact)
+}
+
+func (f *FilterArchive) Read(p []byte) (n int, err error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.r == nil {
+		if f.r, err = f.open(); err != nil {
+			f.err = err
+			return 0, err
+		}
+	}
+	return f.r.Read(p)
+}
+
+// PreferArchive configures the client to fetch the given artifacts from the
+// archive at location, as read by FilterArchive, before any other chain. The
+// remaining chains of each method are used for builds that are missing from
+// the archive. If no artifacts are given, then DefaultArtifacts is used.
+//
+// For each artifact, a chain named "<method>Archive:<location>" is defined and
+// prepended to the method, unless it already exists.
+func (client *Client) PreferArchive(location string, artifacts ...Artifact) error {
+	if len(artifacts) == 0 {
+		artifacts = DefaultArtifacts
+	}
+	config := client.Config()
+	for _, artifact := range artifacts {
+		name := artifact.Method + "Archive:" + location
+		if _, ok := config.Chains[name]; !ok {
+			config.Chains[name] = iofl.Chain{
+				{Filter: "archive", Params: iofl.Params{"Location": location, "Artifact": artifact.Name}},
+			}
+		}
+		chains := config.Methods[artifact.Method]
+		if !containsString(chains, name) {
+			config.Methods[artifact.Method] = append([]string{name}, chains...)
+		}
+	}
+	return client.SetConfig(config)
+}
+
+// applyArchiveIndexes applies a cache of archive indexes to the chain of
+// filters.
+func applyArchiveIndexes(filter iofl.Filter, indexes *ArchiveIndexCache) {
+	type indexer interface {
+		iofl.Filter
+		SetArchiveIndexes(indexes *ArchiveIndexCache)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(indexer); ok {
+			f.SetArchiveIndexes(indexes)
+		}
+		return nil
+	})
+}
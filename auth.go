@@ -0,0 +1,75 @@
+package rbxfetch
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// CredentialProvider adds credentials to requests made by a Client.
+type CredentialProvider interface {
+	// Authorize is called before each request is made, and may add headers
+	// or cookies to req. Returning an error fails the request.
+	Authorize(req *http.Request) error
+}
+
+// CredentialFunc adapts a function to a CredentialProvider.
+type CredentialFunc func(req *http.Request) error
+
+// Authorize implements CredentialProvider.
+func (f CredentialFunc) Authorize(req *http.Request) error {
+	return f(req)
+}
+
+// RobloSecurity returns a CredentialProvider that adds the given value as the
+// .ROBLOSECURITY cookie to requests made to roblox.com and its subdomains.
+// Requests to other hosts, such as mirrors, do not receive the cookie.
+func RobloSecurity(value string) CredentialProvider {
+	return CredentialFunc(func(req *http.Request) error {
+		host := strings.ToLower(req.URL.Hostname())
+		if host == "roblox.com" || strings.HasSuffix(host, ".roblox.com") {
+			req.AddCookie(&http.Cookie{Name: ".ROBLOSECURITY", Value: value})
+		}
+		return nil
+	})
+}
+
+// getStringMap returns the value of key from params as a map of strings. The
+// value may be a map[string]string, or a map[string]interface{} as decoded
+// from JSON, in which case non-string values are ignored.
+func getStringMap(params iofl.Params, key string) map[string]string {
+	var values map[string]interface{}
+	switch v := params[key].(type) {
+	case map[string]string:
+		return v
+	case map[string]interface{}:
+		values = v
+	case iofl.Params:
+		values = v
+	default:
+		return nil
+	}
+	m := make(map[string]string, len(values))
+	for k, v := range values {
+		if v, ok := v.(string); ok {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// applyCredentials applies provider to the chain of filters.
+func applyCredentials(filter iofl.Filter, provider CredentialProvider) {
+	type credentialer interface {
+		iofl.Filter
+		SetCredentials(provider CredentialProvider)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(credentialer); ok {
+			f.SetCredentials(provider)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,157 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Binary types of Roblox deployments.
+const (
+	BinaryWindowsPlayer   = "WindowsPlayer"
+	BinaryWindowsStudio   = "WindowsStudio"
+	BinaryWindowsStudio64 = "WindowsStudio64"
+	BinaryMacPlayer       = "MacPlayer"
+	BinaryMacStudio       = "MacStudio"
+)
+
+// DefaultLiveBinaryTypes lists the binary types reported by LiveByBinaryType
+// when the client does not specify any.
+var DefaultLiveBinaryTypes = []string{
+	BinaryWindowsStudio64,
+	BinaryWindowsStudio,
+	BinaryMacStudio,
+	BinaryWindowsPlayer,
+}
+
+// isMac returns whether binaryType is deployed to the Mac deployment line.
+func isMac(binaryType string) bool {
+	return strings.HasPrefix(strings.ToLower(binaryType), "mac")
+}
+
+// binaryTypeVars returns the variables that describe binaryType:
+//
+//   - BINARYTYPE: The binary type.
+//   - PLATFORMPATH: "mac/" for Mac binary types. Empty otherwise.
+//   - VERSIONFILE: The name of the file that contains the GUID of the latest
+//     build of the binary type.
+func binaryTypeVars(binaryType string) map[string]string {
+	vars := map[string]string{"BINARYTYPE": binaryType}
+	if isMac(binaryType) {
+		vars["PLATFORMPATH"] = "mac/"
+	}
+	switch strings.ToLower(binaryType) {
+	case "windowsstudio", "windowsstudio64":
+		vars["VERSIONFILE"] = "versionQTStudio"
+	case "macstudio":
+		vars["VERSIONFILE"] = "versionStudio"
+	default:
+		vars["VERSIONFILE"] = "version"
+	}
+	return vars
+}
+
+// buildBinaryType returns the binary type of a build of the given type, listed
+// by the deployment history of the Mac deployment line if mac is true, or the
+// Windows deployment line otherwise. Returns an empty string for build types
+// that have no binary type, such as BuildRCCService.
+func buildBinaryType(buildType string, mac bool) string {
+	switch NormalizeBuildType(buildType) {
+	case BuildStudio:
+		if mac {
+			return BinaryMacStudio
+		}
+		return BinaryWindowsStudio
+	case BuildStudio64:
+		if mac {
+			return BinaryMacStudio
+		}
+		return BinaryWindowsStudio64
+	case BuildClient, BuildWindowsPlayer:
+		if mac {
+			return BinaryMacPlayer
+		}
+		return BinaryWindowsPlayer
+	}
+	return ""
+}
+
+// LatestFor returns the GUID of the latest build of the given binary type,
+// such as BinaryMacStudio. Returns an empty string if no "LatestBinaryType"
+// method is configured.
+//
+// The binary type is described to chains by the $BINARYTYPE, $PLATFORMPATH,
+// and $VERSIONFILE variables.
+func (client *Client) LatestFor(binaryType string) (guid string, err error) {
+	return client.latest("LatestBinaryType", binaryTypeVars(binaryType))
+}
+
+// LiveFor returns the GUIDs of the current live builds of the given binary
+// type, in the same manner as Live. Returns an empty slice if no
+// "LiveBinaryType" method is configured.
+func (client *Client) LiveFor(binaryType string) (guids []string, err error) {
+	return client.live("LiveBinaryType", binaryTypeVars(binaryType))
+}
+
+// LiveByBinaryType returns the current live version of each binary type listed
+// by the client's LiveBinaryTypes, or DefaultLiveBinaryTypes if nil, mapped by
+// binary type. Unlike Live, the meaning of each result does not depend on the
+// order of chains. The binary types are fetched concurrently, each with the
+// "LiveBinaryType" method, using the version of its first chain. Returns an
+// empty map if no "LiveBinaryType" method is configured.
+//
+// If a binary type fails, then the error of the first failed binary type, in
+// the order listed, is returned. If PartialLive is set, then the versions of
+// the binary types that succeeded are returned along with the error.
+func (client *Client) LiveByBinaryType() (versions map[string]ClientVersion, err error) {
+	binaryTypes := client.LiveBinaryTypes
+	if binaryTypes == nil {
+		binaryTypes = DefaultLiveBinaryTypes
+	}
+	results := make([]ClientVersion, len(binaryTypes))
+	errs := make([]error, len(binaryTypes))
+	var wg sync.WaitGroup
+	for i, binaryType := range binaryTypes {
+		wg.Add(1)
+		go func(i int, binaryType string) {
+			defer wg.Done()
+			v, err := client.liveVersions("LiveBinaryType", binaryTypeVars(binaryType))
+			if len(v) > 0 {
+				results[i] = v[0]
+			} else if err != nil {
+				errs[i] = fmt.Errorf("binary type %s: %w", binaryType, err)
+			}
+		}(i, binaryType)
+	}
+	wg.Wait()
+	versions = make(map[string]ClientVersion, len(binaryTypes))
+	for i, binaryType := range binaryTypes {
+		if errs[i] != nil {
+			if err == nil {
+				err = errs[i]
+			}
+			continue
+		}
+		if results[i].ClientVersionUpload != "" {
+			versions[binaryType] = results[i]
+		}
+	}
+	if err != nil && !client.PartialLive {
+		return nil, err
+	}
+	return versions, err
+}
+
+// BuildsFor returns the builds listed by the deployment history of the given
+// binary type's deployment line. Returns nil if no "BuildsBinaryType" method
+// is configured.
+func (client *Client) BuildsFor(binaryType string) (builds []Build, err error) {
+	return client.builds("BuildsBinaryType", "LiveBinaryType", "", binaryTypeVars(binaryType))
+}
+
+// MacStudio returns the Mac Studio application package of the given GUID.
+// Returns nil if no "MacStudio" method is configured.
+func (client *Client) MacStudio(guid string) (rc io.ReadCloser, err error) {
+	return client.method("MacStudio", guid)
+}
@@ -0,0 +1,84 @@
+package rbxfetch
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// BulkFetcher runs a single method of a Client for many GUIDs with a pool of
+// workers. Requests are made with PriorityBackground, so that they do not
+// delay interactive requests of the same client when MaxFetches is set.
+type BulkFetcher struct {
+	// Client is the client that runs the method.
+	Client *Client
+	// Method is the name of the method to run.
+	Method string
+	// Concurrency is the number of GUIDs fetched at once. If less than 1,
+	// then 4 is used.
+	Concurrency int
+	// Interval, if greater than zero, is the minimum duration between the
+	// start of each fetch, limiting the rate of fetching.
+	Interval time.Duration
+	// Handle, if not nil, is called with the content of each successful
+	// fetch, and may be called concurrently. An error returned by Handle is
+	// reported as the error of the GUID. If nil, then the content is read and
+	// discarded, which is useful for populating the cache.
+	Handle func(guid string, r io.Reader) error
+}
+
+// BulkResult is the outcome of fetching one GUID with a BulkFetcher.
+type BulkResult struct {
+	GUID string
+	Err  error
+}
+
+// fetch runs the method for a single GUID.
+func (b *BulkFetcher) fetch(guid string) error {
+	rc, err := b.Client.WithPriority(PriorityBackground).Method(b.Method, guid)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if b.Handle == nil {
+		_, err = io.Copy(io.Discard, rc)
+		return err
+	}
+	return b.Handle(guid, rc)
+}
+
+// Fetch runs the method for each of guids, returning a result for each, in
+// the same order. The failure of one GUID does not affect the others.
+func (b *BulkFetcher) Fetch(guids []string) []BulkResult {
+	n := b.Concurrency
+	if n < 1 {
+		n = 4
+	}
+	results := make([]BulkResult, len(guids))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = BulkResult{GUID: guids[i], Err: b.fetch(guids[i])}
+			}
+		}()
+	}
+	var tick <-chan time.Time
+	if b.Interval > 0 {
+		ticker := time.NewTicker(b.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for i := range guids {
+		if tick != nil && i > 0 {
+			<-tick
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
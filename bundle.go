@@ -0,0 +1,99 @@
+package rbxfetch
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"sync"
+
+	"github.com/robloxapi/rbxdump"
+	"github.com/robloxapi/rbxdump/json"
+	"github.com/robloxapi/rbxdump/legacy"
+)
+
+// decodeAPIDump decodes an API dump read from rc, according to the format
+// reported by rc.
+func decodeAPIDump(rc io.Reader) (root *rbxdump.Root, err error) {
+	format := FormatJSON
+	if f, ok := rc.(Formatter); ok {
+		// The format is known only once the result has been read, so peek
+		// at the first byte.
+		var b [1]byte
+		n, err := io.ReadFull(rc, b[:])
+		if err == io.EOF {
+			return nil, classify(ErrDecode, errors.New("empty API dump"))
+		} else if err != nil {
+			return nil, err
+		}
+		if f.Format() != "" {
+			format = f.Format()
+		}
+		rc = io.MultiReader(bytes.NewReader(b[:n]), rc)
+	}
+	if format == FormatText {
+		root, err = legacy.Decode(rc)
+	} else {
+		root, err = json.Decode(rc)
+	}
+	return root, classify(ErrDecode, err)
+}
+
+// Bundle contains the decoded artifacts of a build that are most commonly
+// used together.
+type Bundle struct {
+	GUID string
+	// APIDump is the decoded API dump.
+	APIDump *rbxdump.Root
+	// ReflectionMetadata is the decoded reflection metadata.
+	ReflectionMetadata *ReflectionMetadata
+	// ClassImages is the decoded class icon sheet.
+	ClassImages image.Image
+}
+
+// Bundle concurrently fetches and decodes the API dump, reflection metadata,
+// and class images of the given GUID. If any of these fail, then the
+// remainder are still returned, along with a ChainErrors describing each
+// method that failed.
+func (client *Client) Bundle(guid string) (bundle Bundle, err error) {
+	bundle.GUID = guid
+	var mu sync.Mutex
+	var errs ChainErrors
+	var wg sync.WaitGroup
+	fetch := func(method string, decode func(r io.Reader) error) {
+		defer wg.Done()
+		var chain string
+		rc, err := client.Method(method, guid)
+		if err == nil {
+			err = decode(rc)
+			if f, ok := rc.(Formatter); ok {
+				chain = f.Chain()
+			}
+			rc.Close()
+		}
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, newChainError(method, chain, err))
+			mu.Unlock()
+		}
+	}
+	wg.Add(3)
+	go fetch("APIDump", func(r io.Reader) (err error) {
+		bundle.APIDump, err = decodeAPIDump(r)
+		return err
+	})
+	go fetch("ReflectionMetadata", func(r io.Reader) (err error) {
+		bundle.ReflectionMetadata, err = DecodeReflectionMetadata(r)
+		return err
+	})
+	go fetch("ClassImages", func(r io.Reader) (err error) {
+		bundle.ClassImages, err = png.Decode(r)
+		return classify(ErrDecode, err)
+	})
+	wg.Wait()
+	if len(errs) > 0 {
+		return bundle, errs
+	}
+	return bundle, nil
+}
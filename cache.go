@@ -0,0 +1,400 @@
+package rbxfetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/anaminus/iofl"
+)
+
+const cacheDirName = "roblox-fetch"
+
+// cacheDir returns the directory in which files are cached according to the
+// given mode and location. Returns an empty string if the mode does not cache.
+func cacheDir(mode CacheMode, loc string) string {
+	switch mode {
+	case CacheTemp:
+		return filepath.Join(os.TempDir(), cacheDirName)
+	case CachePerm:
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		return filepath.Join(dir, cacheDirName)
+	case CacheCustom:
+		return loc
+	}
+	return ""
+}
+
+// FilterCache is an iofl.Filter that caches the content of its source. If the
+// content is already cached, then the source is not read.
+//
+// Key identifies the content within the cache, and may contain variables such
+// as $GUID. If Key is empty, or the cache mode is CacheNone, then the content
+// of the source is passed through uncached.
+//
+// If Dedup is true, then content is stored once per unique content hash, and
+// each key is linked to the stored content. This reduces disk usage when
+// several keys have identical content.
+//
+// If the filter has no source, then the content is only retrieved from the
+// cache, failing if it is not present.
+//
+// FilterCache implements io.ReaderAt and io.Seeker, so that subsequent filters
+// may access the content randomly. If the content is not cached, then it is
+// buffered within Memory, which may be nil, when accessed in this way.
+type FilterCache struct {
+	Key           string
+	GUID          string
+	Vars          map[string]string
+	CacheMode     CacheMode
+	CacheLocation string
+	Dedup         bool
+	Memory        *MemoryBudget
+
+	r    io.ReadCloser
+	c    readAtSeekCloser
+	pass bool
+	// hit is 1 if the content was retrieved from the cache, and -1 if it was
+	// stored.
+	hit int
+	err error
+}
+
+// NewFilterCache is an iofl.NewFilter that returns a FilterCache.
+func NewFilterCache(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error) {
+	return &FilterCache{r: r,
+		Key: params.GetString("Key"),
+	}, nil
+}
+
+func (f *FilterCache) SetGUID(guid string) {
+	f.GUID = guid
+}
+
+func (f *FilterCache) SetVars(vars map[string]string) {
+	f.Vars = vars
+}
+
+func (f *FilterCache) SetCache(mode CacheMode, loc string) {
+	f.CacheMode = mode
+	f.CacheLocation = loc
+}
+
+func (f *FilterCache) SetDedup(dedup bool) {
+	f.Dedup = dedup
+}
+
+func (f *FilterCache) SetMemory(m *MemoryBudget) {
+	f.Memory = m
+}
+
+// CacheHit returns whether the content was retrieved from the cache. ok is
+// false if the cache was not consulted.
+func (f *FilterCache) CacheHit() (hit, ok bool) {
+	return f.hit > 0, f.hit != 0
+}
+
+// CachedSize returns the size of the cached content, without reading the
+// source. ok is false if the content is not cached.
+func (f *FilterCache) CachedSize() (size int64, ok bool) {
+	path := f.path()
+	if path == "" {
+		return -1, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return -1, false
+	}
+	return info.Size(), true
+}
+
+func (f *FilterCache) Source() io.ReadCloser {
+	return f.r
+}
+
+func (f *FilterCache) Failure() error {
+	return failure(f.err)
+}
+
+func (f *FilterCache) Close() error {
+	if f.err != nil {
+		return f.err
+	}
+	var err error
+	if f.c != nil {
+		err = f.c.Close()
+	}
+	if f.r != nil {
+		if f.err = f.r.Close(); f.err == nil {
+			f.err = err
+		}
+	} else {
+		f.err = err
+	}
+	if f.err == nil {
+		f.err = iofl.Closed
+		return nil
+	}
+	return f.err
+}
+
+// path returns the path of the cached file, or an empty string if the content
+// is not to be cached.
+func (f *FilterCache) path() string {
+	if f.Key == "" {
+		return ""
+	}
+	dir := cacheDir(f.CacheMode, f.CacheLocation)
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, url.PathEscape(expandVars(f.Key, f.GUID, f.Vars)))
+}
+
+// store writes the content of the source to a temporary file within the cache
+// directory, then moves it to path. Returns the file containing the content.
+func (f *FilterCache) store(path string) (file *os.File, err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	tempFile, err := f.resumePartial(dir, path, h)
+	if err != nil {
+		return nil, err
+	}
+	tempName := tempFile.Name()
+	// Remove the temp file on failure, including if reading the source
+	// panics.
+	keep := false
+	defer func() {
+		if !keep {
+			tempFile.Close()
+			os.Remove(tempName)
+		}
+	}()
+
+	// Write to temp file.
+	filled := false
+	if src, ok := f.r.(fileFiller); ok {
+		if filled, err = src.fillFile(tempFile); err != nil {
+			return nil, err
+		}
+	}
+	if filled {
+		// The content was written directly, so hash it from the file.
+		if _, err = io.Copy(h, io.NewSectionReader(tempFile, 0, 1<<63-1)); err != nil {
+			return nil, err
+		}
+	} else if _, err = io.Copy(io.MultiWriter(tempFile, h), f.r); err != nil {
+		if f.keepPartial(tempFile, path) {
+			keep = true
+		}
+		return nil, err
+	}
+
+	// Sync temp file.
+	if err = tempFile.Sync(); err != nil {
+		return nil, err
+	}
+	tempFile.Close()
+	keep = true
+
+	if f.Dedup {
+		return storeBlob(tempName, path, hex.EncodeToString(h.Sum(nil)))
+	}
+
+	// Attempt to relocate temp file to cache file.
+	if err := os.Rename(tempName, path); err != nil {
+		// Rename failed. Data is still in temp file, so we'll reuse that.
+		path = tempName
+	}
+	return os.Open(path)
+}
+
+// blobDirName is the name of the directory within the cache directory that
+// contains content-addressed blobs.
+const blobDirName = "blobs"
+
+// storeBlob moves the file src to a blob named by hash, then replaces path
+// with a link to the blob. If the blob already exists, then src is discarded.
+// src may be path itself, such as an existing entry of the cache, which is
+// replaced only once its link has been made, so that a failure leaves it in
+// place. Returns the file containing the content.
+func storeBlob(src, path, hash string) (file *os.File, err error) {
+	blobDir := filepath.Join(filepath.Dir(path), blobDirName)
+	if err := os.MkdirAll(blobDir, 0755); err != nil {
+		if src != path {
+			os.Remove(src)
+		}
+		return nil, err
+	}
+	blob := filepath.Join(blobDir, hash)
+	if _, err := os.Stat(blob); err != nil {
+		if src == path {
+			// The entry becomes the blob by linking the blob to it. If
+			// linking is not supported, then the entry is left as it is.
+			os.Link(path, blob)
+			return os.Open(path)
+		}
+		if err := os.Rename(src, blob); err != nil {
+			// Rename failed. Data is still in temp file, so we'll reuse that.
+			return os.Open(src)
+		}
+	} else if src != path {
+		os.Remove(src)
+	}
+	if err := linkBlob(blob, hash, path); err != nil {
+		// Linking is not supported. The content will be retrieved again next
+		// time, but will still be deduplicated.
+		return os.Open(blob)
+	}
+	return os.Open(path)
+}
+
+// linkBlob replaces path with a link to blob, which is named by hash. The link
+// is made at a temporary name beside path, then renamed over path, so that
+// path is left in place if linking fails.
+func linkBlob(blob, hash, path string) error {
+	temp, err := createTemp(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	tempName := temp.Name()
+	temp.Close()
+	os.Remove(tempName)
+	if err := os.Link(blob, tempName); err != nil {
+		if err := os.Symlink(filepath.Join(blobDirName, hash), tempName); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tempName, path); err != nil {
+		os.Remove(tempName)
+		return err
+	}
+	return nil
+}
+
+// open prepares the content for reading. If the content is not to be cached,
+// then it is passed through, unless random is true, in which case it is
+// buffered.
+func (f *FilterCache) open(random bool) error {
+	path := f.path()
+	if path != "" {
+		if err := ensureCacheFormat(cacheDir(f.CacheMode, f.CacheLocation)); err != nil {
+			return err
+		}
+	}
+	if f.r == nil {
+		// Without a source, the content can only be retrieved from the cache.
+		if path == "" {
+			return errors.New("cache: no source and caching is disabled")
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			f.hit = -1
+			return err
+		}
+		f.c = file
+		f.hit = 1
+		return nil
+	}
+	if path == "" {
+		if !random {
+			f.pass = true
+			return nil
+		}
+		c, err := f.Memory.buffer(f.r)
+		if err != nil {
+			return err
+		}
+		f.c = c
+		return nil
+	}
+	if file, err := os.Open(path); err == nil {
+		f.c = file
+		f.hit = 1
+		return nil
+	}
+	f.hit = -1
+	file, err := f.store(path)
+	if err != nil {
+		return err
+	}
+	f.c = file
+	return nil
+}
+
+// openRandom prepares the content for random access.
+func (f *FilterCache) openRandom() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.c == nil {
+		if f.pass {
+			return errors.New("cache: random access after sequential read")
+		}
+		if err := f.open(true); err != nil {
+			f.err = classify(ErrCache, err)
+			return f.err
+		}
+	}
+	return nil
+}
+
+// ready opens the content of the filter, if it has not been opened.
+func (f *FilterCache) ready() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.c == nil && !f.pass {
+		if err := f.open(false); err != nil {
+			f.err = classify(ErrCache, err)
+			return f.err
+		}
+	}
+	return nil
+}
+
+func (f *FilterCache) Read(p []byte) (n int, err error) {
+	if err := f.ready(); err != nil {
+		return 0, err
+	}
+	if f.pass {
+		return f.r.Read(p)
+	}
+	return f.c.Read(p)
+}
+
+// WriteTo implements io.WriterTo. Cached content is copied directly from the
+// cache file, allowing the copy to be performed by the operating system.
+func (f *FilterCache) WriteTo(w io.Writer) (n int64, err error) {
+	if err := f.ready(); err != nil {
+		return 0, err
+	}
+	if f.pass {
+		return io.Copy(w, f.r)
+	}
+	return io.Copy(w, f.c)
+}
+
+func (f *FilterCache) ReadAt(p []byte, off int64) (n int, err error) {
+	if err := f.openRandom(); err != nil {
+		return 0, err
+	}
+	return f.c.ReadAt(p, off)
+}
+
+func (f *FilterCache) Seek(offset int64, whence int) (n int64, err error) {
+	if err := f.openRandom(); err != nil {
+		return 0, err
+	}
+	return f.c.Seek(offset, whence)
+}
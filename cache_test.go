@@ -0,0 +1,86 @@
+package rbxfetch_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anaminus/iofl"
+	"github.com/robloxapi/rbxfetch"
+)
+
+// cacheFilter returns a FilterCache that stores content under key in dir. If
+// content is empty, then the filter has no source.
+func cacheFilter(t *testing.T, dir, key, content string) *rbxfetch.FilterCache {
+	t.Helper()
+	var src io.ReadCloser
+	if content != "" {
+		src = io.NopCloser(strings.NewReader(content))
+	}
+	f, err := rbxfetch.NewFilterCache(iofl.Params{"Key": key}, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := f.(*rbxfetch.FilterCache)
+	cache.SetCache(rbxfetch.CacheCustom, dir)
+	return cache
+}
+
+func TestFilterCacheDedup(t *testing.T) {
+	entries := []struct{ key, content string }{
+		{"a.xml", "<roblox/>"},
+		{"b.xml", "<roblox/>"},
+		{"c.xml", "<roblox></roblox>"},
+	}
+	for _, dedup := range []bool{true, false} {
+		dir := t.TempDir()
+		for _, entry := range entries {
+			f := cacheFilter(t, dir, entry.key, entry.content)
+			f.Dedup = dedup
+			b, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != entry.content {
+				t.Fatalf("dedup %v: %s: expected %q, got %q", dedup, entry.key, entry.content, b)
+			}
+		}
+
+		a, err := os.Stat(filepath.Join(dir, "a.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.Stat(filepath.Join(dir, "b.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shared := os.SameFile(a, b); shared != dedup {
+			t.Errorf("dedup %v: expected identical entries to share a file: %v, got %v", dedup, dedup, shared)
+		}
+		// Each unique content is stored once.
+		expected := 0
+		if dedup {
+			expected = 2
+		}
+		blobs, _ := os.ReadDir(filepath.Join(dir, "blobs"))
+		if len(blobs) != expected {
+			t.Errorf("dedup %v: expected %d blobs, got %d", dedup, expected, len(blobs))
+		}
+
+		// Linked entries are retrieved like any other.
+		for _, entry := range entries {
+			f := cacheFilter(t, dir, entry.key, "")
+			b, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != entry.content {
+				t.Errorf("dedup %v: cached %s: expected %q, got %q", dedup, entry.key, entry.content, b)
+			}
+		}
+	}
+}
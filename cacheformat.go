@@ -0,0 +1,113 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// cacheFormatFile is the name of the file within a cache directory that
+// records the format version of the cache.
+const cacheFormatFile = "FORMAT"
+
+// cacheFormat is the current format version of cache directories.
+//
+// Version 0 is a cache without a format marker. Version 1 stores each entry
+// under its path-escaped key, with deduplicated content in the blobs
+// directory.
+const cacheFormat = 1
+
+// cacheMigrations upgrades a cache directory. The migration at index i
+// upgrades a cache of version i to version i+1.
+var cacheMigrations = []func(dir string) error{
+	// The layout of version 0 is identical to version 1.
+	func(dir string) error { return nil },
+}
+
+// cacheFormats records the directories whose formats have been verified.
+var cacheFormats sync.Map
+
+// readCacheFormat returns the format version of the cache directory. Returns
+// cacheFormat for a directory that is empty or does not exist.
+func readCacheFormat(dir string) (version int, err error) {
+	b, err := os.ReadFile(filepath.Join(dir, cacheFormatFile))
+	if err == nil {
+		if version, err = strconv.Atoi(strings.TrimSpace(string(b))); err != nil {
+			return 0, fmt.Errorf("malformed cache format: %w", err)
+		}
+		return version, nil
+	}
+	if !os.IsNotExist(err) {
+		return 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cacheFormat, nil
+		}
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return cacheFormat, nil
+	}
+	return 0, nil
+}
+
+// writeCacheFormat atomically writes the format version of the cache
+// directory.
+func writeCacheFormat(dir string, version int) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return saveFile(filepath.Join(dir, cacheFormatFile), strings.NewReader(strconv.Itoa(version)+"\n"))
+}
+
+// MigrateCache upgrades the cache directory of the given mode and location to
+// the current format, applying each migration in turn. Returns an error if the
+// cache has a newer format than is supported. Caches are migrated
+// automatically when first accessed by a FilterCache.
+func MigrateCache(mode CacheMode, loc string) error {
+	dir := cacheDir(mode, loc)
+	if dir == "" {
+		return nil
+	}
+	return migrateCacheDir(dir)
+}
+
+// migrateCacheDir upgrades the cache directory to the current format.
+func migrateCacheDir(dir string) error {
+	version, err := readCacheFormat(dir)
+	if err != nil {
+		return err
+	}
+	if version > cacheFormat {
+		return fmt.Errorf("cache format %d is newer than supported format %d", version, cacheFormat)
+	}
+	for ; version < cacheFormat; version++ {
+		if err := cacheMigrations[version](dir); err != nil {
+			return fmt.Errorf("migrate cache from format %d: %w", version, err)
+		}
+		if err := writeCacheFormat(dir, version+1); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, cacheFormatFile)); os.IsNotExist(err) {
+		return writeCacheFormat(dir, cacheFormat)
+	}
+	return nil
+}
+
+// ensureCacheFormat migrates the cache directory once per process.
+func ensureCacheFormat(dir string) error {
+	if _, ok := cacheFormats.Load(dir); ok {
+		return nil
+	}
+	if err := migrateCacheDir(dir); err != nil {
+		return err
+	}
+	cacheFormats.Store(dir, true)
+	return nil
+}
@@ -0,0 +1,75 @@
+package rbxfetch
+
+import (
+	"strings"
+)
+
+// buildTypeNames maps the lowercase variations of the names of build types that
+// have appeared in deployment histories to their canonical names.
+var buildTypeNames = map[string]string{
+	"studio":        BuildStudio,
+	"studiobeta":    BuildStudio,
+	"qtstudio":      BuildStudio,
+	"studio64":      BuildStudio64,
+	"client":        BuildClient,
+	"windowsplayer": BuildWindowsPlayer,
+	"rccservice":    BuildRCCService,
+}
+
+// NormalizeBuildType returns the canonical name of a build type, such as
+// BuildStudio for "StudioBeta". Unknown build types are returned unchanged.
+func NormalizeBuildType(buildType string) string {
+	if name, ok := buildTypeNames[strings.ToLower(buildType)]; ok {
+		return name
+	}
+	return buildType
+}
+
+// CanonicalizeBuilds returns a clean list of builds, as listed in
+// chronological order by a deployment history:
+//
+//   - The type of each build is normalized by NormalizeBuildType.
+//   - Builds are deduplicated by GUID, case-insensitively. The first
+//     occurrence of a GUID is retained, with a Status of "Done" if any
+//     occurrence succeeded.
+//   - Builds whose deployment failed with an error, and builds that were
+//     withdrawn by a later job reverting the same build type to an earlier
+//     build, are marked as Revoked.
+//
+// Jobs that revert to an earlier build do not appear in the result, except
+// when the reverted build is not otherwise listed. The given list is not
+// modified.
+func CanonicalizeBuilds(builds []Build) []Build {
+	result := make([]Build, 0, len(builds))
+	// index maps a lowercase GUID to the index of its build within result.
+	index := make(map[string]int, len(builds))
+	// latest maps a build type to the index of the build most recently
+	// deployed as that type.
+	latest := map[string]int{}
+	for _, build := range builds {
+		build.Type = NormalizeBuildType(build.Type)
+		key := strings.ToLower(build.GUID)
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			build.Revoked = build.Status == "Error"
+			result = append(result, build)
+		} else if build.Status == "Done" {
+			// A successful redeployment restores the build.
+			result[i].Status = "Done"
+			result[i].Revoked = false
+		}
+		if build.Status == "Error" {
+			continue
+		}
+		if build.Revert {
+			if j, ok := latest[build.Type]; ok && j != i {
+				result[j].Revoked = true
+			}
+			result[i].Revoked = false
+		}
+		latest[build.Type] = i
+	}
+	return result
+}
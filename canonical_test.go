@@ -0,0 +1,94 @@
+package rbxfetch_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/robloxapi/rbxfetch"
+)
+
+func TestCanonicalizeBuilds(t *testing.T) {
+	type result struct {
+		Type    string
+		GUID    string
+		Status  string
+		Revoked bool
+	}
+	tests := []struct {
+		name   string
+		builds []rbxfetch.Build
+		want   []result
+	}{
+		{
+			name: "normalized",
+			builds: []rbxfetch.Build{
+				{Type: "StudioBeta", GUID: "version-a", Status: "Done"},
+				{Type: "studio64", GUID: "version-b", Status: "Done"},
+			},
+			want: []result{
+				{rbxfetch.BuildStudio, "version-a", "Done", false},
+				{rbxfetch.BuildStudio64, "version-b", "Done", false},
+			},
+		},
+		{
+			name: "duplicate",
+			builds: []rbxfetch.Build{
+				{Type: "Studio64", GUID: "version-a", Status: "Error"},
+				{Type: "Studio64", GUID: "VERSION-A", Status: "Done"},
+			},
+			want: []result{
+				{rbxfetch.BuildStudio64, "version-a", "Done", false},
+			},
+		},
+		{
+			name: "failed",
+			builds: []rbxfetch.Build{
+				{Type: "Studio64", GUID: "version-a", Status: "Done"},
+				{Type: "Studio64", GUID: "version-b", Status: "Error"},
+			},
+			want: []result{
+				{rbxfetch.BuildStudio64, "version-a", "Done", false},
+				{rbxfetch.BuildStudio64, "version-b", "Error", true},
+			},
+		},
+		{
+			name: "reverted",
+			builds: []rbxfetch.Build{
+				{Type: "Studio64", GUID: "version-a", Status: "Done"},
+				{Type: "Studio64", GUID: "version-b", Status: "Done"},
+				{Type: "Studio64", GUID: "version-a", Status: "Done", Revert: true},
+			},
+			want: []result{
+				{rbxfetch.BuildStudio64, "version-a", "Done", false},
+				{rbxfetch.BuildStudio64, "version-b", "Done", true},
+			},
+		},
+		{
+			name: "reverted other type",
+			builds: []rbxfetch.Build{
+				{Type: "Studio64", GUID: "version-a", Status: "Done"},
+				{Type: "WindowsPlayer", GUID: "version-b", Status: "Done"},
+				{Type: "Studio64", GUID: "version-a", Status: "Done", Revert: true},
+			},
+			want: []result{
+				{rbxfetch.BuildStudio64, "version-a", "Done", false},
+				{rbxfetch.BuildWindowsPlayer, "version-b", "Done", false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]rbxfetch.Build(nil), tt.builds...)
+			var got []result
+			for _, build := range rbxfetch.CanonicalizeBuilds(tt.builds) {
+				got = append(got, result{build.Type, build.GUID, build.Status, build.Revoked})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+			if !reflect.DeepEqual(tt.builds, input) {
+				t.Error("input was modified")
+			}
+		})
+	}
+}
@@ -2,10 +2,15 @@
 package rbxfetch
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/anaminus/iofl"
@@ -36,12 +41,45 @@ type Build struct {
 	GUID    string
 	Date    time.Time
 	Version Version
+
+	// Channel is the deployment channel from which the build was listed.
+	Channel string `json:",omitempty"`
+	// Track is the track of the chain from which the build was listed. Empty
+	// for the default track.
+	Track string `json:",omitempty"`
+	// BinaryType is the binary type of the build, such as BinaryMacStudio,
+	// as determined by its type and the deployment line from which it was
+	// listed. Empty if the type has no binary type.
+	BinaryType string `json:",omitempty"`
+	// Live indicates whether the build is a current live build. Set only
+	// when the client's EnrichBuilds is enabled.
+	Live bool `json:",omitempty"`
+	// BootstrapperVersion is the version of the bootstrapper of a live build,
+	// if reported.
+	BootstrapperVersion string `json:",omitempty"`
+	// Revert indicates whether the build was listed by a job that reverted
+	// the deployment to a previously deployed build.
+	Revert bool `json:",omitempty"`
+	// Status is the status reported by the job that deployed the build:
+	// "Done", "Error", or empty if the job reported no status, such as when
+	// it was cancelled.
+	Status string `json:",omitempty"`
+	// Revoked indicates whether the deployment of the build failed, or was
+	// withdrawn by reverting to an earlier build. Set only by
+	// CanonicalizeBuilds.
+	Revoked bool `json:",omitempty"`
+	// Line is the line of the deployment history from which the build was
+	// listed, without the line terminator.
+	Line string `json:",omitempty"`
 }
 
+// UnmarshalJSON decodes a build from either a JSON object, as encoded by
+// json.Marshal, in which case every field is restored, or a JSON string, which
+// is decoded as the GUID of the build.
 func (b *Build) UnmarshalJSON(p []byte) (err error) {
 	var s string
 	if err = json.Unmarshal(p, &s); err == nil {
-		b.GUID = s
+		*b = Build{GUID: s}
 		return nil
 	}
 	type jBuild Build
@@ -61,47 +99,366 @@ type Client struct {
 	// CacheLocation specifies the path to store cached files, when CacheMode
 	// is CacheCustom.
 	CacheLocation string
-	// Client is the HTTP client that performs requests.
+	// CacheDedup specifies whether cached content is deduplicated by its
+	// content hash.
+	CacheDedup bool
+	// CacheDerived specifies whether data derived from content, such as the
+	// decoded API dumps of APIDumpDecoded, are stored in the cache, so that
+	// they are not derived again by later processes. See Client.Derive.
+	CacheDerived bool
+	// Client is the HTTP client that performs requests. If nil, then
+	// http.DefaultClient is used. For bulk fetching, a client using the
+	// transport returned by BulkTransport reuses connections more
+	// effectively.
 	Client *http.Client
+	// Proxy, if not empty, is the URL of the proxy through which requests are
+	// made, such as "http://proxy:8080" or "socks5://proxy:1080", taking
+	// precedence over the proxy specified by the environment.
+	Proxy string
+	// HostProxies maps a host name, such as "setup.rbxcdn.com", to the URL of
+	// the proxy through which requests to the host are made, taking
+	// precedence over Proxy. A value of ProxyDirect makes requests to the
+	// host without a proxy.
+	//
+	// Proxy and HostProxies are applied to a copy of the transport of
+	// Client, and are ignored if the transport is not an *http.Transport.
+	HostProxies map[string]string
+	// TLSConfig, if not nil, configures the TLS connections of requests, such
+	// as to trust additional root certificate authorities (see
+	// CertPoolFromFiles), present a client certificate, or require a minimum
+	// version. Like Proxy, it is applied to a copy of the transport of
+	// Client.
+	TLSConfig *tls.Config
+	// Mirrors lists base URLs of hosts that serve the same content. When a
+	// request to a URL beginning with one mirror fails, the request is
+	// attempted with each other mirror. DefaultMirrors lists known mirrors of
+	// the deployment CDN.
+	Mirrors []string
+	// ResumeAttempts is the number of times a response that fails partway
+	// through is resumed from the failed offset.
+	ResumeAttempts int
+	// Segments, if greater than 1, is the number of concurrent connections
+	// used to download large content from servers that support Range
+	// requests.
+	Segments int
+	// Retry decides whether failed requests are retried. If nil, then
+	// requests are not retried.
+	Retry RetryPolicy
+	// Credentials, if not nil, adds credentials to each request, such as
+	// cookies or API keys required by authenticated endpoints.
+	Credentials CredentialProvider
+	// RewriteURL, if not nil, is called with the URL of each request before
+	// it is made, and may modify the URL, such as to redirect the request to
+	// a private mirror, or to add a signed token. Returning an error fails
+	// the request.
+	RewriteURL func(u *url.URL) error
+	// AllowURLs, if not empty, lists glob patterns of the only URLs that the
+	// client is permitted to request, such as "https://setup.rbxcdn.com/**".
+	// "**" matches any sequence of characters, and "*" matches any sequence
+	// excluding "/". Patterns are case-sensitive, and other characters,
+	// including "?", match only themselves. Other requests, including
+	// redirects, fail with ErrForbidden.
+	AllowURLs []string
+	// BeforeFetch, if not nil, is called before each request is made.
+	// Returning an error vetoes the request, which fails with ErrForbidden.
+	BeforeFetch func(req FetchRequest) error
+	// UserAgent is the value of the User-Agent header of each request. If
+	// empty, then the header of the HTTP client is used. NewClient sets
+	// UserAgent to DefaultUserAgent. Applications are encouraged to identify
+	// themselves, such as "myapp/1.0 rbxfetch".
+	UserAgent string
+	// Header contains headers added to each request. Headers specified by
+	// the parameters of a chain take precedence.
+	Header http.Header
+	// Hooks are called around each HTTP request made by the client.
+	Hooks RequestHooks
+	// MaxFetches, if greater than zero, limits the number of concurrent
+	// requests. Requests beyond the limit wait in a queue, where interactive
+	// requests are admitted before background requests. See WithPriority.
+	// Time spent waiting in the queue does not count against Timeout, and a
+	// request whose context is done leaves the queue.
+	MaxFetches int
+	// Channel is the deployment channel targeted by the client, such as
+	// "zcanary" or "zintegration". An empty string or "LIVE" targets the
+	// production channel.
+	Channel string
+	// Adaptive specifies whether the chains of a method are reordered
+	// according to their observed success rates and latencies. When enabled,
+	// the chain that most recently succeeded is preferred.
+	Adaptive bool
+	// Race specifies whether the chains of Latest and Live methods are
+	// visited concurrently. Latest returns the result of the first chain to
+	// succeed, cancelling the remaining chains. Live fails as soon as any
+	// chain fails, cancelling the remaining chains.
+	Race bool
+	// ChainTimeouts maps the name of a chain to the time allotted to each
+	// fetch by the chain, such as a short timeout for a version endpoint,
+	// and a long one for a large archive, taking precedence over the Timeout
+	// parameter of the chain's links. Exceeding the timeout fails the chain
+	// with an error detected as ErrTimeout. Unlike the Timeout of the HTTP
+	// client, the timeout of each chain is independent.
+	ChainTimeouts map[string]time.Duration
+	// Metrics, if not nil, receives measurements of each fetch, such as the
+	// number of requests made, and the latency of the fetch.
+	Metrics Metrics
+	// Memory, if not nil, limits the memory used by filters to buffer
+	// content. See MemoryBudget.
+	Memory *MemoryBudget
+	// EagerFetch specifies whether methods that return an io.ReadCloser
+	// request their content before returning, so that errors are returned by
+	// the method rather than by the first Read. May be overridden per request
+	// by the FETCH variable; see WithFetch.
+	EagerFetch bool
+	// PartialLive specifies whether Live returns the GUIDs of chains that
+	// succeeded even when other chains fail.
+	PartialLive bool
+	// LiveTimeout, when PartialLive is set and LiveTimeout is greater than
+	// zero, bounds the time Live waits for its chains to complete. Chains
+	// that have not completed by then are cancelled.
+	LiveTimeout time.Duration
+	// LiveBinaryTypes lists the binary types reported by LiveByBinaryType. If
+	// nil, then DefaultLiveBinaryTypes is used.
+	LiveBinaryTypes []string
+	// StrictLive specifies whether the content of Live chains must be a JSON
+	// string. When false, a JSON object containing a "clientVersionUpload"
+	// field is also accepted.
+	StrictLive bool
+	// Naming specifies the paths of files written by SaveAll. If empty, then
+	// DefaultNaming is used.
+	Naming NamingTemplate
+	// Artifacts specifies the artifacts written by SaveAll. If nil, then
+	// DefaultArtifacts is used.
+	Artifacts []Artifact
+	// Progress, if not nil, is called as Download and DownloadAll write
+	// content to a file.
+	Progress func(p Progress)
+	// CanonicalBuilds specifies whether the lists of builds returned by
+	// Builds, BuildsFor, and similar methods are passed through
+	// CanonicalizeBuilds. Builds walked by BuildsFunc are not affected.
+	CanonicalBuilds bool
+	// EnrichBuilds specifies whether builds returned by Builds and BuildsFor
+	// are enriched with the live version information of the channel. Failure
+	// to retrieve live versions does not cause the builds to fail.
+	EnrichBuilds bool
 
-	methods  map[string][]string
-	chainSet *iofl.ChainSet
+	// config holds the current *clientConfig. configMu serializes changes
+	// to the configuration.
+	config   atomic.Value
+	configMu sync.Mutex
+	stats    chainStatSet
+	timings  methodStatSet
+	queue    fetchQueue
+	decoded  decodedSet
+	derived  httpClientCache
+	allowed  urlPatternCache
+	archives ArchiveIndexCache
+	// envErr is the error of the ApplyEnv call made by NewClient.
+	envErr error
 }
 
-// NewClient returns a client with a default configuration and temporary
-// caching. The Client is initialized with the following filters:
+// NewClient returns a client with a default configuration, temporary caching,
+// 3 resume attempts, DefaultRetryPolicy, and DefaultUserAgent, then applies
+// the environment variables described by ApplyEnv. An error from invalid
+// variables is not returned, but is recorded, and is reported by EnvError;
+// NewClientEnv returns it directly. The Client is initialized with the
+// following filters:
 //
 //     - url: FilterURL
+//     - cache: FilterCache
 //     - file: FilterFile
 //     - zip: FilterZip
+//     - zipglob: FilterZipGlob
 //     - iconscan: FilterIconScan
+//     - xmlpath: FilterXMLPath
+//     - gzip: FilterGzip
+//     - archive: FilterArchive
+//     - studio: FilterStudio
+//     - macstudio: FilterMacStudio
+//
+// Filter parameters may contain the following variables:
+//
+//     - $GUID: The GUID passed to a method.
+//     - $CHANNEL: The name of the client's Channel.
+//     - $CHANNELPATH: "channel/<name>/" for a non-production channel.
+//     - $CHANNELCOMMONPATH: "channel/common/" for a non-production channel.
+//     - $CHANNELSUFFIX: "/channel/<name>" for a non-production channel.
+//     - $CHANNELBUCKET: "/bucket/<name>" for a non-production channel.
+//     - $APPLICATION: The application passed to ClientSettings.
+//     - $PACKAGE: The name of the package passed to Package.
+//     - $LOCALE: The locale passed to APIDocs.
+//     - $PRIORITY: The priority of requests, such as PriorityBackground.
+//     - $FETCH: The fetch mode of requests, such as FetchEager.
+//     - $BINARYTYPE: The binary type passed to LatestFor, LiveFor, or
+//       BuildsFor.
+//     - $PLATFORMPATH: "mac/" for a Mac binary type.
+//     - $VERSIONFILE: The name of the file containing the latest GUID of a
+//       binary type.
 //
 // Using these filters, the following chains are specified:
 //
 //     - Latest: Fetches the GUID of the latest build.
 //     - Live: Fetches the GUID of the latest live 32-bit Studio build.
 //     - Live64: Fetches the GUID of the latest live 64-bit Studio build.
+//     - LiveLegacy: Fetches the GUID of the latest live 32-bit Studio build
+//       from the deprecated versioncompatibility API. A fallback for Live.
+//     - Live64Legacy: Fetches the GUID of the latest live 64-bit Studio build
+//       from the deprecated versioncompatibility API. A fallback for Live64.
+//     - LatestPlayer: Fetches the GUID of the latest player build.
+//     - LivePlayer: Fetches the GUID of the latest live player build.
+//     - LatestBinaryType: Fetches the GUID of the latest build of a given
+//       binary type.
+//     - LiveBinaryType: Fetches the GUID of the latest live build of a given
+//       binary type.
+//     - LatestStudio: Fetches the GUID of the latest 32-bit Studio build from
+//       the versionStudio endpoint.
+//     - LatestStudio64: Fetches the GUID of the latest 64-bit Studio build
+//       from the versionStudio64 endpoint.
+//     - LatestMac: Fetches the GUID of the latest Mac player build.
+//     - LatestMacStudio: Fetches the GUID of the latest Mac Studio build.
 //     - Builds: Fetches a list of builds.
+//     - BuildsMac: Fetches a list of builds of the Mac deployment line, under
+//       the "mac" track.
+//     - BuildsBinaryType: Fetches a list of builds of the deployment line of a
+//       given binary type.
+//     - ClientSettings: Fetches the fast flags of a given application.
 //     - APIDump: Fetches the API dump of a given GUID.
+//     - APIDocs: Fetches the API documentation of a given GUID and locale.
+//     - StudioTranslations: Fetches a zip archive of the Qt translation files
+//       (*.qm) of Studio of a given GUID.
+//     - StudioExtraTranslations: Fetches a zip archive of the translation
+//       tables (*.json) of the extra content of Studio of a given GUID.
+//     - ContentFonts: Fetches the content-fonts package of a given GUID.
+//     - ContentSky: Fetches the content-sky package of a given GUID.
+//     - Shaders: Fetches the shaders package of a given GUID.
+//     - APIDumpText: Fetches the API dump of a given GUID in the legacy text
+//       format, which precedes the JSON format.
+//     - FullAPIDump: Fetches the full API dump of a given GUID, which
+//       includes the default values of properties.
+//     - Player: Fetches the player application package of a given GUID.
+//     - RCCService: Fetches the RCC service package of a given GUID.
+//     - MacStudio: Fetches the Mac Studio application package of a given
+//       GUID.
+//     - Manifest: Fetches the package manifest of a given GUID.
+//     - Package: Fetches a package of a given GUID.
 //     - ReflectionMetadata: Fetches the reflection metadata of a given GUID.
+//     - ReflectionMetadataClasses: Fetches the ReflectionMetadataClasses
+//       section of the reflection metadata of a given GUID.
 //     - ClassImages: Fetches the class icons of a given GUID.
+//     - StudioImages: Fetches a zip archive of the image assets included with
+//       Studio, such as insert and tool images, of a given GUID.
 //     - ExplorerIcons: Fetches the class icons of a given GUID, scanned from
 //       the Studio executable.
+//     - BuildsArchive: Fetches a list of production builds from the
+//       build-archive repository. Used only for the production channel.
+//     - APIDumpArchive: Fetches the API dump of a given GUID from the
+//       build-archive repository.
+//     - ReflectionMetadataArchive: Fetches the reflection metadata of a given
+//       GUID from the build-archive repository.
+//
+// Chains that fetch the files of a build from the deployment CDN list, as
+// variants, the locations of the files shared by every non-production channel,
+// the locations without a channel prefix, and each location with the file
+// name in lowercase, so that builds are found regardless of the layout used
+// when they were deployed. Variants are requested only when a file is not
+// found.
 //
 // Finally, the following methods are specified:
 //
-//     - Builds: Builds
+//     - Builds: Builds, BuildsArchive, BuildsMac
 //     - Latest: Latest
-//     - APIDump: APIDump
-//     - ReflectionMetadata: ReflectionMetadata
+//     - ClientSettings: ClientSettings
+//     - APIDump: APIDump, APIDumpArchive, APIDumpText
+//     - FullAPIDump: FullAPIDump
+//     - APIDocs: APIDocs
+//     - StudioTranslations: StudioTranslations
+//     - StudioExtraTranslations: StudioExtraTranslations
+//     - ContentFonts: ContentFonts
+//     - ContentSky: ContentSky
+//     - Shaders: Shaders
+//     - Manifest: Manifest
+//     - Package: Package
+//     - ReflectionMetadata: ReflectionMetadata, ReflectionMetadataArchive
+//     - ReflectionMetadataClasses: ReflectionMetadataClasses
 //     - ClassImages: ClassImages, ExplorerIcons
-//     - Live: Live64, Live
+//     - StudioImages: StudioImages
+//     - Live: Live64, Live, Live64Legacy, LiveLegacy
+//     - LatestPlayer: LatestPlayer
+//     - LivePlayer: LivePlayer
+//     - Player: Player
+//     - RCCService: RCCService
+//     - LatestBinaryType: LatestBinaryType
+//     - LiveBinaryType: LiveBinaryType
+//     - BuildsBinaryType: BuildsBinaryType
+//     - LatestStudio: LatestStudio
+//     - LatestStudio64: LatestStudio64
+//     - LatestMac: LatestMac
+//     - LatestMacStudio: LatestMacStudio
+//     - MacStudio: MacStudio
 func NewClient() *Client {
-	return &Client{
-		CacheMode: CacheTemp,
-		chainSet:  newDefaultChainSet(),
-		methods:   newDefaultMethods(),
+	client := &Client{
+		CacheMode:      CacheTemp,
+		ResumeAttempts: 3,
+		Retry:          DefaultRetryPolicy,
+		UserAgent:      DefaultUserAgent,
 	}
+	client.config.Store(&clientConfig{
+		methods:  newDefaultMethods(),
+		filters:  newDefaultFilters(),
+		chainSet: newDefaultChainSet(),
+	})
+	client.envErr = client.ApplyEnv()
+	return client
+}
+
+// NewClientEnv returns a client in the same manner as NewClient, along with
+// the error of applying the environment variables, if any. The client is
+// returned regardless, configured by the valid variables.
+func NewClientEnv() (*Client, error) {
+	client := NewClient()
+	return client, client.envErr
+}
+
+// EnvError returns the error of applying the environment variables when the
+// client was created by NewClient, or nil if they were applied successfully.
+func (client *Client) EnvError() error {
+	return client.envErr
+}
+
+// NewClientWithFilters returns a client in the same manner as NewClient, with
+// the given filters registered in addition to the default filters. Returns an
+// error if a filter has the same name as an already registered filter.
+func NewClientWithFilters(filters ...iofl.FilterDef) (*Client, error) {
+	client := NewClient()
+	for _, filter := range filters {
+		if err := client.RegisterFilter(filter.Name, filter.New); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// RegisterFilter registers a filter under the given name, so that it can be
+// referred to by chains configured with SetConfig. Returns an error if a filter
+// of the same name is already registered.
+func (client *Client) RegisterFilter(name string, fn iofl.NewFilter) error {
+	client.configMu.Lock()
+	defer client.configMu.Unlock()
+	current := client.snapshot()
+	// The ChainSet of the current configuration may be in use, so a new one
+	// is created with the additional filter.
+	def := iofl.FilterDef{Name: name, New: fn}
+	set := iofl.NewChainSet(current.filters...)
+	if err := set.Register(def); err != nil {
+		return err
+	}
+	if err := set.SetConfig(current.chainSet.Config()); err != nil {
+		return err
+	}
+	next := *current
+	next.filters = append(current.filters[:len(current.filters):len(current.filters)], def)
+	next.chainSet = set
+	client.config.Store(&next)
+	return nil
 }
 
 // Config is used to configure a Client.
@@ -110,35 +467,29 @@ type Config struct {
 	// client method. The result of each chain in the list may be used, or the
 	// result of the first chain that doesn't error.
 	Methods map[string][]string
+	// Weights specifies the priority of each chain. Within a method, chains
+	// with a greater weight are attempted first. A chain without a weight has
+	// a weight of 1.
+	Weights map[string]float64
+	// Vars specifies variables to be expanded within filter parameters. These
+	// take precedence over variables provided by the client, such as those
+	// derived from the client's Channel.
+	Vars map[string]string
 	iofl.Config
 }
 
 // Config returns a copy of the configuration used by the client.
 func (client *Client) Config() Config {
-	var config Config
-
-	config.Methods = make(map[string][]string, len(client.methods))
-	for name, method := range client.methods {
-		m := make([]string, len(method))
-		copy(m, method)
-		config.Methods[name] = m
-	}
-
-	config.Config = client.chainSet.Config()
-
-	return config
+	return client.snapshot().export()
 }
 
-// SetConfig uses config to configure the client.
+// SetConfig uses config to configure the client. Requests already in progress
+// continue to use the previous configuration.
 func (client *Client) SetConfig(config Config) error {
-	client.methods = make(map[string][]string, len(config.Methods))
-	for name, method := range config.Methods {
-		m := make([]string, len(method))
-		copy(m, method)
-		client.methods[name] = m
-	}
-
-	return client.chainSet.SetConfig(config.Config)
+	return client.UpdateConfig(func(c *Config) error {
+		*c = config
+		return nil
+	})
 }
 
 // applyGUID applies guid to the chain of filters.
@@ -160,32 +511,106 @@ func applyClient(filter iofl.Filter, client *http.Client, cacheMode CacheMode, c
 	type clienter interface {
 		iofl.Filter
 		SetClient(client *http.Client)
+	}
+	type cacher interface {
+		iofl.Filter
 		SetCache(mode CacheMode, loc string)
 	}
 	iofl.Apply(filter, func(f io.ReadCloser) error {
 		if f, ok := f.(clienter); ok {
 			f.SetClient(client)
+		}
+		if f, ok := f.(cacher); ok {
 			f.SetCache(cacheMode, cacheLoc)
 		}
 		return nil
 	})
 }
 
+// applyDedup applies cache deduplication to the chain of filters.
+func applyDedup(filter iofl.Filter, dedup bool) {
+	type deduper interface {
+		iofl.Filter
+		SetDedup(dedup bool)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(deduper); ok {
+			f.SetDedup(dedup)
+		}
+		return nil
+	})
+}
+
+// applyResume applies the number of resume attempts to the chain of filters.
+func applyResume(filter iofl.Filter, n int) {
+	type resumer interface {
+		iofl.Filter
+		SetResume(n int)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(resumer); ok {
+			f.SetResume(n)
+		}
+		return nil
+	})
+}
+
 // resolve resolves the given chain using the given GUID. If guid is empty, then
 // the chain is assumed to be a build endpoint, and will not be cached.
 func (client *Client) resolve(chain string, guid string) (filter iofl.Filter, err error) {
-	f, err := client.chainSet.Resolve(chain, nil)
+	return client.resolveVars("", chain, guid, nil)
+}
+
+// resolveVars resolves the given chain of method in the same manner as
+// resolve, with extra variables that take precedence over the client's
+// variables.
+func (client *Client) resolveVars(method, chain string, guid string, extra map[string]string) (filter iofl.Filter, err error) {
+	f, err := client.snapshot().chainSet.Resolve(chain, nil)
 	if err != nil {
-		return nil, err
+		return nil, newChainError(method, chain, err)
+	}
+	client.prepare(f, method, chain, guid, extra)
+	return f, nil
+}
+
+// prepare applies the state of the client to the filters of the given chain of
+// method. If guid is empty, then the chain is assumed to be a build endpoint,
+// and will not be cached.
+func (client *Client) prepare(f iofl.Filter, method, chain, guid string, extra map[string]string) {
+	vars := client.vars()
+	for k, v := range extra {
+		vars[strings.ToUpper(k)] = v
 	}
+	applyVars(f, vars)
+	applyMirrors(f, client.Mirrors)
+	applyResume(f, client.ResumeAttempts)
+	applyRetry(f, client.Retry)
+	applySegments(f, client.Segments)
+	applyMemory(f, client.Memory)
+	applyCredentials(f, client.Credentials)
+	applyRewrite(f, client.RewriteURL)
+	applyGate(f, client.gate(method, chain, guid))
+	applyHooks(f, client.Hooks)
+	if d, ok := client.ChainTimeouts[chain]; ok {
+		applyTimeout(f, d)
+	}
+	applyDefaultHeader(f, client.defaultHeader())
+	applyArchiveIndexes(f, &client.archives)
+	applyVersionOf(f, func(guid string) (Version, bool) {
+		return client.versionOf(guid, extra)
+	})
+	priority := vars["PRIORITY"]
+	applyQueue(f, func(ctx context.Context) (func(), error) {
+		return client.queue.acquire(ctx, client.MaxFetches, priority)
+	})
 	if guid == "" {
 		// Disable caching of build endpoints.
-		applyClient(f, client.Client, CacheNone, "")
+		applyClient(f, client.httpClient(), CacheNone, "")
 	} else {
-		applyClient(f, client.Client, client.CacheMode, client.CacheLocation)
+		applyClient(f, client.httpClient(), client.CacheMode, client.CacheLocation)
+		applyDedup(f, client.CacheDedup)
 		applyGUID(f, guid)
 	}
-	return f, nil
 }
 
 // Latest returns the GUID of the latest build, which can be passed to other
@@ -195,126 +620,401 @@ func (client *Client) resolve(chain string, guid string) (filter iofl.Filter, er
 //
 // The content of a chain is expected to be a raw GUID.
 func (client *Client) Latest() (guid string, err error) {
-	for _, chain := range client.methods["Latest"] {
-		var f iofl.Filter
-		if f, err = client.resolve(chain, ""); err != nil {
-			continue
-		}
-		var b []byte
-		b, err = ioutil.ReadAll(f)
-		f.Close()
-		if err != nil {
-			continue
+	return client.latest("Latest", nil)
+}
+
+// latest returns the GUID produced by the first chain of method that does not
+// error. vars are passed to each chain.
+func (client *Client) latest(method string, vars map[string]string) (guid string, err error) {
+	if client.Race {
+		return client.latestRace(method, vars)
+	}
+	for _, chain := range client.chains(method) {
+		if guid, err = client.latestChain(nil, method, chain, vars); err == nil {
+			return guid, nil
 		}
-		return string(b), nil
 	}
 	return guid, err
 }
 
+// latestChain fetches a GUID from a single chain of a Latest method. If ctx
+// is not nil, then it is the context of the chain's requests.
+func (client *Client) latestChain(ctx context.Context, method, chain string, vars map[string]string) (guid string, err error) {
+	start := time.Now()
+	f, err := client.resolveVars(method, chain, "", vars)
+	if err != nil {
+		return "", err
+	}
+	if ctx != nil {
+		applyContext(f, ctx)
+	}
+	b, err := readBounded(client.newResult(method, chain, f, false), maxVersionSize)
+	f.Close()
+	if ctx == nil || ctx.Err() == nil {
+		client.observe(method, chain, start, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// LatestMethod returns the GUID produced by the given method, in the same
+// manner as Latest. It may be used with any method that fetches a raw GUID,
+// such as "LatestStudio64" or "LatestMac".
+func (client *Client) LatestMethod(method string) (guid string, err error) {
+	return client.latest(method, nil)
+}
+
 // Live returns the GUIDs of the current live builds, which can be passed to
 // other methods to fetch data corresponding to current live versions. Live
 // visits every configured chain, returning a list of GUIDs, or the first error
 // that occurs. Returns an empty slice if no "Live" method is configured.
 //
-// The content of a chain is expected to be a JSON string containing the GUID.
+// If PartialLive is set, then every chain is visited concurrently, and the
+// GUIDs of successful chains are returned even if other chains fail. In this
+// case, the error is a ChainErrors describing each chain that failed or timed
+// out.
+//
+// A chain with a "FallbackFor" parameter naming another chain of the method is
+// not visited on its own. Instead, it is visited when the named chain fails,
+// in which case its GUID is returned in place of the chain's.
+//
+// The content of a chain is expected to be a JSON string containing the GUID,
+// or, unless StrictLive is set, a JSON object containing the GUID in the
+// "clientVersionUpload" field.
 func (client *Client) Live() (guids []string, err error) {
-	for _, chain := range client.methods["Live"] {
-		var f iofl.Filter
-		if f, err = client.resolve(chain, ""); err != nil {
-			return nil, err
-		}
-		var guid string
-		err = json.NewDecoder(f).Decode(&guid)
-		f.Close()
-		if err != nil {
-			return nil, err
-		}
-		guids = append(guids, guid)
+	return client.live("Live", nil)
+}
+
+// live returns the GUIDs produced by each chain of method. vars are passed to
+// each chain.
+func (client *Client) live(method string, vars map[string]string) (guids []string, err error) {
+	versions, err := client.liveVersions(method, vars)
+	if versions == nil && err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		guids = append(guids, v.ClientVersionUpload)
 	}
-	return guids, nil
+	return guids, err
 }
 
 // Builds returns a list of available builds. Returns nil if no "Builds" method
-// is configured.
+// is configured. Only chains of the default track are used; see BuildsTrack.
 //
-// The content of a chain is expected to be a histlog stream.
+// The content of a chain is expected to be a histlog stream. If a chain fails
+// before listing a build, then the next chain is used. If a chain fails after
+// listing builds, then those builds are returned along with the error.
 func (client *Client) Builds() (builds []Build, err error) {
-	for _, chain := range client.methods["Builds"] {
-		var f iofl.Filter
-		if f, err = client.resolve(chain, ""); err != nil {
+	return client.builds("Builds", "Live", "", nil)
+}
+
+// BuildsChannel returns the builds listed by the deployment history of the
+// given deployment channel, such as "zcanary", regardless of the client's
+// Channel. Each build is tagged with the channel. Returns nil if no "Builds"
+// method is configured.
+//
+// A chain with a "Channel" parameter lists only the builds of the named
+// channel, and is skipped for other channels. For example, the default
+// BuildsArchive chain lists only production builds, so it is not used as a
+// fallback for other channels.
+func (client *Client) BuildsChannel(channel string) (builds []Build, err error) {
+	vars := map[string]string{}
+	channelVars(channel, vars)
+	return client.builds("Builds", "Live", "", vars)
+}
+
+// BuildsTrack returns the builds listed by the chains of the "Builds" method
+// that belong to the given track, such as "mac". A chain belongs to a track
+// when one of its links has a "Track" parameter with the track's name. Chains
+// without a track belong to the default track, which is used by Builds. Each
+// build is labeled with the track.
+func (client *Client) BuildsTrack(track string) (builds []Build, err error) {
+	return client.builds("Builds", "Live", track, nil)
+}
+
+// BuildsFunc calls fn with each build listed by the "Builds" method, in the
+// order in which they appear in the deployment history, which is usually
+// chronological. The history is read and lexed incrementally, and reading
+// stops as soon as fn returns false, so a caller looking for a particular
+// build need not wait for the entire history to be processed.
+func (client *Client) BuildsFunc(fn func(build Build) bool) error {
+	return client.walkBuilds("Builds", "Live", "", nil, fn)
+}
+
+// BuildsStream returns the full content of the deployment history produced by
+// the "Builds" method, as a histlog stream. Unlike Builds, the stream retains
+// every token of the history, including the status of each job and any
+// content that could not be interpreted. Returns nil if no "Builds" method is
+// configured. Only chains of the default track are used. As with Builds, the
+// tokens read before a failure are returned along with the error.
+func (client *Client) BuildsStream() (stream histlog.Stream, err error) {
+	return client.stream("Builds", "", nil)
+}
+
+// builds returns the builds produced by the first chain of method of the given
+// track that does not error before producing a build, along with the error of
+// the chain, if any. vars are passed to each chain. If EnrichBuilds is set,
+// then the builds are enriched by the live method.
+func (client *Client) builds(method, live, track string, vars map[string]string) (builds []Build, err error) {
+	err = client.walkBuilds(method, live, track, vars, func(build Build) bool {
+		builds = append(builds, build)
+		return true
+	})
+	if client.CanonicalBuilds && len(builds) > 0 {
+		builds = CanonicalizeBuilds(builds)
+	}
+	return builds, err
+}
+
+// walkBuilds calls fn with each build produced by the first chain of method
+// of the given track that does not error before producing a build. Walking
+// stops when fn returns false.
+//
+// If a chain errors after producing a build, then walking stops and the error
+// is returned. The remaining chains are not used, since their histories need
+// not continue from the builds already produced.
+func (client *Client) walkBuilds(method, live, track string, vars map[string]string, fn func(build Build) bool) (err error) {
+	var enrich func(b *Build)
+	if client.EnrichBuilds {
+		enrich = client.enricher(live, vars)
+	}
+	channel := client.channel(vars)
+	mac := strings.EqualFold(track, "mac") || vars["PLATFORMPATH"] == "mac/"
+	for _, chain := range client.historyChains(method, track, vars) {
+		start := time.Now()
+		var rc io.ReadCloser
+		if rc, err = client.history(method, chain, vars); err != nil {
+			client.observe(method, chain, start, err)
 			continue
 		}
-		var b []byte
-		b, err = ioutil.ReadAll(f)
-		f.Close()
-		if err != nil {
+		scanner := buildScanner{channel: channel, track: track, mac: mac}
+		produced := false
+		stopped := false
+		emit := func(build *Build) bool {
+			if build == nil {
+				return true
+			}
+			if enrich != nil {
+				enrich(build)
+			}
+			produced = true
+			if !fn(*build) {
+				stopped = true
+				return false
+			}
+			return true
+		}
+		err = scanHistory(rc, func(token histlog.Token, line string) bool {
+			return emit(scanner.scan(token, line))
+		})
+		if err == nil && !stopped {
+			emit(scanner.flush())
+		}
+		rc.Close()
+		client.observe(method, chain, start, err)
+		if err != nil && !produced {
+			continue
+		}
+		return err
+	}
+	return err
+}
+
+// method runs each chain of the given method for guid, returning the first
+// that resolves.
+func (client *Client) method(method, guid string) (rc io.ReadCloser, err error) {
+	return client.methodVars(method, guid, nil)
+}
+
+// methodVars runs each chain of the given method for guid with extra
+// variables, returning the first that resolves. If the resolved chain fails
+// before producing any content, then the result falls back to the remaining
+// chains. If fetching is eager, then the result is read from before returning.
+func (client *Client) methodVars(method, guid string, vars map[string]string) (rc io.ReadCloser, err error) {
+	chains := client.chains(method)
+	for i, chain := range chains {
+		var f iofl.Filter
+		if f, err = client.resolveVars(method, chain, guid, vars); err != nil {
 			continue
 		}
-		stream := histlog.Lex(b)
-		for i := 0; i < len(stream); i++ {
-			switch job := stream[i].(type) {
-			case *histlog.Job:
-				builds = append(builds, Build{
-					Type:    job.Build,
-					GUID:    job.GUID,
-					Date:    job.Time,
-					Version: job.Version,
-				})
+		r := client.newResult(method, chain, f, client.Adaptive)
+		r.fallback = client.fallback(method, guid, vars, chains[i+1:])
+		if client.eager(vars) {
+			if err = r.begin(); err != nil {
+				r.Close()
+				return nil, err
 			}
 		}
-		return builds, nil
+		return r.random(), nil
 	}
 	return nil, err
 }
 
+// fallback returns a function that resolves each of chains in turn, skipping
+// those that fail to resolve.
+func (client *Client) fallback(method, guid string, vars map[string]string, chains []string) func() (string, iofl.Filter) {
+	return func() (string, iofl.Filter) {
+		for len(chains) > 0 {
+			chain := chains[0]
+			chains = chains[1:]
+			if f, err := client.resolveVars(method, chain, guid, vars); err == nil {
+				return chain, f
+			}
+		}
+		return "", nil
+	}
+}
+
 // APIDump returns the API dump of the given GUID. Returns nil if no "APIDump"
 // method is configured.
+//
+// The dump of an older build may be available only in the legacy text
+// format. The format of the result is reported by its Formatter, which
+// returns FormatText for such a dump.
 func (client *Client) APIDump(guid string) (rc io.ReadCloser, err error) {
-	for _, chain := range client.methods["APIDump"] {
-		var f iofl.Filter
-		if f, err = client.resolve(chain, guid); err != nil {
-			continue
-		}
-		return f, nil
+	return client.method("APIDump", guid)
+}
+
+// FullAPIDump returns the full API dump of the given GUID, which additionally
+// includes the default values of properties. Returns nil if no "FullAPIDump"
+// method is configured. The full dump is not available for older builds.
+func (client *Client) FullAPIDump(guid string) (rc io.ReadCloser, err error) {
+	return client.method("FullAPIDump", guid)
+}
+
+// DefaultLocale is the locale of the API documentation used by APIDocs when no
+// locale is given.
+const DefaultLocale = "en-us"
+
+// APIDocs returns the API documentation bundle of the given GUID, in the given
+// locale, such as "en-us". If locale is empty, then DefaultLocale is used.
+// Returns nil if no "APIDocs" method is configured.
+//
+// The locale is available to chains as the $LOCALE variable.
+func (client *Client) APIDocs(guid, locale string) (rc io.ReadCloser, err error) {
+	if locale == "" {
+		locale = DefaultLocale
 	}
-	return nil, err
+	return client.methodVars("APIDocs", guid, map[string]string{"LOCALE": strings.ToLower(locale)})
 }
 
 // ReflectionMetadata returns the reflection metadata for the given GUID.
 // Returns nil if no "ReflectionMetadata" method is configured.
 func (client *Client) ReflectionMetadata(guid string) (rc io.ReadCloser, err error) {
-	for _, chain := range client.methods["ReflectionMetadata"] {
-		var f iofl.Filter
-		if f, err = client.resolve(chain, guid); err != nil {
-			continue
-		}
-		return f, nil
-	}
-	return nil, err
+	return client.method("ReflectionMetadata", guid)
 }
 
 // ClassImages returns the class explorer icons for the given GUID. Returns nil
 // if no "ClassImages" method is configured.
 func (client *Client) ClassImages(guid string) (rc io.ReadCloser, err error) {
-	for _, chain := range client.methods["ClassImages"] {
-		var f iofl.Filter
-		if f, err = client.resolve(chain, guid); err != nil {
-			continue
+	return client.method("ClassImages", guid)
+}
+
+// StudioImages returns a zip archive containing the image assets included with
+// Studio for the given GUID. Returns nil if no "StudioImages" method is
+// configured.
+func (client *Client) StudioImages(guid string) (rc io.ReadCloser, err error) {
+	return client.method("StudioImages", guid)
+}
+
+// StudioTranslations returns a zip archive containing the localization
+// resources of Studio for the given GUID: the files produced by the
+// "StudioTranslations" method, which are the Qt translation files, followed by
+// those of the "StudioExtraTranslations" method, which are the translation
+// tables. An unconfigured method is skipped. Returns nil if neither method is
+// configured.
+func (client *Client) StudioTranslations(guid string) (rc io.ReadCloser, err error) {
+	var archives []io.ReadCloser
+	defer func() {
+		for _, archive := range archives {
+			archive.Close()
+		}
+	}()
+	for _, method := range []string{"StudioTranslations", "StudioExtraTranslations"} {
+		archive, err := client.method(method, guid)
+		if err != nil {
+			return nil, err
+		}
+		if archive != nil {
+			archives = append(archives, archive)
 		}
-		return f, nil
 	}
-	return nil, err
+	if len(archives) == 0 {
+		return nil, nil
+	}
+	return mergeZips(archives, client.Memory)
 }
 
 // Method runs the configured method for the given GUID. Returns nil if no such
 // method is configured.
+//
+// The chains of the method are attempted in order. If reading from a chain
+// fails before any content is produced, such as when a build has expired from
+// the CDN, then the next chain is attempted.
+//
+// The result of Method, and other methods that return an io.ReadCloser,
+// implements Timer and Formatter. When the content supports random access,
+// such as when it is cached, the result also implements RandomReader.
 func (client *Client) Method(method, guid string) (rc io.ReadCloser, err error) {
-	for _, chain := range client.methods[method] {
-		var f iofl.Filter
-		if f, err = client.resolve(chain, guid); err != nil {
-			continue
-		}
-		return f, nil
-	}
-	return nil, err
+	return client.method(method, guid)
+}
+
+// GUIDClient is a handle to a Client bound to a single GUID.
+type GUIDClient struct {
+	client *Client
+	guid   string
+}
+
+// ForGUID returns a handle that runs methods of the client for the given GUID.
+func (client *Client) ForGUID(guid string) *GUIDClient {
+	return &GUIDClient{client: client, guid: guid}
+}
+
+// GUID returns the GUID to which the handle is bound.
+func (g *GUIDClient) GUID() string {
+	return g.guid
+}
+
+// APIDump returns the API dump of the bound GUID.
+func (g *GUIDClient) APIDump() (rc io.ReadCloser, err error) {
+	return g.client.APIDump(g.guid)
+}
+
+// APIDocs returns the API documentation of the bound GUID in the given
+// locale.
+func (g *GUIDClient) APIDocs(locale string) (rc io.ReadCloser, err error) {
+	return g.client.APIDocs(g.guid, locale)
+}
+
+// FullAPIDump returns the full API dump of the bound GUID.
+func (g *GUIDClient) FullAPIDump() (rc io.ReadCloser, err error) {
+	return g.client.FullAPIDump(g.guid)
+}
+
+// ReflectionMetadata returns the reflection metadata of the bound GUID.
+func (g *GUIDClient) ReflectionMetadata() (rc io.ReadCloser, err error) {
+	return g.client.ReflectionMetadata(g.guid)
+}
+
+// ClassImages returns the class explorer icons of the bound GUID.
+func (g *GUIDClient) ClassImages() (rc io.ReadCloser, err error) {
+	return g.client.ClassImages(g.guid)
+}
+
+// StudioImages returns the Studio image assets of the bound GUID.
+func (g *GUIDClient) StudioImages() (rc io.ReadCloser, err error) {
+	return g.client.StudioImages(g.guid)
+}
+
+// StudioTranslations returns the Studio localization resources of the bound
+// GUID.
+func (g *GUIDClient) StudioTranslations() (rc io.ReadCloser, err error) {
+	return g.client.StudioTranslations(g.guid)
+}
+
+// Method runs the configured method for the bound GUID.
+func (g *GUIDClient) Method(method string) (rc io.ReadCloser, err error) {
+	return g.client.Method(method, g.guid)
 }
@@ -0,0 +1,344 @@
+package rbxfetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// DiffSet lists the names of entries that differ between two maps.
+type DiffSet struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty returns whether the set contains no differences.
+func (d DiffSet) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffKeys compares the entries of a and b, which must be maps with string
+// keys. Entries are compared with reflect.DeepEqual.
+func diffKeys(a, b interface{}) (d DiffSet) {
+	va := reflect.ValueOf(a)
+	vb := reflect.ValueOf(b)
+	for _, k := range va.MapKeys() {
+		y := vb.MapIndex(k)
+		if !y.IsValid() {
+			d.Removed = append(d.Removed, k.String())
+		} else if !reflect.DeepEqual(va.MapIndex(k).Interface(), y.Interface()) {
+			d.Changed = append(d.Changed, k.String())
+		}
+	}
+	for _, k := range vb.MapKeys() {
+		if !va.MapIndex(k).IsValid() {
+			d.Added = append(d.Added, k.String())
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+// ConfigDiff describes the differences between two Configs.
+type ConfigDiff struct {
+	Methods DiffSet
+	Weights DiffSet
+	Vars    DiffSet
+	Chains  DiffSet
+}
+
+// Empty returns whether the diff contains no differences.
+func (d ConfigDiff) Empty() bool {
+	return d.Methods.Empty() && d.Weights.Empty() && d.Vars.Empty() && d.Chains.Empty()
+}
+
+// Diff returns the differences from config to other. For example, an entry
+// is Added if it is present in other, but not in config.
+func (config Config) Diff(other Config) ConfigDiff {
+	return ConfigDiff{
+		Methods: diffKeys(config.Methods, other.Methods),
+		Weights: diffKeys(config.Weights, other.Weights),
+		Vars:    diffKeys(config.Vars, other.Vars),
+		Chains:  diffKeys(config.Chains, other.Chains),
+	}
+}
+
+// LoadConfig decodes a Config from r. The content is a JSON object with the
+// following structure, where each field is optional:
+//
+//	{
+//		"Methods": {"<method>": ["<chain>", ...], ...},
+//		"Weights": {"<chain>": <number>, ...},
+//		"Vars": {"<name>": "<value>", ...},
+//		"Chains": {
+//			"<chain>": [
+//				{"Filter": "<filter>", "Params": {"<param>": <value>, ...}},
+//				...
+//			],
+//			...
+//		}
+//	}
+//
+// The links of a chain are listed from the source to the final filter, and the
+// parameters of each link are those documented by the corresponding filter.
+// Unknown fields are rejected. This is the same structure produced by
+// Config.WriteTo.
+func LoadConfig(r io.Reader) (config Config, err error) {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	if err = d.Decode(&config); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// WriteTo encodes the configuration as JSON to w, in the structure described
+// by LoadConfig.
+func (config Config) WriteTo(w io.Writer) (n int64, err error) {
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetIndent("", "\t")
+	if err = e.Encode(config); err != nil {
+		return 0, err
+	}
+	return buf.WriteTo(w)
+}
+
+// LoadConfigFile decodes a Config from the JSON file at path with LoadConfig,
+// and sets it as the client's configuration.
+func (client *Client) LoadConfigFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	config, err := LoadConfig(f)
+	if err != nil {
+		return err
+	}
+	return client.SetConfig(config)
+}
+
+// Merge returns a copy of config, with each entry of other added to it. An
+// entry of other replaces the entry of the same name within config. For
+// example, merging a Config containing only an "APIDump" chain replaces only
+// the APIDump chain, leaving the remaining chains and methods unchanged.
+func (config Config) Merge(other Config) Config {
+	merged := Config{
+		Methods: make(map[string][]string, len(config.Methods)+len(other.Methods)),
+		Config:  iofl.Config{Chains: make(map[string]iofl.Chain, len(config.Chains)+len(other.Chains))},
+	}
+	for _, c := range []Config{config, other} {
+		for name, method := range c.Methods {
+			merged.Methods[name] = append([]string(nil), method...)
+		}
+		for chain, weight := range c.Weights {
+			if merged.Weights == nil {
+				merged.Weights = map[string]float64{}
+			}
+			merged.Weights[chain] = weight
+		}
+		for name, value := range c.Vars {
+			if merged.Vars == nil {
+				merged.Vars = map[string]string{}
+			}
+			merged.Vars[name] = value
+		}
+		for name, chain := range c.Chains {
+			merged.Chains[name] = append(iofl.Chain(nil), chain...)
+		}
+	}
+	return merged
+}
+
+// clientConfig is a snapshot of the configuration of a Client. A snapshot is
+// not modified once it is in use; a change to the configuration replaces the
+// snapshot as a whole, so that the configuration can be read by requests while
+// it is being changed.
+type clientConfig struct {
+	methods map[string][]string
+	weights map[string]float64
+	vars    map[string]string
+	// filters lists the filters registered with chainSet, which does not
+	// expose them.
+	filters  []iofl.FilterDef
+	chainSet *iofl.ChainSet
+}
+
+// snapshot returns the current configuration of the client, which must not be
+// modified.
+func (client *Client) snapshot() *clientConfig {
+	if config, ok := client.config.Load().(*clientConfig); ok {
+		return config
+	}
+	return &clientConfig{chainSet: &iofl.ChainSet{}}
+}
+
+// export returns a copy of the configuration as a Config.
+func (c *clientConfig) export() Config {
+	var config Config
+
+	config.Methods = make(map[string][]string, len(c.methods))
+	for name, method := range c.methods {
+		m := make([]string, len(method))
+		copy(m, method)
+		config.Methods[name] = m
+	}
+
+	if c.weights != nil {
+		config.Weights = make(map[string]float64, len(c.weights))
+		for chain, weight := range c.weights {
+			config.Weights[chain] = weight
+		}
+	}
+
+	if c.vars != nil {
+		config.Vars = make(map[string]string, len(c.vars))
+		for name, value := range c.vars {
+			config.Vars[name] = value
+		}
+	}
+
+	config.Config = c.chainSet.Config()
+
+	return config
+}
+
+// UpdateConfig changes the configuration of the client atomically. fn is
+// called with a copy of the current configuration, which it modifies in
+// place. The result then replaces the configuration of the client, unless fn
+// returns an error, which is returned. Concurrent updates are applied one at a
+// time, so that no update is lost, while requests already in progress continue
+// to use the previous configuration. fn must not itself change the
+// configuration of the client.
+//
+// SetConfig, MergeConfig, AddChain, AddMethod, DefineChain, and SetMethod are
+// each an update made by UpdateConfig.
+func (client *Client) UpdateConfig(fn func(config *Config) error) error {
+	client.configMu.Lock()
+	defer client.configMu.Unlock()
+	current := client.snapshot()
+	config := current.export()
+	if err := fn(&config); err != nil {
+		return err
+	}
+
+	next := &clientConfig{filters: current.filters}
+	next.methods = make(map[string][]string, len(config.Methods))
+	for name, method := range config.Methods {
+		m := make([]string, len(method))
+		copy(m, method)
+		next.methods[name] = m
+	}
+	if config.Weights != nil {
+		next.weights = make(map[string]float64, len(config.Weights))
+		for chain, weight := range config.Weights {
+			next.weights[chain] = weight
+		}
+	}
+	if config.Vars != nil {
+		next.vars = make(map[string]string, len(config.Vars))
+		for name, value := range config.Vars {
+			next.vars[strings.ToUpper(name)] = value
+		}
+	}
+	// A copy of the ChainSet shares the registered filters, while setting
+	// its configuration replaces only the chains of the copy.
+	set := *current.chainSet
+	if err := set.SetConfig(config.Config); err != nil {
+		return err
+	}
+	next.chainSet = &set
+	client.config.Store(next)
+	return nil
+}
+
+// MergeConfig merges config into the client's configuration, as described by
+// Config.Merge.
+func (client *Client) MergeConfig(config Config) error {
+	return client.UpdateConfig(func(c *Config) error {
+		*c = c.Merge(config)
+		return nil
+	})
+}
+
+// AddChain adds a chain of the given name to the client's configuration,
+// replacing any existing chain of the same name. Returns an error if a link of
+// the chain refers to a filter that is not registered.
+func (client *Client) AddChain(name string, chain iofl.Chain) error {
+	return client.putChain(name, chain, true)
+}
+
+// putChain sets the chain of the given name. If replace is false, then
+// returns an error if the chain is already defined.
+func (client *Client) putChain(name string, chain iofl.Chain, replace bool) error {
+	for i, link := range chain {
+		if !client.hasFilter(link.Filter) {
+			return fmt.Errorf("%s[%d]: unknown filter %q", name, i, link.Filter)
+		}
+	}
+	return client.UpdateConfig(func(config *Config) error {
+		if _, ok := config.Chains[name]; ok && !replace {
+			return fmt.Errorf("chain %q already defined", name)
+		}
+		config.Chains[name] = append(iofl.Chain(nil), chain...)
+		return nil
+	})
+}
+
+// AddMethod appends the given chains to the chains of a method of the client's
+// configuration, creating the method if it does not exist. Appended chains are
+// attempted after the existing chains of the method. Returns an error if a
+// chain is not defined.
+func (client *Client) AddMethod(method string, chains ...string) error {
+	return client.putMethod(method, chains, true)
+}
+
+// putMethod sets the chains of the given method. If extend is true, then the
+// chains are appended to the existing chains of the method. Otherwise, they
+// replace the existing chains, and the method is removed if no chains are
+// given.
+func (client *Client) putMethod(method string, chains []string, extend bool) error {
+	return client.UpdateConfig(func(config *Config) error {
+		for _, chain := range chains {
+			if _, ok := config.Chains[chain]; !ok {
+				return fmt.Errorf("method %s: unknown chain %q", method, chain)
+			}
+		}
+		switch {
+		case extend:
+			config.Methods[method] = append(config.Methods[method], chains...)
+		case len(chains) == 0:
+			delete(config.Methods, method)
+		default:
+			config.Methods[method] = append([]string(nil), chains...)
+		}
+		return nil
+	})
+}
+
+// DefineChain defines a new chain of the given name, preserving the remainder
+// of the client's configuration. Unlike AddChain, returns an error if a chain
+// of the same name is already defined, so that an embedding application does
+// not inadvertently replace a chain of the default configuration.
+func (client *Client) DefineChain(name string, chain iofl.Chain) error {
+	return client.putChain(name, chain, false)
+}
+
+// SetMethod sets the chains of the given method, replacing any existing
+// chains of the method, and preserving the remainder of the client's
+// configuration. If no chains are given, then the method is removed. Returns
+// an error if a chain is not defined.
+func (client *Client) SetMethod(method string, chains ...string) error {
+	return client.putMethod(method, chains, false)
+}
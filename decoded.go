@@ -0,0 +1,104 @@
+package rbxfetch
+
+import (
+	"sync"
+
+	"github.com/robloxapi/rbxdump"
+)
+
+// decodedCacheSize is the number of decoded artifacts retained by a client.
+const decodedCacheSize = 4
+
+// decodedSet retains the most recently used artifacts decoded by a client.
+type decodedSet struct {
+	mu sync.Mutex
+	// keys is the order in which each key was used, least recent first.
+	keys []string
+	m    map[string]interface{}
+}
+
+func (s *decodedSet) get(key string) (v interface{}, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok = s.m[key]; ok {
+		s.touch(key)
+	}
+	return v, ok
+}
+
+func (s *decodedSet) set(key string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = map[string]interface{}{}
+	}
+	if _, ok := s.m[key]; ok {
+		s.touch(key)
+	} else {
+		s.keys = append(s.keys, key)
+	}
+	s.m[key] = v
+	for len(s.keys) > decodedCacheSize {
+		delete(s.m, s.keys[0])
+		s.keys = s.keys[1:]
+	}
+}
+
+// touch marks key as the most recently used.
+func (s *decodedSet) touch(key string) {
+	for i, k := range s.keys {
+		if k == key {
+			copy(s.keys[i:], s.keys[i+1:])
+			s.keys[len(s.keys)-1] = key
+			return
+		}
+	}
+}
+
+// APIDumpDecoded returns the decoded API dump of the given GUID, as fetched by
+// the APIDump method. A dump in the legacy text format is decoded as such.
+// Returns nil if no "APIDump" method is configured.
+//
+// The decoded dumps of recently requested GUIDs are retained by the client,
+// so that they are not fetched and decoded again. Each call returns a copy,
+// which may be modified freely. If the client's CacheDerived is set, then
+// decoded dumps are also stored in the cache; see DerivedAPIDump.
+func (client *Client) APIDumpDecoded(guid string) (root *rbxdump.Root, err error) {
+	key := "APIDump|" + guid
+	if v, ok := client.decoded.get(key); ok {
+		return v.(*rbxdump.Root).Copy(), nil
+	}
+	v, err := client.derive(DerivedAPIDump, guid)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	root = v.(*rbxdump.Root)
+	if guid != "" {
+		client.decoded.set(key, root)
+		root = root.Copy()
+	}
+	return root, nil
+}
+
+// ReflectionMetadataDecoded returns the decoded reflection metadata of the
+// given GUID, as fetched by the ReflectionMetadata method. Returns nil if no
+// "ReflectionMetadata" method is configured.
+//
+// As with APIDumpDecoded, the decoded metadata of recently requested GUIDs is
+// retained by the client, and each call returns a copy.
+func (client *Client) ReflectionMetadataDecoded(guid string) (rmd *ReflectionMetadata, err error) {
+	key := "ReflectionMetadata|" + guid
+	if v, ok := client.decoded.get(key); ok {
+		return v.(*ReflectionMetadata).Copy(), nil
+	}
+	v, err := client.derive(DerivedReflectionMetadata, guid)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	rmd = v.(*ReflectionMetadata)
+	if guid != "" {
+		client.decoded.set(key, rmd)
+		rmd = rmd.Copy()
+	}
+	return rmd, nil
+}
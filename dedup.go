@@ -0,0 +1,82 @@
+package rbxfetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DedupCache deduplicates the content already stored in the client's cache
+// directory, in the same manner as CacheDedup: each cached file is moved to a
+// blob named by the hash of its content, and linked back to its original
+// location. Files with identical content then share a single blob. Returns
+// the number of bytes reclaimed.
+//
+// This is useful for converting a cache populated without CacheDedup. Content
+// stored afterwards is deduplicated only if CacheDedup is set.
+func (client *Client) DedupCache() (saved int64, err error) {
+	dir := cacheDir(client.CacheMode, client.CacheLocation)
+	if dir == "" {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() ||
+			name == cacheFormatFile ||
+			strings.HasPrefix(name, tempPrefix) ||
+			strings.HasSuffix(name, partialSuffix) ||
+			strings.HasSuffix(name, metaSuffix) {
+			continue
+		}
+		n, e := dedupFile(filepath.Join(dir, name))
+		saved += n
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return saved, err
+}
+
+// dedupFile links path to the blob of its content. Returns the size of the
+// file if an identical blob already existed. Should deduplication fail, then
+// the file is left in place.
+func dedupFile(path string) (saved int64, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	file.Close()
+	if err != nil {
+		return 0, err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	blob := filepath.Join(filepath.Dir(path), blobDirName, hash)
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if blobStat, err := os.Stat(blob); err == nil {
+		if os.SameFile(stat, blobStat) {
+			// Already deduplicated.
+			return 0, nil
+		}
+		saved = size
+	}
+	if file, err = storeBlob(path, path, hash); err != nil {
+		return 0, err
+	}
+	file.Close()
+	return saved, nil
+}
@@ -0,0 +1,67 @@
+package rbxfetch_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robloxapi/rbxfetch"
+)
+
+func TestDedupCache(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"version-a-ReflectionMetadata.xml": "<roblox>a</roblox>",
+		"version-b-ReflectionMetadata.xml": "<roblox>a</roblox>",
+		"version-c-ReflectionMetadata.xml": "<roblox>c</roblox>",
+		// Partial content is still being written, and is left alone.
+		"version-d-ReflectionMetadata.xml.partial": "<roblox>a</roblox>",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := rbxfetch.NewClient()
+	client.CacheMode = rbxfetch.CacheCustom
+	client.CacheLocation = dir
+
+	saved, err := client.DedupCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := int64(len(files["version-b-ReflectionMetadata.xml"])); saved != expected {
+		t.Errorf("expected %d bytes saved, got %d", expected, saved)
+	}
+	// Converting again finds nothing more to reclaim.
+	if saved, err := client.DedupCache(); err != nil || saved != 0 {
+		t.Errorf("expected nothing saved again, got %d, %v", saved, err)
+	}
+
+	stat := func(name string) os.FileInfo {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info
+	}
+	a := stat("version-a-ReflectionMetadata.xml")
+	if !os.SameFile(a, stat("version-b-ReflectionMetadata.xml")) {
+		t.Error("expected identical files to share a blob")
+	}
+	if os.SameFile(a, stat("version-c-ReflectionMetadata.xml")) {
+		t.Error("expected different files to remain separate")
+	}
+	if os.SameFile(a, stat("version-d-ReflectionMetadata.xml.partial")) {
+		t.Error("expected partial content to be left alone")
+	}
+	for name, content := range files {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, b)
+		}
+	}
+}
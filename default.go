@@ -1,58 +1,263 @@
 package rbxfetch
 
 import (
+	"strings"
+
 	"github.com/anaminus/iofl"
 )
 
+// DefaultMethods returns the methods of the default configuration, mapping the
+// name of each method to the names of its chains.
+func DefaultMethods() map[string][]string {
+	return newDefaultMethods()
+}
+
+// DefaultChains returns the chains of the default configuration.
+func DefaultChains() map[string]iofl.Chain {
+	return newDefaultChains()
+}
+
+// DefaultFilters returns the definitions of the filters registered with a
+// client returned by NewClient.
+func DefaultFilters() []iofl.FilterDef {
+	return newDefaultFilters()
+}
+
 func newDefaultMethods() map[string][]string {
 	return map[string][]string{
-		"Builds":             {"Builds"},
-		"Latest":             {"Latest"},
-		"APIDump":            {"APIDump"},
-		"ReflectionMetadata": {"ReflectionMetadata"},
-		"ClassImages":        {"ClassImages", "ExplorerIcons"},
-		"Live":               {"Live64", "Live"},
+		"Builds":                    {"Builds", "BuildsArchive", "BuildsMac"},
+		"Latest":                    {"Latest"},
+		"ClientSettings":            {"ClientSettings"},
+		"APIDump":                   {"APIDump", "APIDumpArchive", "APIDumpText"},
+		"FullAPIDump":               {"FullAPIDump"},
+		"APIDocs":                   {"APIDocs"},
+		"StudioTranslations":        {"StudioTranslations"},
+		"StudioExtraTranslations":   {"StudioExtraTranslations"},
+		"ContentFonts":              {"ContentFonts"},
+		"ContentSky":                {"ContentSky"},
+		"Shaders":                   {"Shaders"},
+		"Manifest":                  {"Manifest"},
+		"Package":                   {"Package"},
+		"ReflectionMetadata":        {"ReflectionMetadata", "ReflectionMetadataArchive"},
+		"ClassImages":               {"ClassImages", "ExplorerIcons"},
+		"ReflectionMetadataClasses": {"ReflectionMetadataClasses"},
+		"StudioImages":              {"StudioImages"},
+		"Live":                      {"Live64", "Live", "Live64Legacy", "LiveLegacy"},
+		"LatestPlayer":              {"LatestPlayer"},
+		"LivePlayer":                {"LivePlayer"},
+		"Player":                    {"Player"},
+		"RCCService":                {"RCCService"},
+		"LatestBinaryType":          {"LatestBinaryType"},
+		"LiveBinaryType":            {"LiveBinaryType"},
+		"BuildsBinaryType":          {"BuildsBinaryType"},
+		"LatestStudio":              {"LatestStudio"},
+		"LatestStudio64":            {"LatestStudio64"},
+		"LatestMac":                 {"LatestMac"},
+		"LatestMacStudio":           {"LatestMacStudio"},
+		"MacStudio":                 {"MacStudio"},
 	}
 }
 
 func newDefaultChainSet() *iofl.ChainSet {
-	return iofl.NewChainSet(
-		iofl.FilterDef{Name: "url", New: NewFilterURL},
-		iofl.FilterDef{Name: "file", New: NewFilterFile},
-		iofl.FilterDef{Name: "zip", New: NewFilterZip},
-		iofl.FilterDef{Name: "iconscan", New: NewFilterIconScan},
-	).MustSetConfig(
-		iofl.Config{
-			Chains: map[string]iofl.Chain{
-				"Latest": {
-					{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/versionQTStudio"}},
-				},
-				"Live": {
-					{Filter: "url", Params: iofl.Params{"URL": "https://versioncompatibility.api.roblox.com/GetCurrentClientVersionUpload/?apiKey=76e5a40c-3ae1-4028-9f10-7c62520bd94f&binaryType=WindowsStudio"}},
-				},
-				"Live64": {
-					{Filter: "url", Params: iofl.Params{"URL": "https://versioncompatibility.api.roblox.com/GetCurrentClientVersionUpload/?apiKey=76e5a40c-3ae1-4028-9f10-7c62520bd94f&binaryType=WindowsStudio64"}},
-				},
-				"Builds": {
-					{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/DeployHistory.txt"}},
-				},
-				"APIDump": {
-					{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/$GUID-API-Dump.json"}},
-				},
-				"ReflectionMetadata": {
-					{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/$GUID-RobloxStudio.zip"}},
-					{Filter: "zip", Params: iofl.Params{"File": "ReflectionMetadata.xml"}},
-				},
-				"ClassImages": {
-					{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/$GUID-content-textures2.zip#ClassImages.PNG"}},
-					{Filter: "zip", Params: iofl.Params{"File": "ClassImages.PNG"}},
-				},
-				"ExplorerIcons": {
-					{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/$GUID-RobloxStudio.zip#RobloxStudioBeta.exe"}},
-					{Filter: "zip", Params: iofl.Params{"File": "RobloxStudioBeta.exe"}},
-					{Filter: "iconscan", Params: iofl.Params{"Size": 16}},
-				},
-			},
-		},
+	return iofl.NewChainSet(newDefaultFilters()...).MustSetConfig(
+		iofl.Config{Chains: newDefaultChains()},
 	)
 }
+
+// DefaultConfig returns the configuration of a client returned by NewClient.
+// Each call returns a new copy, which may be modified freely and passed to
+// SetConfig.
+func DefaultConfig() Config {
+	return Config{
+		Methods: newDefaultMethods(),
+		Config:  iofl.Config{Chains: newDefaultChains()},
+	}
+}
+
+func newDefaultFilters() []iofl.FilterDef {
+	return []iofl.FilterDef{
+		{Name: "url", New: NewFilterURL},
+		{Name: "cache", New: NewFilterCache},
+		{Name: "file", New: NewFilterFile},
+		{Name: "zip", New: NewFilterZip},
+		{Name: "zipglob", New: NewFilterZipGlob},
+		{Name: "iconscan", New: NewFilterIconScan},
+		{Name: "xmlpath", New: NewFilterXMLPath},
+		{Name: "gzip", New: NewFilterGzip},
+		{Name: "archive", New: NewFilterArchive},
+		{Name: "studio", New: NewFilterStudio},
+		{Name: "macstudio", New: NewFilterMacStudio},
+	}
+}
+
+// setupVariants returns the variants of the URL of a file of the deployment
+// CDN, where file is the path following the channel prefix. Because some
+// files were uploaded with lowercase names, each location is also given with
+// the path in lowercase. Variables are case-insensitive, so lowercasing
+// them does not affect their expansion.
+func setupVariants(file string) []string {
+	lower := strings.ToLower(file)
+	return []string{
+		"https://setup.rbxcdn.com/${CHANNELPATH}" + lower,
+		"https://setup.rbxcdn.com/${CHANNELCOMMONPATH}" + file,
+		"https://setup.rbxcdn.com/${CHANNELCOMMONPATH}" + lower,
+		"https://setup.rbxcdn.com/" + file,
+		"https://setup.rbxcdn.com/" + lower,
+	}
+}
+
+func newDefaultChains() map[string]iofl.Chain {
+	return map[string]iofl.Chain{
+		"Latest": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}versionQTStudio"}},
+		},
+		"Live": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://clientsettings.roblox.com/v2/client-version/WindowsStudio${CHANNELSUFFIX}"}},
+		},
+		"Live64": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://clientsettings.roblox.com/v2/client-version/WindowsStudio64${CHANNELSUFFIX}"}},
+		},
+		"LatestPlayer": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}version"}},
+		},
+		"LivePlayer": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://clientsettings.roblox.com/v2/client-version/WindowsPlayer${CHANNELSUFFIX}"}},
+		},
+		"LatestBinaryType": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}${PLATFORMPATH}${VERSIONFILE}"}},
+		},
+		"LiveBinaryType": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://clientsettings.roblox.com/v2/client-version/${BINARYTYPE}${CHANNELSUFFIX}"}},
+		},
+		"BuildsMac": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}mac/DeployHistory.txt", "Track": "mac"}},
+		},
+		"BuildsBinaryType": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}${PLATFORMPATH}DeployHistory.txt"}},
+		},
+		"LatestStudio": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}versionStudio"}},
+		},
+		"LatestStudio64": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}versionStudio64"}},
+		},
+		"LatestMac": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}mac/version"}},
+		},
+		"LatestMacStudio": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}mac/versionStudio"}},
+		},
+		"LiveLegacy": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://versioncompatibility.api.roblox.com/GetCurrentClientVersionUpload/?apiKey=76e5a40c-3ae1-4028-9f10-7c62520bd94f&binaryType=WindowsStudio", "FallbackFor": "Live"}},
+		},
+		"Live64Legacy": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://versioncompatibility.api.roblox.com/GetCurrentClientVersionUpload/?apiKey=76e5a40c-3ae1-4028-9f10-7c62520bd94f&binaryType=WindowsStudio64", "FallbackFor": "Live64"}},
+		},
+		"Builds": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}DeployHistory.txt"}},
+		},
+		"ClientSettings": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://clientsettings.roblox.com/v2/settings/application/$APPLICATION${CHANNELBUCKET}"}},
+		},
+		"APIDump": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-API-Dump.json", "Variants": setupVariants("$GUID-API-Dump.json")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-API-Dump.json"}},
+		},
+		"APIDumpText": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-API-Dump.txt", "Variants": setupVariants("$GUID-API-Dump.txt"), "Format": FormatText}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-API-Dump.txt"}},
+		},
+		"FullAPIDump": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-Full-API-Dump.json", "Variants": setupVariants("$GUID-Full-API-Dump.json")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-Full-API-Dump.json"}},
+		},
+		"APIDocs": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-content-api-docs.zip", "Variants": setupVariants("$GUID-content-api-docs.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-content-api-docs.zip"}},
+			{Filter: "zip", Params: iofl.Params{"File": "${LOCALE}.json"}},
+		},
+		"StudioTranslations": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-content-qt_translations.zip", "Variants": setupVariants("$GUID-content-qt_translations.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-content-qt_translations.zip"}},
+			{Filter: "zipglob", Params: iofl.Params{"Pattern": []string{"**.qm"}}},
+		},
+		"StudioExtraTranslations": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-extracontent-translations.zip", "Variants": setupVariants("$GUID-extracontent-translations.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-extracontent-translations.zip"}},
+			{Filter: "zipglob", Params: iofl.Params{"Pattern": []string{"**.json"}}},
+		},
+		"ContentFonts": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-content-fonts.zip", "Variants": setupVariants("$GUID-content-fonts.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-content-fonts.zip"}},
+		},
+		"ContentSky": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-content-sky.zip", "Variants": setupVariants("$GUID-content-sky.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-content-sky.zip"}},
+		},
+		"Shaders": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-shaders.zip", "Variants": setupVariants("$GUID-shaders.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-shaders.zip"}},
+		},
+		"Player": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-RobloxApp.zip", "Variants": setupVariants("$GUID-RobloxApp.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-RobloxApp.zip"}},
+		},
+		"RCCService": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-RCCService.zip", "Variants": setupVariants("$GUID-RCCService.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-RCCService.zip"}},
+		},
+		"MacStudio": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}mac/$GUID-RobloxStudioApp.zip", "Variants": setupVariants("mac/$GUID-RobloxStudioApp.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}mac/$GUID-RobloxStudioApp.zip"}},
+		},
+		"Manifest": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-rbxPkgManifest.txt", "Variants": setupVariants("$GUID-rbxPkgManifest.txt")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-rbxPkgManifest.txt"}},
+		},
+		"Package": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-$PACKAGE", "Variants": setupVariants("$GUID-$PACKAGE")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-$PACKAGE"}},
+		},
+		"ReflectionMetadata": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-RobloxStudio.zip", "Variants": setupVariants("$GUID-RobloxStudio.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-RobloxStudio.zip"}},
+			{Filter: "zip", Params: iofl.Params{"File": "ReflectionMetadata.xml"}},
+		},
+		"ReflectionMetadataClasses": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-RobloxStudio.zip", "Variants": setupVariants("$GUID-RobloxStudio.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-RobloxStudio.zip"}},
+			{Filter: "zip", Params: iofl.Params{"File": "ReflectionMetadata.xml"}},
+			{Filter: "xmlpath", Params: iofl.Params{"Path": "roblox/Item[@class=ReflectionMetadataClasses]"}},
+		},
+		"ClassImages": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-content-textures2.zip", "Variants": setupVariants("$GUID-content-textures2.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-content-textures2.zip"}},
+			{Filter: "zip", Params: iofl.Params{"File": "ClassImages.PNG"}},
+		},
+		"StudioImages": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-content-textures2.zip", "Variants": setupVariants("$GUID-content-textures2.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-content-textures2.zip"}},
+			{Filter: "zipglob", Params: iofl.Params{"Pattern": []string{"**.png", "**.jpg", "**.dds"}}},
+		},
+		"ExplorerIcons": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://setup.rbxcdn.com/${CHANNELPATH}$GUID-RobloxStudio.zip", "Variants": setupVariants("$GUID-RobloxStudio.zip")}},
+			{Filter: "cache", Params: iofl.Params{"Key": "setup.rbxcdn.com/${CHANNELPATH}$GUID-RobloxStudio.zip"}},
+			{Filter: "zip", Params: iofl.Params{"File": "RobloxStudioBeta.exe"}},
+			{Filter: "iconscan", Params: iofl.Params{"Size": 16.0}},
+			{Filter: "cache", Params: iofl.Params{"Key": "${CHANNELPATH}$GUID-ExplorerIcons.png"}},
+		},
+		// The build-archive repository retains the content of production
+		// builds that have expired from the CDN.
+		"BuildsArchive": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://raw.githubusercontent.com/RobloxAPI/build-archive/master/data/production/DeployHistory.txt", "Channel": ChannelLive}},
+		},
+		"APIDumpArchive": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://raw.githubusercontent.com/RobloxAPI/build-archive/master/data/production/builds/$GUID/API-Dump.json"}},
+			{Filter: "cache", Params: iofl.Params{"Key": "build-archive/$GUID-API-Dump.json"}},
+		},
+		"ReflectionMetadataArchive": {
+			{Filter: "url", Params: iofl.Params{"URL": "https://raw.githubusercontent.com/RobloxAPI/build-archive/master/data/production/builds/$GUID/ReflectionMetadata.xml"}},
+			{Filter: "cache", Params: iofl.Params{"Key": "build-archive/$GUID-ReflectionMetadata.xml"}},
+		},
+	}
+}
@@ -0,0 +1,201 @@
+package rbxfetch
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/robloxapi/rbxdump"
+)
+
+// derivedDirName is the name of the directory within a cache directory that
+// contains derived data.
+const derivedDirName = "derived"
+
+func init() {
+	// Register the implementations of rbxdump.Member, so that the members of
+	// a class can be encoded.
+	gob.Register(&rbxdump.Property{})
+	gob.Register(&rbxdump.Function{})
+	gob.Register(&rbxdump.Event{})
+	gob.Register(&rbxdump.Callback{})
+}
+
+// Derivation describes a transformation of the content of a build, such as
+// decoding an API dump, the result of which may be cached by Client.Derive.
+type Derivation struct {
+	// Name identifies the transformation within the cache.
+	Name string
+	// Version is the version of the transformation. Results cached by other
+	// versions are discarded, so the version should be changed whenever the
+	// transformation produces a different result.
+	Version int
+	// Derive produces the result for the given GUID.
+	Derive func(client *Client, guid string) (v interface{}, err error)
+	// Encode writes a result to the cache.
+	Encode func(w io.Writer, v interface{}) error
+	// Decode reads a result written by Encode.
+	Decode func(r io.Reader) (v interface{}, err error)
+}
+
+// Derive returns the result of the derivation for the given GUID. If the
+// client caches content, then the result is stored in the cache directory,
+// keyed by the name and version of the derivation and the GUID, so that later
+// calls, including those of other processes, decode the stored result instead
+// of deriving it again. Results of other versions of the derivation are
+// removed when a result is stored. A stored result that fails to decode is
+// derived and stored again. Failure to store a result is not an error.
+//
+// As with FilterCache, the result of a GUID is assumed not to change.
+func (client *Client) Derive(d Derivation, guid string) (v interface{}, err error) {
+	dir := cacheDir(client.CacheMode, client.CacheLocation)
+	if dir == "" || guid == "" || d.Encode == nil || d.Decode == nil {
+		return d.Derive(client, guid)
+	}
+	base := filepath.Join(dir, derivedDirName, url.PathEscape(d.Name))
+	path := filepath.Join(base, strconv.Itoa(d.Version), url.PathEscape(guid))
+	if file, err := os.Open(path); err == nil {
+		v, err = d.Decode(bufio.NewReader(file))
+		file.Close()
+		if err == nil {
+			return v, nil
+		}
+	}
+	if v, err = d.Derive(client, guid); err != nil || v == nil {
+		return v, err
+	}
+	var b bytes.Buffer
+	if d.Encode(&b, v) == nil && saveFile(path, &b) == nil {
+		pruneDerived(base, d.Version)
+	}
+	return v, nil
+}
+
+// pruneDerived removes the results of each version of a derivation other than
+// the given version.
+func pruneDerived(base string, version int) {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return
+	}
+	current := strconv.Itoa(version)
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != current {
+			os.RemoveAll(filepath.Join(base, entry.Name()))
+		}
+	}
+}
+
+// derive returns the result of the derivation, cached according to the
+// client's CacheDerived.
+func (client *Client) derive(d Derivation, guid string) (v interface{}, err error) {
+	if !client.CacheDerived {
+		return d.Derive(client, guid)
+	}
+	return client.Derive(d, guid)
+}
+
+// gobEncode encodes v as a gob.
+func gobEncode(w io.Writer, v interface{}) error {
+	return gob.NewEncoder(w).Encode(v)
+}
+
+// DerivedAPIDump is the derivation of the decoded API dump of a build, as
+// returned by APIDumpDecoded. The result is a *rbxdump.Root.
+var DerivedAPIDump = Derivation{
+	Name:    "APIDump",
+	Version: 1,
+	Derive: func(client *Client, guid string) (v interface{}, err error) {
+		rc, err := client.APIDump(guid)
+		if rc == nil || err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		root, err := decodeAPIDump(rc)
+		if err != nil {
+			return nil, err
+		}
+		return root, nil
+	},
+	Encode: gobEncode,
+	Decode: func(r io.Reader) (v interface{}, err error) {
+		var root *rbxdump.Root
+		if err := gob.NewDecoder(r).Decode(&root); err != nil {
+			return nil, err
+		}
+		return root, nil
+	},
+}
+
+// DerivedReflectionMetadata is the derivation of the decoded reflection
+// metadata of a build, as returned by ReflectionMetadataDecoded. The result is
+// a *ReflectionMetadata.
+var DerivedReflectionMetadata = Derivation{
+	Name:    "ReflectionMetadata",
+	Version: 1,
+	Derive: func(client *Client, guid string) (v interface{}, err error) {
+		rc, err := client.ReflectionMetadata(guid)
+		if rc == nil || err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		rmd, err := DecodeReflectionMetadata(rc)
+		if err != nil {
+			return nil, err
+		}
+		return rmd, nil
+	},
+	Encode: gobEncode,
+	Decode: func(r io.Reader) (v interface{}, err error) {
+		var rmd *ReflectionMetadata
+		if err := gob.NewDecoder(r).Decode(&rmd); err != nil {
+			return nil, err
+		}
+		return rmd, nil
+	},
+}
+
+// DerivedIconHashes is the derivation of the hashes of the class icons of a
+// build, as compared by IconChanges. The result is a [][sha256.Size]byte.
+var DerivedIconHashes = Derivation{
+	Name:    "IconHashes",
+	Version: 1,
+	Derive: func(client *Client, guid string) (v interface{}, err error) {
+		hashes, err := client.scanIconHashes(guid)
+		if err != nil {
+			return nil, err
+		}
+		return hashes, nil
+	},
+	Encode: func(w io.Writer, v interface{}) error {
+		for _, h := range v.([][sha256.Size]byte) {
+			if _, err := w.Write(h[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Decode: func(r io.Reader) (v interface{}, err error) {
+		var hashes [][sha256.Size]byte
+		for {
+			var h [sha256.Size]byte
+			if _, err := io.ReadFull(r, h[:]); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, h)
+		}
+		if len(hashes) == 0 {
+			return nil, errors.New("no icon hashes")
+		}
+		return hashes, nil
+	},
+}
@@ -0,0 +1,109 @@
+package rbxfetch_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/robloxapi/rbxfetch"
+	"github.com/robloxapi/rbxfetch/rbxfetchtest"
+)
+
+// upperDerivation returns a derivation of the given version that produces the
+// GUID in upper case, incrementing derived each time the result is derived.
+func upperDerivation(version int, derived *int) rbxfetch.Derivation {
+	return rbxfetch.Derivation{
+		Name:    "Upper",
+		Version: version,
+		Derive: func(client *rbxfetch.Client, guid string) (v interface{}, err error) {
+			*derived++
+			return strings.ToUpper(guid), nil
+		},
+		Encode: func(w io.Writer, v interface{}) error {
+			_, err := io.WriteString(w, v.(string))
+			return err
+		},
+		Decode: func(r io.Reader) (v interface{}, err error) {
+			b, err := io.ReadAll(r)
+			if err == nil && string(b) != strings.ToUpper(string(b)) {
+				err = errors.New("invalid result")
+			}
+			return string(b), err
+		},
+	}
+}
+
+func TestDerive(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "derived", "Upper")
+	// Each step derives with a new client, as a new process would.
+	steps := []struct {
+		name    string
+		version int
+		guid    string
+		corrupt bool
+		derived bool
+	}{
+		{name: "derived", version: 1, guid: "version-a", derived: true},
+		{name: "stored", version: 1, guid: "version-a"},
+		{name: "other guid", version: 1, guid: "version-b", derived: true},
+		{name: "corrupt", version: 1, guid: "version-b", corrupt: true, derived: true},
+		{name: "new version", version: 2, guid: "version-a", derived: true},
+		{name: "old version pruned", version: 1, guid: "version-a", derived: true},
+	}
+	for _, step := range steps {
+		client := rbxfetch.NewClient()
+		client.CacheMode = rbxfetch.CacheCustom
+		client.CacheLocation = dir
+		if step.corrupt {
+			path := filepath.Join(base, strconv.Itoa(step.version), step.guid)
+			if err := os.WriteFile(path, []byte("invalid"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		derived := 0
+		v, err := client.Derive(upperDerivation(step.version, &derived), step.guid)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if v != strings.ToUpper(step.guid) {
+			t.Errorf("%s: expected %s, got %v", step.name, strings.ToUpper(step.guid), v)
+		}
+		if (derived > 0) != step.derived {
+			t.Errorf("%s: expected derived %v, got %v", step.name, step.derived, derived > 0)
+		}
+		// Only the results of the latest version are kept.
+		entries, _ := os.ReadDir(base)
+		if len(entries) != 1 || entries[0].Name() != strconv.Itoa(step.version) {
+			t.Errorf("%s: expected only version %d to be stored, got %v", step.name, step.version, entries)
+		}
+	}
+}
+
+func TestDerivedAPIDump(t *testing.T) {
+	s := rbxfetchtest.NewServer()
+	defer s.Close()
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		s.ResetRequests()
+		client := rbxfetchtest.NewTestClient(s)
+		client.CacheMode = rbxfetch.CacheCustom
+		client.CacheLocation = dir
+		client.CacheDerived = true
+		root, err := client.APIDumpDecoded(rbxfetchtest.GUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root.Classes["Instance"] == nil {
+			t.Fatalf("process %d: expected the Instance class", i+1)
+		}
+		// A later process decodes the stored result without fetching.
+		if n := len(s.Requests()); i > 0 && n != 0 {
+			t.Errorf("process %d: expected no requests, got %d", i+1, n)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package rbxfetch
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// progressInterval is the minimum duration between calls to the Progress of a
+// client for a single download.
+const progressInterval = 100 * time.Millisecond
+
+// Progress describes the progress of a download.
+type Progress struct {
+	// Method and GUID identify the content being downloaded.
+	Method string
+	GUID   string
+	// Path is the destination of the download.
+	Path string
+	// Written is the number of bytes written so far.
+	Written int64
+	// Done indicates whether the content has been fully written.
+	Done bool
+}
+
+// progressReader reports the progress of content read through it.
+type progressReader struct {
+	r        io.Reader
+	fn       func(p Progress)
+	progress Progress
+	last     time.Time
+}
+
+func (r *progressReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	r.progress.Written += int64(n)
+	if err == io.EOF {
+		r.progress.Done = true
+		r.fn(r.progress)
+	} else if now := time.Now(); now.Sub(r.last) >= progressInterval {
+		r.last = now
+		r.fn(r.progress)
+	}
+	return n, err
+}
+
+// checksumHash returns the hash that produces checksums of the same length as
+// checksum: MD5 or SHA-256.
+func checksumHash(checksum string) (hash.Hash, error) {
+	switch len(checksum) {
+	case md5.Size * 2:
+		return md5.New(), nil
+	case sha256.Size * 2:
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("checksum %q is not an MD5 or SHA-256 hex digest", checksum)
+}
+
+// Download fetches the content of method for guid, and writes it to the file
+// at path, creating parent directories as needed. The content is streamed
+// into a temporary file, which is renamed to path only once the content has
+// been fully written, so that a failed download does not leave a partial
+// file. Progress is reported to the client's Progress.
+func (client *Client) Download(method, guid, path string) error {
+	return client.DownloadChecksum(method, guid, path, "")
+}
+
+// DownloadChecksum downloads in the same manner as Download, verifying the
+// content against checksum, which is the hex-encoded MD5 or SHA-256 digest of
+// the content. If the content does not match, then the file is not written,
+// and a ChecksumError is returned. If checksum is empty, then the content is
+// not verified.
+func (client *Client) DownloadChecksum(method, guid, path, checksum string) error {
+	var h hash.Hash
+	if checksum != "" {
+		var err error
+		if h, err = checksumHash(checksum); err != nil {
+			return err
+		}
+	}
+	rc, err := client.Method(method, guid)
+	if err != nil {
+		return err
+	}
+	return client.download(method, guid, path, rc, h, checksum)
+}
+
+// DownloadPackage downloads the package of the given name from the deployment
+// of guid, in the same manner as Download. The content is verified against the
+// checksum listed by the manifest, as by Package.
+func (client *Client) DownloadPackage(guid, name, path string) error {
+	rc, err := client.Package(guid, name)
+	if err != nil {
+		return err
+	}
+	return client.download("Package", guid, path, rc, nil, "")
+}
+
+// download writes the content of rc to path, verifying it with h against
+// checksum, if h is not nil. rc is closed.
+func (client *Client) download(method, guid, path string, rc io.ReadCloser, h hash.Hash, checksum string) error {
+	if rc == nil {
+		return fmt.Errorf("method %q is not configured", method)
+	}
+	defer rc.Close()
+	var r io.Reader = rc
+	if client.Progress != nil {
+		r = &progressReader{
+			r:        r,
+			fn:       client.Progress,
+			progress: Progress{Method: method, GUID: guid, Path: path},
+			last:     time.Now(),
+		}
+	}
+	var verify func() error
+	if h != nil {
+		r = io.TeeReader(r, h)
+		verify = func() error {
+			if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, checksum) {
+				return classify(ErrDecode, &ChecksumError{Name: filepath.Base(path), Expected: checksum, Actual: actual})
+			}
+			return nil
+		}
+	}
+	if err := saveFileVerify(path, r, verify); err != nil {
+		return fmt.Errorf("download %s: %w", method, err)
+	}
+	return nil
+}
+
+// DownloadAll downloads each artifact of the given GUID into dir, with the
+// file name of the artifact. The client's Artifacts specify the artifacts to
+// download; if nil, then DefaultArtifacts is used. Artifacts of methods that
+// are not configured are skipped. Returns the paths of the written files.
+func (client *Client) DownloadAll(guid, dir string) (paths []string, err error) {
+	artifacts := client.Artifacts
+	if artifacts == nil {
+		artifacts = DefaultArtifacts
+	}
+	for _, artifact := range artifacts {
+		if _, ok := client.snapshot().methods[artifact.Method]; !ok {
+			continue
+		}
+		path := filepath.Join(dir, artifact.Name)
+		if err := client.Download(artifact.Method, guid, path); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
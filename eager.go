@@ -0,0 +1,72 @@
+package rbxfetch
+
+import "io"
+
+// Fetch modes, which determine when the content of a method is requested. The
+// mode of a request is specified by the FETCH variable. If unspecified, then
+// the mode is determined by the EagerFetch field of the client.
+const (
+	// FetchLazy defers the request until the result is first read. Errors
+	// that occur while requesting the content are returned by Read.
+	FetchLazy = "lazy"
+	// FetchEager requests the content before the method returns, so that
+	// errors, such as a build that does not exist, are returned by the method
+	// itself. If the request fails, then the remaining chains of the method
+	// are attempted before returning.
+	FetchEager = "eager"
+)
+
+// eagerSize is the number of bytes read from a result to begin fetching
+// eagerly.
+const eagerSize = 512
+
+// WithFetch returns a Scope that makes requests with the given fetch mode,
+// such as FetchEager.
+func (client *Client) WithFetch(mode string) *Scope {
+	return client.WithVars(map[string]string{"FETCH": mode})
+}
+
+// WithFetch returns a copy of the Scope that makes requests with the given
+// fetch mode.
+func (s *Scope) WithFetch(mode string) *Scope {
+	return s.WithVars(map[string]string{"FETCH": mode})
+}
+
+// eager returns whether requests made with vars are made eagerly.
+func (client *Client) eager(vars map[string]string) bool {
+	mode := vars["FETCH"]
+	if mode == "" {
+		mode = client.vars()["FETCH"]
+	}
+	switch mode {
+	case FetchEager:
+		return true
+	case FetchLazy:
+		return false
+	}
+	return client.EagerFetch
+}
+
+// begin begins reading the result, retaining the content that was read so
+// that it is returned by subsequent reads. Returns the error of the read, if
+// it is not io.EOF.
+func (r *result) begin() error {
+	buf := make([]byte, eagerSize)
+	n, err := r.Read(buf)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	r.pending, r.pendingErr = buf[:n], err
+	return nil
+}
+
+// readPending reads content retained by begin.
+func (r *result) readPending(p []byte) (n int, err error) {
+	n = copy(p, r.pending)
+	r.pending = r.pending[n:]
+	if len(r.pending) == 0 {
+		r.pending = nil
+		err, r.pendingErr = r.pendingErr, nil
+	}
+	return n, err
+}
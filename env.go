@@ -0,0 +1,72 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Environment variables read by ApplyEnv.
+const (
+	// EnvCacheMode specifies the CacheMode of a client. The value is one of
+	// "none", "temp", "perm", or "custom".
+	EnvCacheMode = "RBXFETCH_CACHE_MODE"
+	// EnvCacheDir specifies the CacheLocation of a client. If EnvCacheMode is
+	// not set, then the CacheMode is set to CacheCustom.
+	EnvCacheDir = "RBXFETCH_CACHE_DIR"
+	// EnvConfig specifies the path to a JSON file containing the
+	// configuration of a client, as described by LoadConfig.
+	EnvConfig = "RBXFETCH_CONFIG"
+	// EnvProxy specifies the Proxy of a client, taking precedence over
+	// HTTP_PROXY and HTTPS_PROXY.
+	EnvProxy = "RBXFETCH_PROXY"
+)
+
+// cacheModeNames maps the name of each CacheMode, as specified by
+// EnvCacheMode.
+var cacheModeNames = map[string]CacheMode{
+	"none":   CacheNone,
+	"temp":   CacheTemp,
+	"perm":   CachePerm,
+	"custom": CacheCustom,
+}
+
+// ApplyEnv configures the client according to the environment variables
+// EnvCacheMode, EnvCacheDir, EnvConfig, and EnvProxy. Variables that are unset
+// or empty are ignored. NewClient calls ApplyEnv, so that deployments may
+// reconfigure a client without changes to the program. The error of that call
+// is reported by EnvError, and is returned by NewClientEnv.
+//
+// The standard HTTP_PROXY, HTTPS_PROXY, and NO_PROXY variables are honored by
+// the default HTTP client regardless.
+func (client *Client) ApplyEnv() error {
+	var errs []string
+	if v := os.Getenv(EnvCacheDir); v != "" {
+		client.CacheLocation = v
+		client.CacheMode = CacheCustom
+	}
+	if v := os.Getenv(EnvCacheMode); v != "" {
+		if mode, ok := cacheModeNames[strings.ToLower(v)]; ok {
+			client.CacheMode = mode
+		} else {
+			errs = append(errs, fmt.Sprintf("%s: unknown cache mode %q", EnvCacheMode, v))
+		}
+	}
+	if v := os.Getenv(EnvConfig); v != "" {
+		if err := client.LoadConfigFile(v); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", EnvConfig, err))
+		}
+	}
+	if v := os.Getenv(EnvProxy); v != "" {
+		if _, err := url.Parse(v); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", EnvProxy, err))
+		} else {
+			client.Proxy = v
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("environment: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package rbxfetch
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anaminus/iofl"
+)
+
+// Errors returned by the client are classified into the following categories,
+// which can be detected with errors.Is.
+var (
+	// ErrNetwork classifies errors that occur while communicating with a
+	// remote host.
+	ErrNetwork = errors.New("network error")
+	// ErrStatus classifies errors caused by an unsuccessful HTTP status. The
+	// status can be retrieved with StatusCode.
+	ErrStatus = errors.New("http status error")
+	// ErrDecode classifies errors that occur while decoding content.
+	ErrDecode = errors.New("decode error")
+	// ErrCache classifies errors that occur while reading from or writing to
+	// the cache.
+	ErrCache = errors.New("cache error")
+)
+
+// classError classifies an error into a category.
+type classError struct {
+	class error
+	err   error
+}
+
+func (e *classError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classError) Unwrap() error {
+	return e.err
+}
+
+func (e *classError) Is(target error) bool {
+	return target == e.class
+}
+
+// classify wraps err so that it is classified as class. Returns nil if err is
+// nil, and returns err unchanged if it is io.EOF, or is already classified.
+func classify(class, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	var c *classError
+	if errors.As(err, &c) {
+		return err
+	}
+	return &classError{class: class, err: err}
+}
+
+// StatusCode returns the HTTP status code of an error classified as ErrStatus.
+func StatusCode(err error) (status int, ok bool) {
+	var s interface{ Status() int }
+	if errors.As(err, &s) {
+		return s.Status(), true
+	}
+	return 0, false
+}
+
+// ErrTimeout is returned by a chain that did not complete within the time
+// allotted to it.
+var ErrTimeout = errors.New("timed out")
+
+// ErrExpiredBuild indicates that the artifacts of a build are no longer
+// available from the deployment CDN, which responds to requests for the files
+// of old builds with 403 Forbidden. Methods fall back to the remaining chains
+// of the method, such as archive chains, before returning the error. The
+// details are retrieved with errors.As and an *ExpiredBuildError.
+var ErrExpiredBuild = errors.New("build expired")
+
+// ExpiredBuildError is an error classified as ErrStatus, indicating that an
+// artifact of a build was refused by the host. It is detected as
+// ErrExpiredBuild.
+type ExpiredBuildError struct {
+	// GUID is the GUID of the build.
+	GUID string
+	// URL is the URL of the artifact that was requested.
+	URL string
+	// Err is the underlying status error.
+	Err error
+}
+
+func (e *ExpiredBuildError) Error() string {
+	return "build " + e.GUID + " expired: " + e.Err.Error()
+}
+
+func (e *ExpiredBuildError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExpiredBuildError) Is(target error) bool {
+	return target == ErrExpiredBuild
+}
+
+// RateLimitedError is an error classified as ErrStatus, indicating that a
+// request was rate-limited by the host, and was not retried, either because
+// the retry policy was exhausted, or because the host requested a delay
+// longer than the policy permits.
+type RateLimitedError struct {
+	// URL is the URL that was requested.
+	URL string
+	// RetryAfter is the delay requested by the Retry-After header of the
+	// last response, or zero if the header was absent.
+	RetryAfter time.Duration
+	// Attempts is the number of attempts made.
+	Attempts int
+	// Err is the underlying status error.
+	Err error
+}
+
+func (e *RateLimitedError) Error() string {
+	msg := "rate limited after " + strconv.Itoa(e.Attempts) + " attempt"
+	if e.Attempts != 1 {
+		msg += "s"
+	}
+	if e.RetryAfter > 0 {
+		msg += " (retry after " + e.RetryAfter.String() + ")"
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return e.Err
+}
+
+// ChainError is an error produced by a chain of a method. Errors returned by
+// the methods of a Client are wrapped in a ChainError identifying where the
+// error occurred.
+type ChainError struct {
+	// Method is the name of the method, or an empty string if the chain was
+	// not run by a method.
+	Method string
+	// Chain is the name of the chain.
+	Chain string
+	// Filter is the name of the filter of the chain that failed, or an empty
+	// string if unknown.
+	Filter string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ChainError) Error() string {
+	s := "rbxfetch: "
+	if e.Method != "" {
+		s += "method " + e.Method + ": "
+	}
+	s += "chain " + e.Chain + ": "
+	if e.Filter != "" {
+		s += "filter " + e.Filter + ": "
+	}
+	return s + e.Err.Error()
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.Err
+}
+
+// newChainError returns err as a ChainError of chain of method. If err is
+// already a ChainError, then it is returned unchanged.
+func newChainError(method, chain string, err error) *ChainError {
+	if c, ok := err.(*ChainError); ok {
+		return c
+	}
+	return &ChainError{Method: method, Chain: chain, Err: err}
+}
+
+// failer is implemented by filters that report the error that caused them to
+// fail.
+type failer interface {
+	iofl.Filter
+	// Failure returns the error that caused the filter to fail, or nil if
+	// the filter has not failed.
+	Failure() error
+}
+
+// failure returns err, or nil if err indicates that a filter was closed.
+func failure(err error) error {
+	if err == iofl.Closed {
+		return nil
+	}
+	return err
+}
+
+// chainError returns err as a ChainError of chain of method, which was
+// produced by f. The filter is identified as the link of the chain nearest to
+// the source that has failed. err is returned unchanged if it is nil, io.EOF,
+// or already a ChainError.
+func (client *Client) chainError(method, chain string, f iofl.Filter, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if _, ok := err.(*ChainError); ok {
+		return err
+	}
+	e := newChainError(method, chain, err)
+	var filters []iofl.Filter
+	iofl.Apply(f, func(r io.ReadCloser) error {
+		if f, ok := r.(iofl.Filter); ok {
+			filters = append(filters, f)
+		}
+		return nil
+	})
+	links := client.snapshot().chainSet.Config().Chains[chain]
+	if len(filters) != len(links) {
+		// The chain has an external source; the links cannot be matched.
+		return e
+	}
+	// Filters are visited from the end of the chain.
+	var unknown []string
+	for i, link := range links {
+		f, ok := filters[len(filters)-1-i].(failer)
+		if !ok {
+			unknown = append(unknown, link.Filter)
+			continue
+		}
+		if f.Failure() != nil {
+			e.Filter = link.Filter
+			return e
+		}
+	}
+	if len(unknown) == 1 {
+		// Only one filter does not report failure.
+		e.Filter = unknown[0]
+	}
+	return e
+}
+
+// ChainErrors is a list of errors produced by the chains of a method.
+type ChainErrors []*ChainError
+
+func (e ChainErrors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
@@ -0,0 +1,138 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NamingTemplate describes the path of an exported artifact, relative to the
+// export directory. The template may contain the following variables:
+//
+//   - {guid}: The GUID of the build.
+//   - {version}: The version of the build, or the GUID if the version is
+//     unknown.
+//   - {type}: The type of the build.
+//   - {date}: The date of the build, formatted as YYYY-MM-DD.
+//   - {artifact}: The file name of the artifact.
+//
+// Slashes separate directories.
+type NamingTemplate string
+
+// DefaultNaming is the NamingTemplate used when none is specified.
+const DefaultNaming NamingTemplate = "{guid}-{artifact}"
+
+// Path returns the path of the given artifact of build, according to the
+// template.
+func (t NamingTemplate) Path(build Build, artifact string) string {
+	if t == "" {
+		t = DefaultNaming
+	}
+	version := build.GUID
+	if !build.Version.Empty() {
+		version = build.Version.String()
+	}
+	var date string
+	if !build.Date.IsZero() {
+		date = build.Date.Format("2006-01-02")
+	}
+	path := strings.NewReplacer(
+		"{guid}", build.GUID,
+		"{version}", version,
+		"{type}", build.Type,
+		"{date}", date,
+		"{artifact}", artifact,
+	).Replace(string(t))
+	return filepath.FromSlash(path)
+}
+
+// Artifact associates a method with the file name of its exported result.
+type Artifact struct {
+	Method string
+	Name   string
+}
+
+// DefaultArtifacts is the list of artifacts exported by SaveAll when the
+// client does not specify any.
+var DefaultArtifacts = []Artifact{
+	{Method: "APIDump", Name: "API-Dump.json"},
+	{Method: "ReflectionMetadata", Name: "ReflectionMetadata.xml"},
+	{Method: "ClassImages", Name: "ClassImages.png"},
+}
+
+// saveFile atomically writes the content of r to path.
+func saveFile(path string, r io.Reader) error {
+	return saveFileVerify(path, r, nil)
+}
+
+// saveFileVerify atomically writes the content of r to path. If verify is not
+// nil, it is called once r is fully written, and the file is discarded if it
+// returns an error.
+func saveFileVerify(path string, r io.Reader, verify func() error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tempFile, err := createTemp(dir)
+	if err != nil {
+		return err
+	}
+	tempName := tempFile.Name()
+	// Remove the temp file on failure, including if reading r panics.
+	keep := false
+	defer func() {
+		if !keep {
+			tempFile.Close()
+			os.Remove(tempName)
+		}
+	}()
+	if _, err = io.Copy(tempFile, r); err != nil {
+		return err
+	}
+	if verify != nil {
+		if err = verify(); err != nil {
+			return err
+		}
+	}
+	if err = tempFile.Sync(); err != nil {
+		return err
+	}
+	tempFile.Close()
+	keep = true
+	if err := os.Rename(tempName, path); err != nil {
+		os.Remove(tempName)
+		return err
+	}
+	return nil
+}
+
+// SaveAll fetches each artifact of build, and writes it to a file within dir,
+// with a path determined by the client's Naming template. The client's
+// Artifacts specify the artifacts to save; if nil, then DefaultArtifacts is
+// used. Artifacts of methods that are not configured are skipped. Returns the
+// paths of the written files.
+func (client *Client) SaveAll(dir string, build Build) (paths []string, err error) {
+	artifacts := client.Artifacts
+	if artifacts == nil {
+		artifacts = DefaultArtifacts
+	}
+	for _, artifact := range artifacts {
+		if _, ok := client.snapshot().methods[artifact.Method]; !ok {
+			continue
+		}
+		rc, err := client.Method(artifact.Method, build.GUID)
+		if err != nil {
+			return paths, fmt.Errorf("save %s: %w", artifact.Name, err)
+		}
+		path := filepath.Join(dir, client.Naming.Path(build, artifact.Name))
+		err = saveFile(path, rc)
+		rc.Close()
+		if err != nil {
+			return paths, fmt.Errorf("save %s: %w", artifact.Name, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -11,6 +11,7 @@ import (
 type FilterFile struct {
 	Path string
 	GUID string
+	Vars map[string]string
 
 	r   io.ReadCloser
 	err error
@@ -27,14 +28,27 @@ func (f *FilterFile) SetGUID(guid string) {
 	f.GUID = guid
 }
 
+func (f *FilterFile) SetVars(vars map[string]string) {
+	f.Vars = vars
+}
+
 func (f *FilterFile) Source() io.ReadCloser {
 	return f.r
 }
 
+func (f *FilterFile) Failure() error {
+	return failure(f.err)
+}
+
 func (f *FilterFile) Close() error {
 	if f.err != nil {
 		return f.err
 	}
+	if f.r == nil {
+		// Nothing was opened.
+		f.err = iofl.Closed
+		return nil
+	}
 	if f.err = f.r.Close(); f.err == nil {
 		f.err = iofl.Closed
 		return nil
@@ -47,7 +61,7 @@ func (f *FilterFile) Read(p []byte) (n int, err error) {
 		return 0, f.err
 	}
 	if f.r == nil {
-		if f.r, err = os.Open(expandGUID(f.Path, f.GUID)); err != nil {
+		if f.r, err = os.Open(expandVars(f.Path, f.GUID, f.Vars)); err != nil {
 			f.err = err
 			return 0, err
 		}
@@ -0,0 +1,174 @@
+package rbxfetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/anaminus/iofl"
+)
+
+// ErrForbidden indicates that a request was not permitted by the AllowURLs or
+// BeforeFetch of a Client.
+var ErrForbidden = errors.New("fetch not permitted")
+
+// FetchRequest describes a request about to be made by a Client.
+type FetchRequest struct {
+	// Method is the name of the method that made the request, if known.
+	Method string
+	// Chain is the name of the chain that made the request.
+	Chain string
+	// GUID is the GUID passed to the method, if any.
+	GUID string
+	// URL is the fully expanded URL to be requested.
+	URL string
+}
+
+// urlPatternCache retains the patterns compiled from the AllowURLs of a
+// Client, so that they are compiled only when AllowURLs changes.
+type urlPatternCache struct {
+	mu       sync.Mutex
+	key      string
+	patterns []*regexp.Regexp
+	err      error
+}
+
+// compileURLPattern converts a pattern of AllowURLs to a regular expression.
+// Unlike compileGlob, the pattern is case-sensitive, and only "*" is special,
+// since "?" begins the query of a URL. "**" matches any sequence of
+// characters, and "*" matches any sequence of characters excluding "/".
+func compileURLPattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '*' {
+			j := strings.IndexByte(pattern[i:], '*')
+			if j < 0 {
+				j = len(pattern) - i
+			}
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+j]))
+			i += j - 1
+			continue
+		}
+		if i+1 < len(pattern) && pattern[i+1] == '*' {
+			b.WriteString(".*")
+			i++
+		} else {
+			b.WriteString("[^/]*")
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// urlPatterns returns the compiled patterns of AllowURLs.
+func (client *Client) urlPatterns() ([]*regexp.Regexp, error) {
+	key := strings.Join(client.AllowURLs, "\x00")
+	cache := &client.allowed
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.key == key && (cache.patterns != nil || cache.err != nil) {
+		return cache.patterns, cache.err
+	}
+	patterns := make([]*regexp.Regexp, 0, len(client.AllowURLs))
+	var err error
+	for _, pattern := range client.AllowURLs {
+		re, perr := compileURLPattern(pattern)
+		if perr != nil {
+			patterns, err = nil, fmt.Errorf("allowed url %q: %w", pattern, perr)
+			break
+		}
+		patterns = append(patterns, re)
+	}
+	cache.key, cache.patterns, cache.err = key, patterns, err
+	return patterns, err
+}
+
+// gate returns a function that checks whether a request to a URL from the
+// given chain is permitted. Returns nil if every request is permitted.
+func (client *Client) gate(method, chain, guid string) func(url string) error {
+	before := client.BeforeFetch
+	if len(client.AllowURLs) == 0 && before == nil {
+		return nil
+	}
+	patterns, err := client.urlPatterns()
+	if err != nil {
+		return func(string) error { return err }
+	}
+	return func(url string) error {
+		if len(patterns) > 0 {
+			allowed := false
+			for _, re := range patterns {
+				if re.MatchString(url) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return fmt.Errorf("%s: %w", url, ErrForbidden)
+			}
+		}
+		if before != nil {
+			req := FetchRequest{Method: method, Chain: chain, GUID: guid, URL: url}
+			if err := before(req); err != nil {
+				return fmt.Errorf("%s: %w: %v", url, ErrForbidden, err)
+			}
+		}
+		return nil
+	}
+}
+
+// gatedClient returns a copy of c that also checks each redirect with gate,
+// before following the redirect as c would.
+func gatedClient(c *http.Client, gate func(url string) error) *http.Client {
+	gated := *c
+	check := c.CheckRedirect
+	gated.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := gate(req.URL.String()); err != nil {
+			return err
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		// Matches the default policy of http.Client.
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &gated
+}
+
+// applyRewrite applies a function that rewrites the URLs of requests to the
+// chain of filters.
+func applyRewrite(filter iofl.Filter, rewrite func(u *url.URL) error) {
+	type rewriter interface {
+		iofl.Filter
+		SetRewrite(rewrite func(u *url.URL) error)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(rewriter); ok {
+			f.SetRewrite(rewrite)
+		}
+		return nil
+	})
+}
+
+// applyGate applies a function that checks requests to the chain of filters.
+func applyGate(filter iofl.Filter, gate func(url string) error) {
+	type gater interface {
+		iofl.Filter
+		SetGate(gate func(url string) error)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(gater); ok {
+			f.SetGate(gate)
+		}
+		return nil
+	})
+}
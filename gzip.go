@@ -0,0 +1,70 @@
+package rbxfetch
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/anaminus/iofl"
+)
+
+// FilterGzip is an iofl.Filter that decompresses a gzip source. It allows a
+// chain to retrieve a compressed variant of an artifact, such as
+// "API-Dump.json.gz", while subsequent filters, including caches, receive the
+// decompressed content.
+type FilterGzip struct {
+	r   io.ReadCloser
+	zr  *gzip.Reader
+	err error
+	// failed is the error that interrupted reading.
+	failed error
+}
+
+// NewFilterGzip is an iofl.NewFilter that returns a FilterGzip.
+func NewFilterGzip(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error) {
+	return &FilterGzip{r: r}, nil
+}
+
+func (f *FilterGzip) Source() io.ReadCloser {
+	return f.r
+}
+
+func (f *FilterGzip) Failure() error {
+	if err := failure(f.err); err != nil {
+		return err
+	}
+	return f.failed
+}
+
+func (f *FilterGzip) Close() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.zr != nil {
+		f.zr.Close()
+	}
+	if f.err = f.r.Close(); f.err == nil {
+		f.err = iofl.Closed
+		return nil
+	}
+	return f.err
+}
+
+func (f *FilterGzip) Read(p []byte) (n int, err error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.zr == nil {
+		if f.zr, err = gzip.NewReader(f.r); err != nil {
+			f.zr = nil
+			f.err = classify(ErrDecode, err)
+			f.r.Close()
+			return 0, f.err
+		}
+	}
+	n, err = f.zr.Read(p)
+	if err != nil && err != io.EOF {
+		err = classify(ErrDecode, err)
+		f.failed = err
+	}
+	return n, err
+}
@@ -0,0 +1,38 @@
+package rbxfetch
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/anaminus/iofl"
+)
+
+// DefaultUserAgent is the User-Agent set by NewClient, identifying requests
+// made by this package.
+const DefaultUserAgent = "rbxfetch (+https://github.com/robloxapi/rbxfetch)"
+
+// defaultHeader returns the headers added to each request made by the client.
+func (client *Client) defaultHeader() http.Header {
+	header := client.Header.Clone()
+	if client.UserAgent != "" && header.Get("User-Agent") == "" {
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("User-Agent", client.UserAgent)
+	}
+	return header
+}
+
+// applyDefaultHeader applies default headers to the chain of filters.
+func applyDefaultHeader(filter iofl.Filter, header http.Header) {
+	type headerer interface {
+		iofl.Filter
+		SetDefaultHeader(header http.Header)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(headerer); ok {
+			f.SetDefaultHeader(header)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,318 @@
+package rbxfetch
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anaminus/iofl"
+	"github.com/robloxapi/rbxdump/histlog"
+)
+
+// historyOverlap is the number of bytes of previously fetched history that
+// are requested again, in order to verify that the history has only been
+// appended to.
+const historyOverlap = 256
+
+// historyDirName is the name of the directory within a cache directory that
+// contains retained deployment histories.
+const historyDirName = "history"
+
+// historyKey returns a key that identifies the content produced by chain with
+// vars.
+func (client *Client) historyKey(chain string, vars map[string]string) string {
+	all := client.vars()
+	for k, v := range vars {
+		all[strings.ToUpper(k)] = v
+	}
+	var b strings.Builder
+	b.WriteString(chain)
+	for _, link := range client.snapshot().chainSet.Config().Chains[chain] {
+		fmt.Fprintf(&b, "|%s%v", link.Filter, expandParam(link.Params, "", all))
+	}
+	return b.String()
+}
+
+// historyChains returns the chains of method that belong to the given track,
+// and that serve the channel of vars. A chain with a "Channel" parameter, such
+// as an archive of production builds, serves only the named channel.
+func (client *Client) historyChains(method, track string, vars map[string]string) (chains []string) {
+	channel := client.channel(vars)
+	for _, chain := range client.chains(method) {
+		if !strings.EqualFold(client.chainParam(chain, "Track"), track) {
+			continue
+		}
+		if c := client.chainParam(chain, "Channel"); c != "" && !sameChannel(c, channel) {
+			continue
+		}
+		chains = append(chains, chain)
+	}
+	return chains
+}
+
+// historyPath returns the path of the file in which the history identified by
+// key is retained, or an empty string if the client does not cache content.
+func (client *Client) historyPath(key string) string {
+	dir := cacheDir(client.CacheMode, client.CacheLocation)
+	if dir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, historyDirName, hex.EncodeToString(sum[:16]))
+}
+
+// history returns a reader of the content of a deployment history produced by
+// chain.
+//
+// Because a deployment history is only appended to, the content is retained
+// in the cache directory, so that neither the client nor a new process need
+// fetch the entire history again. If the chain supports offsets, then
+// subsequent fetches request only the content following the retained content,
+// which is then appended to it. The request overlaps the retained content
+// slightly; if the overlap does not match, then the history is fetched
+// entirely. The content of a full fetch is written to a temporary file as it
+// is read, and is retained only once it has been read completely. If the
+// client does not cache content, then the history is not retained.
+func (client *Client) history(method, chain string, vars map[string]string) (rc io.ReadCloser, err error) {
+	f, err := client.resolveVars(method, chain, "", vars)
+	if err != nil {
+		return nil, err
+	}
+	src, ok := f.(offsetter)
+	if !ok {
+		return client.newResult(method, chain, f, false), nil
+	}
+	path := client.historyPath(client.historyKey(chain, vars))
+	if path == "" {
+		return client.newResult(method, chain, f, false), nil
+	}
+	if prev, err := os.Open(path); err == nil {
+		if rc, err = client.appendHistory(method, chain, f, src, prev, path); rc != nil || err != nil {
+			return rc, err
+		}
+		// The history was changed other than by appending.
+		if f, err = client.resolveVars(method, chain, "", vars); err != nil {
+			return nil, err
+		}
+	}
+	return newHistoryReader(client.newResult(method, chain, f, false), path), nil
+}
+
+// appendHistory fetches the content of the history of f following prev, the
+// retained content at path, and appends it to the retained content. Returns a
+// reader of the entire content, or nil if the history does not continue the
+// retained content.
+func (client *Client) appendHistory(method, chain string, f iofl.Filter, src offsetter, prev *os.File, path string) (rc io.ReadCloser, err error) {
+	defer prev.Close()
+	info, err := prev.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil
+	}
+	size := info.Size()
+	overlap := int64(historyOverlap)
+	if overlap > size {
+		overlap = size
+	}
+	tail := make([]byte, overlap)
+	if _, err := prev.ReadAt(tail, size-overlap); err != nil {
+		f.Close()
+		return nil, nil
+	}
+	src.SetOffset(size - overlap)
+	b, err := io.ReadAll(client.newResult(method, chain, f, false))
+	f.Close()
+	var status interface{ Status() int }
+	if errors.As(err, &status) && status.Status() == http.StatusRequestedRangeNotSatisfiable {
+		// The history is now shorter than the retained content.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) < overlap || !bytes.Equal(b[:overlap], tail) {
+		return nil, nil
+	}
+	b = b[overlap:]
+	if len(b) == 0 {
+		return os.Open(path)
+	}
+	if _, err := prev.Seek(0, io.SeekStart); err == nil {
+		// The retained file is closed before it is replaced.
+		if saveFileVerify(path, io.MultiReader(prev, bytes.NewReader(b)), prev.Close) == nil {
+			return os.Open(path)
+		}
+	}
+	// Failure to retain the content is not an error.
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return readCloser{Reader: io.MultiReader(file, bytes.NewReader(b)), Closer: file}, nil
+}
+
+// readCloser combines a Reader with the Closer of its underlying source.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// historyReader retains the content read from a deployment history by writing
+// it to a temporary file, which replaces the file at path once the content has
+// been read completely. The file is discarded if reading fails or is
+// abandoned.
+type historyReader struct {
+	io.ReadCloser
+	path string
+	file *os.File
+}
+
+// newHistoryReader returns a reader that retains the content of rc at path.
+// If a temporary file cannot be created, then the content is not retained.
+func newHistoryReader(rc io.ReadCloser, path string) io.ReadCloser {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return rc
+	}
+	file, err := createTemp(dir)
+	if err != nil {
+		return rc
+	}
+	return &historyReader{ReadCloser: rc, path: path, file: file}
+}
+
+func (r *historyReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if r.file == nil {
+		return n, err
+	}
+	if _, werr := r.file.Write(p[:n]); werr != nil || err != nil && err != io.EOF {
+		r.discard()
+		return n, err
+	}
+	if err == io.EOF {
+		name := r.file.Name()
+		if r.file.Close() != nil || os.Rename(name, r.path) != nil {
+			os.Remove(name)
+		}
+		r.file = nil
+	}
+	return n, err
+}
+
+// discard removes the temporary file.
+func (r *historyReader) discard() {
+	if r.file != nil {
+		r.file.Close()
+		os.Remove(r.file.Name())
+		r.file = nil
+	}
+}
+
+func (r *historyReader) Close() error {
+	r.discard()
+	return r.ReadCloser.Close()
+}
+
+// scanHistory lexes the histlog stream read from r one line at a time,
+// calling fn with each token and the line from which it was lexed, without
+// the line terminator. Scanning stops early if fn returns false.
+func scanHistory(r io.Reader, fn func(token histlog.Token, line string) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		text := strings.TrimRight(string(line), "\r\n")
+		for _, token := range histlog.Lex(line) {
+			if !fn(token, text) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// buildScanner converts the tokens of a deployment history into builds. A
+// build is complete once the status of its job is known, which is when the
+// status or the next job is scanned.
+type buildScanner struct {
+	// channel and track label each build.
+	channel string
+	track   string
+	// mac indicates whether the history is of the Mac deployment line.
+	mac bool
+	// pending is the build whose status is not yet known.
+	pending *Build
+}
+
+// scan scans a token lexed from line, returning the build completed by the
+// token, or nil if no build was completed.
+func (s *buildScanner) scan(token histlog.Token, line string) (build *Build) {
+	switch token := token.(type) {
+	case *histlog.Job:
+		build = s.pending
+		s.pending = &Build{
+			Type:       token.Build,
+			GUID:       token.GUID,
+			Date:       token.Time,
+			Version:    token.Version,
+			Channel:    s.channel,
+			Track:      s.track,
+			BinaryType: buildBinaryType(token.Build, s.mac),
+			Revert:     token.Action == "Revert",
+			Line:       line,
+		}
+	case *histlog.Status:
+		if s.pending != nil {
+			s.pending.Status = string(*token)
+			build, s.pending = s.pending, nil
+		}
+	}
+	return build
+}
+
+// flush returns the pending build, if any, which is considered complete.
+func (s *buildScanner) flush() (build *Build) {
+	build, s.pending = s.pending, nil
+	return build
+}
+
+// stream returns the tokens of the deployment history produced by the first
+// chain of method of the given track that does not error before producing a
+// token, along with the error of the chain, if any. vars are passed to each
+// chain.
+func (client *Client) stream(method, track string, vars map[string]string) (stream histlog.Stream, err error) {
+	for _, chain := range client.historyChains(method, track, vars) {
+		start := time.Now()
+		var rc io.ReadCloser
+		if rc, err = client.history(method, chain, vars); err != nil {
+			client.observe(method, chain, start, err)
+			continue
+		}
+		stream = stream[:0]
+		err = scanHistory(rc, func(token histlog.Token, line string) bool {
+			stream = append(stream, token)
+			return true
+		})
+		rc.Close()
+		client.observe(method, chain, start, err)
+		if err != nil && len(stream) == 0 {
+			continue
+		}
+		return stream, err
+	}
+	return nil, err
+}
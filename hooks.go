@@ -0,0 +1,64 @@
+package rbxfetch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptrace"
+
+	"github.com/anaminus/iofl"
+)
+
+// RequestHooks contains functions called around each HTTP request made by a
+// client, such as to add headers, start tracing spans, or measure latency.
+// Each function is optional.
+type RequestHooks struct {
+	// BeforeRequest is called with each request before it is made, after the
+	// request's headers and credentials have been added. It may modify the
+	// request. Returning an error fails the request.
+	BeforeRequest func(req *http.Request) error
+	// AfterResponse is called after each attempt of a request, with either
+	// the response, or the error that occurred. The body of the response must
+	// not be read or closed.
+	AfterResponse func(req *http.Request, resp *http.Response, err error)
+	// Trace, if it returns a non-nil value, is attached to the context of
+	// the request, receiving the events of the request, such as DNS lookups
+	// and connection establishment.
+	Trace func(req *http.Request) *httptrace.ClientTrace
+}
+
+// before prepares req according to the hooks, returning the request to be
+// made.
+func (h RequestHooks) before(req *http.Request) (*http.Request, error) {
+	if h.Trace != nil {
+		if trace := h.Trace(req); trace != nil {
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		}
+	}
+	if h.BeforeRequest != nil {
+		if err := h.BeforeRequest(req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// after reports the outcome of an attempt of req.
+func (h RequestHooks) after(req *http.Request, resp *http.Response, err error) {
+	if h.AfterResponse != nil {
+		h.AfterResponse(req, resp, err)
+	}
+}
+
+// applyHooks applies request hooks to the chain of filters.
+func applyHooks(filter iofl.Filter, hooks RequestHooks) {
+	type hooker interface {
+		iofl.Filter
+		SetHooks(hooks RequestHooks)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(hooker); ok {
+			f.SetHooks(hooks)
+		}
+		return nil
+	})
+}
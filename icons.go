@@ -0,0 +1,100 @@
+package rbxfetch
+
+import (
+	"crypto/sha256"
+	"errors"
+	"image"
+	"image/draw"
+	"image/png"
+)
+
+// IconHashes returns a hash of each icon within an icon sheet. The height of
+// the sheet is the size of each icon, and icons are arranged horizontally.
+// Hashes are computed from the normalized pixels of each icon, so that
+// differences in encoding do not affect the result.
+func IconHashes(sheet image.Image) (hashes [][sha256.Size]byte, err error) {
+	bounds := sheet.Bounds()
+	size := bounds.Dy()
+	if size <= 0 {
+		return nil, errors.New("empty icon sheet")
+	}
+	if bounds.Dx()%size != 0 {
+		return nil, errors.New("icon sheet width is not a multiple of its height")
+	}
+	icon := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for x := bounds.Min.X; x < bounds.Max.X; x += size {
+		draw.Draw(icon, icon.Bounds(), sheet, image.Pt(x, bounds.Min.Y), draw.Src)
+		hashes = append(hashes, sha256.Sum256(icon.Pix))
+	}
+	return hashes, nil
+}
+
+// IconDiff describes the differences between the icon sheets of two builds.
+// Each field lists the indexes of the icons within the sheets.
+type IconDiff struct {
+	// Added lists icons present only in the second sheet.
+	Added []int
+	// Removed lists icons present only in the first sheet.
+	Removed []int
+	// Changed lists icons present in both sheets that differ.
+	Changed []int
+}
+
+// Empty returns whether the diff contains no differences.
+func (d IconDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffIcons compares two lists of icon hashes, as returned by IconHashes.
+func DiffIcons(prev, next [][sha256.Size]byte) (d IconDiff) {
+	for i := range prev {
+		if i >= len(next) {
+			d.Removed = append(d.Removed, i)
+		} else if prev[i] != next[i] {
+			d.Changed = append(d.Changed, i)
+		}
+	}
+	for i := len(prev); i < len(next); i++ {
+		d.Added = append(d.Added, i)
+	}
+	return d
+}
+
+// iconHashes returns the hash of each icon within the class icon sheet of
+// guid, as derived by DerivedIconHashes.
+func (client *Client) iconHashes(guid string) (hashes [][sha256.Size]byte, err error) {
+	v, err := client.derive(DerivedIconHashes, guid)
+	if err != nil {
+		return nil, err
+	}
+	return v.([][sha256.Size]byte), nil
+}
+
+// scanIconHashes fetches the class icon sheet of guid, and returns the hash of
+// each icon.
+func (client *Client) scanIconHashes(guid string) (hashes [][sha256.Size]byte, err error) {
+	rc, err := client.ClassImages(guid)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	sheet, err := png.Decode(rc)
+	if err != nil {
+		return nil, classify(ErrDecode, err)
+	}
+	return IconHashes(sheet)
+}
+
+// IconChanges fetches the class icon sheets of the builds prev and next, and
+// reports which icons differ between them.
+func (client *Client) IconChanges(prev, next string) (d IconDiff, err error) {
+	a, err := client.iconHashes(prev)
+	if err != nil {
+		return d, err
+	}
+	b, err := client.iconHashes(next)
+	if err != nil {
+		return d, err
+	}
+	return DiffIcons(a, b), nil
+}
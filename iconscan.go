@@ -3,9 +3,12 @@ package rbxfetch
 import (
 	"bufio"
 	"bytes"
-	"image"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
 	"image/png"
 	"io"
+	"sync"
 
 	"github.com/anaminus/iofl"
 )
@@ -15,27 +18,51 @@ import (
 //
 // Because the source may contain multiple images, the following heuristic is
 // used: the format of the image is PNG, the height of the image is Size, the
-// width is a multiple of Size, and is the first widest such image.
+// width is a multiple of Size, and is the first widest such image. Reading
+// fails with ErrDecode if the source contains no such image.
+//
+// Images are selected by the dimensions in their headers, so only the chunks
+// of candidate images are retained, and only candidates are decoded to verify
+// that they are valid. Parallel is the number of candidates that are decoded
+// concurrently while scanning continues. If less than 1, then candidates are
+// decoded one at a time.
+//
+// Candidate images are retained within Memory, which may be nil.
 type FilterIconScan struct {
-	Size int
+	Size     int
+	Parallel int
+	Memory   *MemoryBudget
 
-	r   io.ReadCloser
-	buf bytes.Buffer
-	err error
+	r       io.ReadCloser
+	buf     *bytes.Reader
+	held    int64
+	scanned bool
+	err     error
 }
 
 // NewFilterIconScan is an iofl.NewFilter that returns a FilterIconScan.
 func NewFilterIconScan(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error) {
 	return &FilterIconScan{r: r,
-		Size: params.GetInt("Size"),
+		Size:     params.GetInt("Size"),
+		Parallel: params.GetInt("Parallel"),
 	}, nil
 }
 
+func (f *FilterIconScan) SetMemory(m *MemoryBudget) {
+	f.Memory = m
+}
+
 func (f *FilterIconScan) Source() io.ReadCloser {
 	return f.r
 }
 
+func (f *FilterIconScan) Failure() error {
+	return failure(f.err)
+}
+
 func (f *FilterIconScan) Close() error {
+	f.Memory.release(f.held)
+	f.held = 0
 	if f.err != nil {
 		return f.err
 	}
@@ -64,41 +91,180 @@ func readBytes(r *bufio.Reader, sep []byte) error {
 	return nil
 }
 
-// scan scans f.r for an image, writing the result to f.buf.
+// pngHeader is the signature that begins every PNG image.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+// maxChunkSize is the largest PNG chunk that is accepted while extracting an
+// image. Icon sheets are far smaller, so a larger length is taken to be a
+// false match within the executable.
+const maxChunkSize = 8 << 20
+
+// pngSize returns the width and height of the PNG image beginning at the
+// current position of r, without consuming any bytes. ok is false if the image
+// does not begin with an IHDR chunk.
+func pngSize(r *bufio.Reader) (width, height int, ok bool) {
+	b, err := r.Peek(len(pngHeader) + 16)
+	if err != nil || !bytes.Equal(b[:len(pngHeader)], pngHeader) {
+		return 0, 0, false
+	}
+	b = b[len(pngHeader):]
+	if string(b[4:8]) != "IHDR" {
+		return 0, 0, false
+	}
+	return int(binary.BigEndian.Uint32(b[8:12])), int(binary.BigEndian.Uint32(b[12:16])), true
+}
+
+// extractPNG reads the chunks of the PNG image beginning at the current
+// position of r, returning the bytes of the image. The checksum of each chunk
+// is verified, but the image is not decoded. The bytes are reserved from m,
+// and are released if an error is returned.
+func extractPNG(r *bufio.Reader, m *MemoryBudget) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			m.release(int64(len(b)))
+		}
+	}()
+	if err = m.reserve(int64(len(pngHeader))); err != nil {
+		return nil, err
+	}
+	b = make([]byte, len(pngHeader), 4096)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return b, err
+	}
+	for {
+		var head [8]byte
+		if _, err = io.ReadFull(r, head[:]); err != nil {
+			return b, err
+		}
+		length := binary.BigEndian.Uint32(head[:4])
+		if length > maxChunkSize {
+			return b, errors.New("png chunk too large")
+		}
+		if err = m.reserve(int64(len(head)) + int64(length) + 4); err != nil {
+			return b, err
+		}
+		b = append(b, head[:]...)
+		start := len(b)
+		b = append(b, make([]byte, length+4)...)
+		if _, err = io.ReadFull(r, b[start:]); err != nil {
+			return b, err
+		}
+		data := b[start-4 : len(b)-4]
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(b[len(b)-4:]) {
+			return b, errors.New("png chunk checksum mismatch")
+		}
+		if string(head[4:]) == "IEND" {
+			return b, nil
+		}
+	}
+}
+
+// iconCandidate is an image selected by its dimensions.
+type iconCandidate struct {
+	content []byte
+	width   int
+	valid   bool
+}
+
+// release returns the memory of each candidate other than keep to the budget.
+func (f *FilterIconScan) release(candidates []*iconCandidate, keep *iconCandidate) {
+	for _, c := range candidates {
+		if c != keep {
+			f.Memory.release(int64(len(c.content)))
+		}
+	}
+}
+
+// scan scans f.r for an image, setting f.buf to the result.
 func (f *FilterIconScan) scan() (err error) {
-	header := []byte("\x89PNG\r\n\x1a\n")
-	var largest image.Image
-	for br := bufio.NewReader(f.r); ; {
+	parallel := f.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var candidates []*iconCandidate
+	// widest is the width of the widest candidate that has been validated.
+	var mu sync.Mutex
+	widest := 0
+	br := bufio.NewReader(f.r)
+	for {
 		// Scan for PNG headers.
-		if err := readBytes(br, header); err != nil {
-			if err == io.EOF && largest != nil {
+		if err := readBytes(br, pngHeader); err != nil {
+			if err == io.EOF {
 				break
 			}
+			wg.Wait()
+			f.release(candidates, nil)
 			f.r.Close()
 			return err
 		}
-		f.buf.Reset()
-		img, err := png.Decode(io.TeeReader(br, &f.buf))
 		// Select when height equals Size, and width is multiple of Size.
-		if err != nil || img.Bounds().Dy() != f.Size || img.Bounds().Dx()%f.Size != 0 {
+		// Select first widest.
+		width, height, ok := pngSize(br)
+		mu.Lock()
+		narrower := width <= widest
+		mu.Unlock()
+		if !ok || height != f.Size || width%f.Size != 0 || narrower {
+			br.Discard(len(pngHeader))
+			continue
+		}
+		content, err := extractPNG(br, f.Memory)
+		if errors.Is(err, ErrMemoryBudget) {
+			wg.Wait()
+			f.release(candidates, nil)
+			f.r.Close()
+			return err
+		}
+		if err != nil {
 			continue
 		}
-		//  Select first widest.
-		if largest == nil || img.Bounds().Dx() > largest.Bounds().Dx() {
-			largest = img
+		c := &iconCandidate{content: content, width: width}
+		candidates = append(candidates, c)
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, err := png.Decode(bytes.NewReader(c.content))
+			mu.Lock()
+			defer mu.Unlock()
+			c.valid = err == nil
+			// Only a valid candidate excludes narrower images, since an
+			// invalid one may be followed by a valid image of the same width.
+			if c.valid && c.width > widest {
+				widest = c.width
+			}
+		}()
+	}
+	wg.Wait()
+	// Select the first widest valid candidate.
+	var best *iconCandidate
+	for _, c := range candidates {
+		if c.valid && (best == nil || c.width > best.width) {
+			best = c
 		}
 	}
-	return f.r.Close()
+	if best != nil {
+		f.release(candidates, best)
+		f.buf = bytes.NewReader(best.content)
+		f.held = int64(len(best.content))
+		return f.r.Close()
+	}
+	f.release(candidates, nil)
+	f.r.Close()
+	return errors.New("no icon sheet found")
 }
 
 func (f *FilterIconScan) Read(p []byte) (n int, err error) {
 	if f.err != nil {
 		return 0, f.err
 	}
-	if f.buf.Len() == 0 {
+	if !f.scanned {
+		f.scanned = true
 		if err := f.scan(); err != nil {
-			f.err = err
-			return 0, err
+			f.err = classify(ErrDecode, err)
+			return 0, f.err
 		}
 	}
 	return f.buf.Read(p)
@@ -0,0 +1,215 @@
+package rbxfetch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/robloxapi/rbxdump/histlog"
+)
+
+// indexDirName is the name of the directory within a cache directory that
+// contains build indexes.
+const indexDirName = "index"
+
+// buildIndexFormat is the version of the format of stored build indexes.
+// Stored indexes of other formats are discarded.
+const buildIndexFormat = 1
+
+// buildIndexFile is the stored form of a build index.
+type buildIndexFile struct {
+	Format int
+	// Size is the number of bytes of the history from which Builds were
+	// listed, which ends at a line boundary.
+	Size int
+	// Hash is the hex-encoded SHA-256 hash of the first Size bytes of the
+	// history.
+	Hash   string
+	Builds []Build
+}
+
+// BuildIndex is an index of the builds listed by a deployment history.
+type BuildIndex struct {
+	// Builds lists the builds in the order in which they appear in the
+	// history.
+	Builds []Build
+
+	guids    map[string]int
+	versions map[Version][]int
+}
+
+// NewBuildIndex returns an index of the given builds.
+func NewBuildIndex(builds []Build) *BuildIndex {
+	index := &BuildIndex{
+		Builds:   builds,
+		guids:    make(map[string]int, len(builds)),
+		versions: make(map[Version][]int, len(builds)),
+	}
+	for i, build := range builds {
+		guid := strings.ToLower(build.GUID)
+		if _, ok := index.guids[guid]; !ok {
+			index.guids[guid] = i
+		}
+		index.versions[build.Version] = append(index.versions[build.Version], i)
+	}
+	return index
+}
+
+// GUID returns the first build with the given GUID, compared
+// case-insensitively.
+func (index *BuildIndex) GUID(guid string) (build Build, ok bool) {
+	i, ok := index.guids[strings.ToLower(guid)]
+	if !ok {
+		return build, false
+	}
+	return index.Builds[i], true
+}
+
+// Version returns the builds with the given version, in order.
+func (index *BuildIndex) Version(v Version) []Build {
+	var builds []Build
+	for _, i := range index.versions[v] {
+		builds = append(builds, index.Builds[i])
+	}
+	return builds
+}
+
+// Range returns the builds with a version within r, in order.
+func (index *BuildIndex) Range(r VersionRange) []Build {
+	return BuildsInRange(index.Builds, r)
+}
+
+// Between returns the builds deployed at or after from, and before to, in
+// order. A zero from or to leaves that side unbounded.
+func (index *BuildIndex) Between(from, to time.Time) []Build {
+	var builds []Build
+	for _, build := range index.Builds {
+		if !from.IsZero() && build.Date.Before(from) ||
+			!to.IsZero() && !build.Date.Before(to) {
+			continue
+		}
+		builds = append(builds, build)
+	}
+	return builds
+}
+
+// BuildIndex returns an index of the builds listed by the "Builds" method.
+// Returns nil if no "Builds" method is configured. Only chains of the default
+// track are used.
+//
+// If the client caches content, then the listed builds are stored in the
+// cache directory along with a hash of the history from which they were
+// listed. When the history has only been appended to since, as is usual, only
+// the appended content is lexed, so that a new process need not lex the entire
+// history again. Failure to store the index is not an error.
+//
+// Unlike Builds, the builds are neither enriched nor canonicalized.
+func (client *Client) BuildIndex() (index *BuildIndex, err error) {
+	return client.buildIndex("Builds", "", nil)
+}
+
+// buildIndex returns an index of the builds listed by the first chain of
+// method of the given track that does not error.
+func (client *Client) buildIndex(method, track string, vars map[string]string) (index *BuildIndex, err error) {
+	for _, chain := range client.historyChains(method, track, vars) {
+		start := time.Now()
+		var rc io.ReadCloser
+		if rc, err = client.history(method, chain, vars); err != nil {
+			client.observe(method, chain, start, err)
+			continue
+		}
+		var content []byte
+		content, err = io.ReadAll(rc)
+		rc.Close()
+		client.observe(method, chain, start, err)
+		if err != nil {
+			continue
+		}
+		scanner := buildScanner{
+			channel: client.channel(vars),
+			track:   track,
+			mac:     strings.EqualFold(track, "mac") || vars["PLATFORMPATH"] == "mac/",
+		}
+		return NewBuildIndex(client.indexBuilds(client.historyKey(chain, vars), content, &scanner)), nil
+	}
+	return nil, err
+}
+
+// indexBuilds returns the builds listed by content, the history identified by
+// key. Builds previously stored for the history are reused.
+func (client *Client) indexBuilds(key string, content []byte, scanner *buildScanner) []Build {
+	var builds []Build
+	scan := func(b []byte) {
+		scanHistory(bytes.NewReader(b), func(token histlog.Token, line string) bool {
+			if build := scanner.scan(token, line); build != nil {
+				builds = append(builds, *build)
+			}
+			return true
+		})
+	}
+
+	// Only complete lines are stored, since a partial line may yet be
+	// appended to.
+	size := bytes.LastIndexByte(content, '\n') + 1
+	var path string
+	offset := 0
+	if dir := cacheDir(client.CacheMode, client.CacheLocation); dir != "" {
+		sum := sha256.Sum256([]byte(key))
+		path = filepath.Join(dir, indexDirName, hex.EncodeToString(sum[:16])+".json")
+		if file, ok := readBuildIndex(path); ok && file.Size >= 0 && file.Size <= size && hashHex(content[:file.Size]) == file.Hash {
+			builds, offset = file.Builds, file.Size
+			// The status of the last build may be appended.
+			if n := len(builds); n > 0 && builds[n-1].Status == "" {
+				pending := builds[n-1]
+				scanner.pending = &pending
+				builds = builds[:n-1]
+			}
+		}
+	}
+
+	scan(content[offset:size])
+	if path != "" && size > offset {
+		stored := builds[:len(builds):len(builds)]
+		if scanner.pending != nil {
+			stored = append(stored, *scanner.pending)
+		}
+		if b, err := json.Marshal(buildIndexFile{
+			Format: buildIndexFormat,
+			Size:   size,
+			Hash:   hashHex(content[:size]),
+			Builds: stored,
+		}); err == nil {
+			saveFile(path, bytes.NewReader(b))
+		}
+	}
+	scan(content[size:])
+	if build := scanner.flush(); build != nil {
+		builds = append(builds, *build)
+	}
+	return builds
+}
+
+// readBuildIndex reads the stored build index at path. Returns false if the
+// index does not exist or is not of the current format.
+func readBuildIndex(path string) (file buildIndexFile, ok bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return file, false
+	}
+	if err := json.Unmarshal(b, &file); err != nil || file.Format != buildIndexFormat {
+		return file, false
+	}
+	return file, true
+}
+
+// hashHex returns the hex-encoded SHA-256 hash of b.
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
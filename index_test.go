@@ -0,0 +1,107 @@
+package rbxfetch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildIndexLookup(t *testing.T) {
+	version := func(s string) Version {
+		v, err := ParseVersion(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	day := func(d int) time.Time {
+		return time.Date(2023, 11, d, 10, 0, 0, 0, time.UTC)
+	}
+	index := NewBuildIndex([]Build{
+		{GUID: "version-a", Version: version("0.600.0.1"), Date: day(1)},
+		{GUID: "version-b", Version: version("0.600.0.1"), Date: day(2)},
+		{GUID: "version-c", Version: version("0.601.0.1"), Date: day(8)},
+		{GUID: "version-d", Version: version("0.602.0.1"), Date: day(15)},
+	})
+	guids := func(builds []Build) string {
+		var s []string
+		for _, build := range builds {
+			s = append(s, build.GUID)
+		}
+		return strings.Join(s, ",")
+	}
+
+	if build, ok := index.GUID("VERSION-C"); !ok || build.GUID != "version-c" {
+		t.Errorf("expected version-c by GUID, got %v, %v", build.GUID, ok)
+	}
+	if _, ok := index.GUID("version-e"); ok {
+		t.Error("expected version-e to be absent")
+	}
+	if got := guids(index.Version(version("0.600.0.1"))); got != "version-a,version-b" {
+		t.Errorf("expected builds of 0.600.0.1, got %s", got)
+	}
+	if got := guids(index.Range(VersionRange{Min: version("0.600.0.2"), Max: version("0.602.0.1")})); got != "version-c,version-d" {
+		t.Errorf("expected builds within range, got %s", got)
+	}
+	// From is inclusive, and to is exclusive.
+	if got := guids(index.Between(day(2), day(15))); got != "version-b,version-c" {
+		t.Errorf("expected builds between dates, got %s", got)
+	}
+	if got := guids(index.Between(time.Time{}, day(2))); got != "version-a" {
+		t.Errorf("expected builds before date, got %s", got)
+	}
+}
+
+func TestIndexBuildsStored(t *testing.T) {
+	const (
+		a = "New Studio64 version-a at 11/1/2023 10:00:00 AM, file version: 0, 600, 0, 1...Done!\r\n"
+		b = "New Studio64 version-b at 11/8/2023 10:00:00 AM, file version: 0, 601, 0, 1...Done!\r\n"
+		c = "New Studio64 version-c at 11/9/2023 10:00:00 AM, file version: 0, 602, 0, 1...\r\n"
+		z = "New Studio64 version-z at 11/9/2023 10:00:00 AM, file version: 0, 602, 0, 1...Done!\r\n"
+	)
+	client := NewClient()
+	client.CacheMode = CacheCustom
+	client.CacheLocation = t.TempDir()
+	index := func(content string) (builds []string) {
+		for _, build := range client.indexBuilds("history", []byte(content), &buildScanner{}) {
+			builds = append(builds, build.GUID+":"+build.Type+":"+build.Status)
+		}
+		return builds
+	}
+	index(a + b)
+	// Mark the stored builds, so that builds which were not lexed again can
+	// be distinguished.
+	paths, _ := filepath.Glob(filepath.Join(client.CacheLocation, indexDirName, "*"))
+	if len(paths) != 1 {
+		t.Fatalf("expected one stored index, got %q", paths)
+	}
+	file, ok := readBuildIndex(paths[0])
+	if !ok {
+		t.Fatal("stored index not readable")
+	}
+	for i := range file.Builds {
+		file.Builds[i].Type = "Stored"
+	}
+	data, _ := json.Marshal(file)
+	if err := os.WriteFile(paths[0], data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name    string
+		content string
+		builds  string
+	}{
+		{"appended", a + b + c, "version-a:Stored:Done,version-b:Stored:Done,version-c:Studio64:"},
+		{"completed", a + b + c + "Done!\r\n", "version-a:Stored:Done,version-b:Stored:Done,version-c:Studio64:Done"},
+		{"replaced", z, "version-z:Studio64:Done"},
+	}
+	for _, step := range steps {
+		if got := strings.Join(index(step.content), ","); got != step.builds {
+			t.Errorf("%s: expected %s, got %s", step.name, step.builds, got)
+		}
+	}
+}
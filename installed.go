@@ -0,0 +1,143 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// studioAppSettings is the file written by the bootstrapper to the directory
+// of a build of Studio once the build is installed.
+const studioAppSettings = "AppSettings.xml"
+
+// studioExecutable is the executable of a build of Studio, which distinguishes
+// the directory of a Studio build from that of a Player build installed
+// alongside it.
+const studioExecutable = "RobloxStudioBeta.exe"
+
+// StudioInstalls returns the builds of Studio installed within root, or within
+// StudioVersionsDir if root is empty, ordered from most to least recently
+// installed. A build is considered installed when its directory contains
+// AppSettings.xml, the modification time of which is used as the Date of the
+// build. Directories without RobloxStudioBeta.exe, such as those of Player
+// builds, are skipped. Only the GUID and Date of each build are known.
+func StudioInstalls(root string) (builds []Build, err error) {
+	if root == "" {
+		if root = StudioVersionsDir(); root == "" {
+			return nil, fmt.Errorf("studio: no installation directory on %s: %w", runtime.GOOS, os.ErrNotExist)
+		}
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "version-") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, entry.Name(), studioAppSettings))
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, entry.Name(), studioExecutable)); err != nil {
+			continue
+		}
+		builds = append(builds, Build{
+			Type:       BuildStudio64,
+			GUID:       entry.Name(),
+			Date:       info.ModTime(),
+			BinaryType: BinaryWindowsStudio64,
+		})
+	}
+	sort.SliceStable(builds, func(i, j int) bool {
+		return builds[i].Date.After(builds[j].Date)
+	})
+	return builds, nil
+}
+
+// LocalStudio returns the build of Studio installed on the local system, as
+// determined only from local metadata. On Windows, the build recorded in the
+// registry by the bootstrapper is preferred, followed by the most recently
+// installed build listed by StudioInstalls. On macOS, the build of
+// MacStudioApp is returned, as determined by MacStudioBuild.
+//
+// Depending on the system, either the GUID or the Version of the returned
+// build may be unknown; Client.InstalledStudio fills in the remainder from
+// the deployment history. If no build is installed, then the returned error
+// wraps os.ErrNotExist.
+func LocalStudio() (build Build, err error) {
+	if runtime.GOOS == "darwin" {
+		return MacStudioBuild("")
+	}
+	return localStudio(StudioVersionsDir(), registryStudioGUID())
+}
+
+// localStudio returns the build installed within root, preferring the build
+// of the given GUID if it is installed.
+func localStudio(root, guid string) (build Build, err error) {
+	builds, err := StudioInstalls(root)
+	if err != nil {
+		return build, err
+	}
+	for _, b := range builds {
+		if strings.EqualFold(b.GUID, guid) {
+			return b, nil
+		}
+	}
+	if len(builds) == 0 {
+		return build, fmt.Errorf("studio: no build installed in %s: %w", root, os.ErrNotExist)
+	}
+	return builds[0], nil
+}
+
+// InstalledStudio returns the build of Studio installed on the local system,
+// as determined by LocalStudio. If the GUID or Version of the build is not
+// known locally, it is resolved from the deployment history of the client,
+// allowing content such as the API dump of the installed build to be fetched
+// without the caller guessing the GUID. The build is returned with an error if
+// it could not be resolved.
+func (client *Client) InstalledStudio() (build Build, err error) {
+	if build, err = LocalStudio(); err != nil {
+		return build, err
+	}
+	return client.resolveBuild(build)
+}
+
+// resolveBuild fills in the GUID or Version of build from the deployment
+// history of the build's track.
+func (client *Client) resolveBuild(build Build) (Build, error) {
+	if build.GUID != "" && !build.Version.Empty() {
+		return build, nil
+	}
+	found := false
+	err := client.walkBuilds("Builds", "Live", build.Track, nil, func(b Build) bool {
+		if build.GUID != "" && strings.EqualFold(b.GUID, build.GUID) ||
+			build.GUID == "" && b.Version == build.Version && strings.HasPrefix(b.Type, BuildStudio) {
+			if build.GUID == "" {
+				build.GUID = b.GUID
+			}
+			build.Version = b.Version
+			found = true
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return build, err
+	}
+	if !found {
+		return build, fmt.Errorf("studio: build %s not found in deployment history", buildName(build))
+	}
+	return build, nil
+}
+
+// buildName returns the GUID of build, or its version if the GUID is unknown.
+func buildName(build Build) string {
+	if build.GUID != "" {
+		return build.GUID
+	}
+	return build.Version.String()
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package rbxfetch
+
+// registryStudioGUID returns the GUID of the installed build of Studio as
+// recorded in the registry. The registry exists only on Windows.
+func registryStudioGUID() string {
+	return ""
+}
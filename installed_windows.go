@@ -0,0 +1,32 @@
+//go:build windows
+
+package rbxfetch
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// studioRegistryKey is the registry key, under HKEY_CURRENT_USER, in which the
+// bootstrapper records the installed build of Studio.
+const studioRegistryKey = `Software\ROBLOX Corporation\Environments\roblox-studio`
+
+// registryStudioGUID returns the GUID of the installed build of Studio as
+// recorded in the registry, or an empty string if it is not recorded.
+func registryStudioGUID() string {
+	var key syscall.Handle
+	if syscall.RegOpenKeyEx(syscall.HKEY_CURRENT_USER, syscall.StringToUTF16Ptr(studioRegistryKey), 0, syscall.KEY_READ, &key) != nil {
+		return ""
+	}
+	defer syscall.RegCloseKey(key)
+	name := syscall.StringToUTF16Ptr("version")
+	var typ, n uint32
+	if syscall.RegQueryValueEx(key, name, nil, &typ, nil, &n) != nil || typ != syscall.REG_SZ || n == 0 {
+		return ""
+	}
+	buf := make([]uint16, n/2+1)
+	if syscall.RegQueryValueEx(key, name, nil, &typ, (*byte)(unsafe.Pointer(&buf[0])), &n) != nil {
+		return ""
+	}
+	return syscall.UTF16ToString(buf)
+}
@@ -0,0 +1,72 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/anaminus/iofl"
+)
+
+// MethodInfo describes a method configured on a client.
+type MethodInfo struct {
+	// Name is the name of the method.
+	Name string
+	// Chains describes each chain of the method, in the order they would
+	// currently be attempted.
+	Chains []ChainInfo
+}
+
+// ChainInfo describes a chain configured on a client.
+type ChainInfo struct {
+	// Name is the name of the chain.
+	Name string
+	// Weight is the configured weight of the chain.
+	Weight float64
+	// Links contains each link of the chain, in order, with unexpanded
+	// parameters.
+	Links iofl.Chain
+}
+
+// Methods returns the names of the methods configured on the client, in
+// sorted order.
+func (client *Client) Methods() []string {
+	return sortedKeys(client.snapshot().methods)
+}
+
+// Chains returns the names of the chains configured on the client, in sorted
+// order.
+func (client *Client) Chains() []string {
+	return sortedKeys(client.snapshot().chainSet.Config().Chains)
+}
+
+// Filters returns the names of the filters registered with the client, in
+// sorted order.
+func (client *Client) Filters() []string {
+	var names []string
+	for _, filter := range client.snapshot().filters {
+		names = append(names, filter.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Describe returns a description of the given method, including the structure
+// and parameters of each of its chains. Returns an error if the method is not
+// configured.
+func (client *Client) Describe(method string) (info MethodInfo, err error) {
+	snapshot := client.snapshot()
+	if _, ok := snapshot.methods[method]; !ok {
+		return MethodInfo{}, fmt.Errorf("unknown method %q", method)
+	}
+	info.Name = method
+	config := snapshot.chainSet.Config()
+	for _, name := range client.chains(method) {
+		chain := ChainInfo{Name: name, Weight: 1}
+		if w, ok := snapshot.weights[name]; ok {
+			chain.Weight = w
+		}
+		chain.Links = append(iofl.Chain(nil), config.Chains[name]...)
+		info.Chains = append(info.Chains, chain)
+	}
+	return info, nil
+}
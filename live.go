@@ -0,0 +1,194 @@
+package rbxfetch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ClientVersion describes a live build, as reported by a Live chain.
+type ClientVersion struct {
+	// Version is the version of the build, if reported.
+	Version string `json:"version"`
+	// ClientVersionUpload is the GUID of the build.
+	ClientVersionUpload string `json:"clientVersionUpload"`
+	// BootstrapperVersion is the version of the bootstrapper, if reported.
+	BootstrapperVersion string `json:"bootstrapperVersion"`
+}
+
+// decodeLive decodes a version from either a JSON string containing a GUID,
+// or an object containing the "clientVersionUpload" field. If strict is true,
+// then only a JSON string is accepted.
+func decodeLive(raw json.RawMessage, strict bool) (v ClientVersion, err error) {
+	if err = json.Unmarshal(raw, &v.ClientVersionUpload); err == nil || strict {
+		return v, err
+	}
+	if err = json.Unmarshal(raw, &v); err != nil {
+		return v, err
+	}
+	if v.ClientVersionUpload == "" {
+		return v, errors.New("missing clientVersionUpload")
+	}
+	return v, nil
+}
+
+// liveChains returns the chains of method that are visited by a Live method.
+// Chains that are the fallback of another chain are excluded.
+func (client *Client) liveChains(method string) (chains []string) {
+	for _, chain := range client.snapshot().methods[method] {
+		if client.chainParam(chain, "FallbackFor") == "" {
+			chains = append(chains, chain)
+		}
+	}
+	return chains
+}
+
+// liveChain fetches a version from a single chain of a Live method. If the
+// chain fails, then each chain of the method whose "FallbackFor" parameter
+// names the chain is tried in turn, and the error of the chain is returned if
+// every fallback also fails. If ctx is not nil, then it is the context of the
+// chain's requests.
+func (client *Client) liveChain(ctx context.Context, method, chain string, vars map[string]string) (v ClientVersion, err error) {
+	if v, err = client.liveFetch(ctx, method, chain, vars); err == nil {
+		return v, nil
+	}
+	for _, fallback := range client.snapshot().methods[method] {
+		if !strings.EqualFold(client.chainParam(fallback, "FallbackFor"), chain) {
+			continue
+		}
+		if ctx != nil && ctx.Err() != nil {
+			break
+		}
+		if fv, ferr := client.liveFetch(ctx, method, fallback, vars); ferr == nil {
+			return fv, nil
+		}
+	}
+	return v, err
+}
+
+// liveFetch fetches a version from chain, without falling back.
+func (client *Client) liveFetch(ctx context.Context, method, chain string, vars map[string]string) (v ClientVersion, err error) {
+	start := time.Now()
+	f, err := client.resolveVars(method, chain, "", vars)
+	if err != nil {
+		return v, err
+	}
+	if ctx != nil {
+		applyContext(f, ctx)
+	}
+	var raw []byte
+	if raw, err = readBounded(client.newResult(method, chain, f, false), maxVersionSize); err == nil {
+		v, err = decodeLive(raw, client.StrictLive)
+		err = classify(ErrDecode, err)
+	}
+	f.Close()
+	if ctx == nil || ctx.Err() == nil {
+		client.observe(method, chain, start, err)
+	}
+	return v, err
+}
+
+// liveVersions visits every chain of method, returning the version of each,
+// or the first error that occurs. vars are passed to each chain. If
+// PartialLive is set, then livePartial is used instead.
+func (client *Client) liveVersions(method string, vars map[string]string) (versions []ClientVersion, err error) {
+	if client.PartialLive {
+		return client.livePartial(method, vars)
+	}
+	if client.Race {
+		return client.liveRace(method, vars)
+	}
+	for _, chain := range client.liveChains(method) {
+		var v ClientVersion
+		if v, err = client.liveChain(nil, method, chain, vars); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// LiveVersions behaves like Live, but returns the full version information
+// reported by each chain. Fields other than ClientVersionUpload are empty for
+// chains that report only a GUID.
+func (client *Client) LiveVersions() (versions []ClientVersion, err error) {
+	return client.liveVersions("Live", nil)
+}
+
+// livePartial visits every chain of method concurrently, each bounded by
+// LiveTimeout. The versions of successful chains are returned in the order of
+// their chains, along with a ChainErrors for the chains that failed. Chains
+// that have not completed once LiveTimeout has elapsed are cancelled.
+func (client *Client) livePartial(method string, vars map[string]string) (versions []ClientVersion, err error) {
+	type result struct {
+		v   ClientVersion
+		err error
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if client.LiveTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), client.LiveTimeout)
+	}
+	defer cancel()
+	chains := client.liveChains(method)
+	results := make([]chan result, len(chains))
+	for i, chain := range chains {
+		results[i] = make(chan result, 1)
+		go func(chain string, c chan<- result) {
+			v, err := client.liveChain(ctx, method, chain, vars)
+			c <- result{v: v, err: err}
+		}(chain, results[i])
+	}
+
+	var errs ChainErrors
+	for i, c := range results {
+		var r result
+		select {
+		case r = <-c:
+		case <-ctx.Done():
+			// Once the timeout has fired, select chooses randomly between it
+			// and a chain that has already completed, so check the chain
+			// again before reporting it as timed out. Remaining chains are
+			// abandoned, and close themselves once cancelled.
+			select {
+			case r = <-c:
+			default:
+				r.err = ErrTimeout
+			}
+		}
+		if r.err != nil && errors.Is(r.err, context.DeadlineExceeded) {
+			r.err = ErrTimeout
+		}
+		if r.err != nil {
+			errs = append(errs, newChainError(method, chains[i], r.err))
+			continue
+		}
+		versions = append(versions, r.v)
+	}
+	if len(errs) > 0 {
+		return versions, errs
+	}
+	return versions, nil
+}
+
+// enricher returns a function that sets the live information of a build that
+// matches a version produced by the live method. Errors are ignored; returns
+// nil if no versions are produced.
+func (client *Client) enricher(method string, vars map[string]string) func(b *Build) {
+	versions, _ := client.liveVersions(method, vars)
+	if len(versions) == 0 {
+		return nil
+	}
+	return func(b *Build) {
+		for _, v := range versions {
+			if !strings.EqualFold(b.GUID, v.ClientVersionUpload) {
+				continue
+			}
+			b.Live = true
+			if v.BootstrapperVersion != "" {
+				b.BootstrapperVersion = v.BootstrapperVersion
+			}
+		}
+	}
+}
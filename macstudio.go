@@ -0,0 +1,316 @@
+package rbxfetch
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// MacStudioApp returns the location of the RobloxStudio.app bundle installed
+// on the local system, searching /Applications, then the Applications
+// directory of the user. Returns an empty string if no bundle is found.
+func MacStudioApp() string {
+	dirs := []string{"/Applications"}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "Applications"))
+	}
+	for _, dir := range dirs {
+		app := filepath.Join(dir, "RobloxStudio.app")
+		if info, err := os.Stat(app); err == nil && info.IsDir() {
+			return app
+		}
+	}
+	return ""
+}
+
+// plistStrings returns the string values of the top-level dictionary of an
+// XML property list, mapped by key. Other values are ignored.
+func plistStrings(r io.Reader) (values map[string]string, err error) {
+	values = map[string]string{}
+	d := xml.NewDecoder(r)
+	depth := 0
+	var key string
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("property list: %w", err)
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			depth++
+			// The dictionary is at depth 2, within the plist element.
+			if depth != 3 {
+				continue
+			}
+			var s string
+			switch t.Name.Local {
+			case "key":
+				if err := d.DecodeElement(&key, &t); err != nil {
+					return nil, fmt.Errorf("property list: %w", err)
+				}
+				depth--
+				continue
+			case "string":
+				if err := d.DecodeElement(&s, &t); err != nil {
+					return nil, fmt.Errorf("property list: %w", err)
+				}
+				depth--
+				values[key] = s
+			}
+			key = ""
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// MacStudioBuild returns the build of the RobloxStudio.app bundle at the
+// given location, or of MacStudioApp if app is empty. The version is read
+// from the Info.plist of the bundle. The GUID is set only if the property list
+// records it, as a value beginning with "version-".
+func MacStudioBuild(app string) (build Build, err error) {
+	if app == "" {
+		if app = MacStudioApp(); app == "" {
+			return build, fmt.Errorf("RobloxStudio.app: %w", os.ErrNotExist)
+		}
+	}
+	f, err := os.Open(filepath.Join(app, "Contents", "Info.plist"))
+	if err != nil {
+		return build, err
+	}
+	defer f.Close()
+	values, err := plistStrings(f)
+	if err != nil {
+		return build, err
+	}
+	build.Type = BuildStudio
+	build.BinaryType = BinaryMacStudio
+	build.Track = "mac"
+	for _, key := range []string{"CFBundleShortVersionString", "CFBundleVersion"} {
+		if v, err := ParseVersion(values[key]); err == nil {
+			build.Version = v
+			break
+		}
+	}
+	for _, key := range sortedKeys(values) {
+		if strings.HasPrefix(values[key], "version-") {
+			build.GUID = values[key]
+			break
+		}
+	}
+	if build.Version.Empty() && build.GUID == "" {
+		return build, errors.New("RobloxStudio.app: version not found")
+	}
+	return build, nil
+}
+
+// FilterMacStudio is an iofl.Filter that fetches a file from a RobloxStudio.app
+// bundle installed on the local system. App is the location of the bundle, or
+// empty to use MacStudioApp. File is the path of the file within the resources
+// of the bundle, with slash separators, such as "ReflectionMetadata.xml". Both
+// may contain variables such as $GUID.
+//
+// If the bundle records its GUID, and the GUID differs from the requested
+// GUID, then the returned error wraps os.ErrNotExist. Otherwise, the version
+// of the bundle is compared with the version of the requested GUID, as
+// returned by VersionOf, such as from a deployment history. If VersionOf is
+// nil, does not know the GUID, or returns a different version, then the
+// returned error also wraps os.ErrNotExist, so that a chain for another
+// source may be used instead.
+type FilterMacStudio struct {
+	App       string
+	File      string
+	GUID      string
+	Vars      map[string]string
+	VersionOf func(guid string) (v Version, ok bool)
+
+	r   io.ReadCloser
+	err error
+}
+
+// NewFilterMacStudio is an iofl.NewFilter that returns a FilterMacStudio.
+func NewFilterMacStudio(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error) {
+	return &FilterMacStudio{r: r,
+		App:  params.GetString("App"),
+		File: params.GetString("File"),
+	}, nil
+}
+
+func (f *FilterMacStudio) SetGUID(guid string) {
+	f.GUID = guid
+}
+
+func (f *FilterMacStudio) SetVars(vars map[string]string) {
+	f.Vars = vars
+}
+
+func (f *FilterMacStudio) SetVersionOf(versionOf func(guid string) (v Version, ok bool)) {
+	f.VersionOf = versionOf
+}
+
+func (f *FilterMacStudio) Source() io.ReadCloser {
+	return f.r
+}
+
+func (f *FilterMacStudio) Failure() error {
+	return failure(f.err)
+}
+
+func (f *FilterMacStudio) Close() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.r == nil {
+		// Nothing was opened.
+		f.err = iofl.Closed
+		return nil
+	}
+	if f.err = f.r.Close(); f.err == nil {
+		f.err = iofl.Closed
+		return nil
+	}
+	return f.err
+}
+
+// open opens the file within the resources of the bundle.
+func (f *FilterMacStudio) open() (io.ReadCloser, error) {
+	app := expandVars(f.App, f.GUID, f.Vars)
+	if app == "" {
+		if app = MacStudioApp(); app == "" {
+			return nil, fmt.Errorf("studio: RobloxStudio.app: %w", os.ErrNotExist)
+		}
+	}
+	if f.GUID != "" {
+		build, err := MacStudioBuild(app)
+		if err != nil {
+			return nil, fmt.Errorf("studio: %w", err)
+		}
+		if !f.installed(build) {
+			return nil, fmt.Errorf("studio: build %s is not installed: %w", f.GUID, os.ErrNotExist)
+		}
+	}
+	path := filepath.Join(app, "Contents", "Resources", filepath.FromSlash(expandVars(f.File, f.GUID, f.Vars)))
+	return os.Open(path)
+}
+
+// installed returns whether build, the build of the bundle, is the requested
+// build.
+func (f *FilterMacStudio) installed(build Build) bool {
+	if build.GUID != "" {
+		return strings.EqualFold(build.GUID, f.GUID)
+	}
+	if build.Version.Empty() || f.VersionOf == nil {
+		return false
+	}
+	v, ok := f.VersionOf(f.GUID)
+	return ok && v == build.Version
+}
+
+func (f *FilterMacStudio) Read(p []byte) (n int, err error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.r == nil {
+		if f.r, err = f.open(); err != nil {
+			f.err = err
+			return 0, err
+		}
+	}
+	return f.r.Read(p)
+}
+
+// macStudioChains returns the chains that fetch the content of methods from
+// the RobloxStudio.app bundle at app.
+func macStudioChains(app string) map[string]iofl.Chain {
+	return map[string]iofl.Chain{
+		"ReflectionMetadata": {
+			{Filter: "macstudio", Params: iofl.Params{"App": app, "File": "ReflectionMetadata.xml"}},
+		},
+		"ReflectionMetadataClasses": {
+			{Filter: "macstudio", Params: iofl.Params{"App": app, "File": "ReflectionMetadata.xml"}},
+			{Filter: "xmlpath", Params: iofl.Params{"Path": "roblox/Item[@class=ReflectionMetadataClasses]"}},
+		},
+		"ClassImages": {
+			{Filter: "macstudio", Params: iofl.Params{"App": app, "File": "content/textures/ClassImages.PNG"}},
+		},
+	}
+}
+
+// preferChains defines each of the given chains within config, named
+// "<method><suffix>", and prepends it to the method, unless it already exists.
+func preferChains(config Config, suffix string, chains map[string]iofl.Chain) {
+	for method, chain := range chains {
+		name := method + suffix
+		if _, ok := config.Chains[name]; !ok {
+			config.Chains[name] = chain
+		}
+		methods := config.Methods[method]
+		if !containsString(methods, name) {
+			config.Methods[method] = append([]string{name}, methods...)
+		}
+	}
+}
+
+// PresetMacStudio returns the default configuration, with the reflection
+// metadata and class images of a build fetched from the RobloxStudio.app
+// bundle at app, if the bundle is the requested build, falling back to the
+// default chains otherwise. The bundle is the requested build if its version
+// is the version listed for the GUID by the Mac deployment history. If app is
+// empty, then MacStudioApp is located when content is fetched.
+//
+// For each method, a chain named "<method>MacStudio" is prepended to the
+// method.
+func PresetMacStudio(app string) Config {
+	config := DefaultConfig()
+	preferChains(config, "MacStudio", macStudioChains(app))
+	return config
+}
+
+// PreferMacStudio configures the client in the same manner as
+// PresetMacStudio, preserving the existing configuration. For each method, a
+// chain named "<method>MacStudio:<app>" is defined and prepended to the
+// method, unless it already exists.
+func (client *Client) PreferMacStudio(app string) error {
+	config := client.Config()
+	preferChains(config, "MacStudio:"+app, macStudioChains(app))
+	return client.SetConfig(config)
+}
+
+// versionOf returns the version of the Mac build with the given GUID, as
+// listed by the "mac" track of the "Builds" method. vars are passed to each
+// chain.
+func (client *Client) versionOf(guid string, vars map[string]string) (v Version, ok bool) {
+	index, err := client.buildIndex("Builds", "mac", vars)
+	if err != nil || index == nil {
+		return v, false
+	}
+	build, ok := index.GUID(guid)
+	if !ok || build.Version.Empty() {
+		return v, false
+	}
+	return build.Version, true
+}
+
+// applyVersionOf applies a function that returns the version of a build to
+// the chain of filters.
+func applyVersionOf(filter iofl.Filter, versionOf func(guid string) (v Version, ok bool)) {
+	type versioner interface {
+		iofl.Filter
+		SetVersionOf(versionOf func(guid string) (v Version, ok bool))
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(versioner); ok {
+			f.SetVersionOf(versionOf)
+		}
+		return nil
+	})
+}
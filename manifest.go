@@ -0,0 +1,154 @@
+package rbxfetch
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ManifestEntry describes a package within a deployment.
+type ManifestEntry struct {
+	// Name is the file name of the package, such as "content-textures2.zip".
+	Name string
+	// Checksum is the MD5 hash of the package, in lowercase hexadecimal.
+	Checksum string
+	// Size is the size of the package.
+	Size int64
+	// UnpackedSize is the size of the content of the package once extracted.
+	UnpackedSize int64
+}
+
+// Manifest lists the packages of a deployment.
+type Manifest []ManifestEntry
+
+// Entry returns the entry of the given name. Names are compared
+// case-insensitively.
+func (m Manifest) Entry(name string) (entry ManifestEntry, ok bool) {
+	for _, e := range m {
+		if strings.EqualFold(e.Name, name) {
+			return e, true
+		}
+	}
+	return entry, false
+}
+
+// ParseManifest parses the content of a package manifest (rbxPkgManifest.txt).
+// The manifest begins with a version line, followed by groups of four lines:
+// the package name, its MD5 checksum, its size, and its unpacked size.
+func ParseManifest(r io.Reader) (m Manifest, err error) {
+	s := bufio.NewScanner(r)
+	var lines []string
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("manifest: empty")
+	}
+	if lines[0] != "v0" {
+		return nil, fmt.Errorf("manifest: unsupported version %q", lines[0])
+	}
+	lines = lines[1:]
+	if len(lines)%4 != 0 {
+		return nil, fmt.Errorf("manifest: incomplete entry")
+	}
+	for i := 0; i < len(lines); i += 4 {
+		entry := ManifestEntry{
+			Name:     lines[i],
+			Checksum: strings.ToLower(lines[i+1]),
+		}
+		if entry.Size, err = strconv.ParseInt(lines[i+2], 10, 64); err != nil {
+			return nil, fmt.Errorf("manifest: %s: size: %w", entry.Name, err)
+		}
+		if entry.UnpackedSize, err = strconv.ParseInt(lines[i+3], 10, 64); err != nil {
+			return nil, fmt.Errorf("manifest: %s: unpacked size: %w", entry.Name, err)
+		}
+		m = append(m, entry)
+	}
+	return m, nil
+}
+
+// Manifest returns the package manifest of the given GUID. Returns nil if no
+// "Manifest" method is configured.
+//
+// The content of a chain is expected to be an rbxPkgManifest.txt file.
+func (client *Client) Manifest(guid string) (m Manifest, err error) {
+	rc, err := client.method("Manifest", guid)
+	if err != nil || rc == nil {
+		return nil, err
+	}
+	defer rc.Close()
+	m, err = ParseManifest(rc)
+	return m, classify(ErrDecode, err)
+}
+
+// ChecksumError is returned when the content of a package does not match the
+// checksum listed by the manifest, or when downloaded content does not match
+// the expected checksum.
+type ChecksumError struct {
+	Name     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("package %s: checksum mismatch: expected %s, got %s", e.Name, e.Expected, e.Actual)
+}
+
+// verifyReader compares the checksum of content read through it with an
+// expected checksum once EOF is reached.
+type verifyReader struct {
+	io.ReadCloser
+	name     string
+	expected string
+	hash     hash.Hash
+	err      error
+}
+
+func (r *verifyReader) Read(p []byte) (n int, err error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err = r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if actual := hex.EncodeToString(r.hash.Sum(nil)); actual != r.expected {
+			r.err = classify(ErrDecode, &ChecksumError{Name: r.name, Expected: r.expected, Actual: actual})
+			return n, r.err
+		}
+	}
+	return n, err
+}
+
+// Package returns the package of the given name, such as
+// "content-textures3.zip", from the deployment of the given GUID. The package
+// is located using the manifest returned by Manifest, and its content is
+// verified against the checksum listed by the manifest. If the checksum does
+// not match, then reading the final portion of the content returns a
+// ChecksumError. Returns nil if no "Package" method is configured.
+//
+// The name of the package is available to chains as the $PACKAGE variable.
+func (client *Client) Package(guid, name string) (rc io.ReadCloser, err error) {
+	m, err := client.Manifest(guid)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := m.Entry(name)
+	if !ok {
+		return nil, fmt.Errorf("package %q not in manifest", name)
+	}
+	rc, err = client.methodVars("Package", guid, map[string]string{"PACKAGE": entry.Name})
+	if err != nil || rc == nil {
+		return nil, err
+	}
+	return &verifyReader{ReadCloser: rc, name: entry.Name, expected: entry.Checksum, hash: md5.New()}, nil
+}
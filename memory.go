@@ -0,0 +1,166 @@
+package rbxfetch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/anaminus/iofl"
+)
+
+// ErrMemoryBudget is returned when content cannot be buffered within a strict
+// MemoryBudget.
+var ErrMemoryBudget = errors.New("memory budget exceeded")
+
+// bufferChunkSize is the size of each read made while buffering content.
+const bufferChunkSize = 32 << 10
+
+// MemoryBudget limits the amount of memory used by filters to buffer content,
+// such as a zip filter reading from a source that does not support random
+// access. A single MemoryBudget is shared by every fetch of a client, and may
+// be shared between clients.
+//
+// When buffering content would exceed Limit, the content is instead spilled to
+// a temporary file. If Strict is true, then the content is not spilled, and
+// the filter fails with an error wrapping ErrMemoryBudget. Content that must
+// reside in memory, such as the candidate images of an icon scan, always
+// fails in this way.
+type MemoryBudget struct {
+	// Limit is the maximum number of bytes buffered at once. If zero or
+	// less, then the amount is unlimited.
+	Limit int64
+	// Strict specifies whether exceeding the limit fails rather than
+	// spilling content to disk.
+	Strict bool
+
+	mu   sync.Mutex
+	used int64
+}
+
+// Used returns the number of bytes currently buffered.
+func (m *MemoryBudget) Used() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used
+}
+
+// reserve reserves n bytes of the budget, returning an error if doing so would
+// exceed the limit. A nil budget is unlimited.
+func (m *MemoryBudget) reserve(n int64) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Limit > 0 && m.used+n > m.Limit {
+		return fmt.Errorf("%w: buffering %d more bytes with %d of %d bytes in use", ErrMemoryBudget, n, m.used, m.Limit)
+	}
+	m.used += n
+	return nil
+}
+
+// release returns n reserved bytes to the budget.
+func (m *MemoryBudget) release(n int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.used -= n
+}
+
+// memReader is content buffered in memory, which is released from the budget
+// when closed.
+type memReader struct {
+	*bytes.Reader
+	m *MemoryBudget
+	n int64
+}
+
+func (r *memReader) Close() error {
+	r.m.release(r.n)
+	r.n = 0
+	return nil
+}
+
+// buffer reads r entirely, returning the content as a readAtSeekCloser. The
+// content is held in memory while within the budget, and spilled to a
+// temporary file otherwise.
+func (m *MemoryBudget) buffer(r io.Reader) (rc readAtSeekCloser, err error) {
+	if m == nil {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return nopCloser{bytes.NewReader(b)}, nil
+	}
+	var b []byte
+	chunk := make([]byte, bufferChunkSize)
+	for {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			if e := m.reserve(int64(n)); e != nil {
+				m.release(int64(len(b)))
+				if m.Strict {
+					return nil, e
+				}
+				return spill(b, chunk[:n], r)
+			}
+			b = append(b, chunk[:n]...)
+		}
+		if err == io.EOF {
+			return &memReader{Reader: bytes.NewReader(b), m: m, n: int64(len(b))}, nil
+		}
+		if err != nil {
+			m.release(int64(len(b)))
+			return nil, err
+		}
+	}
+}
+
+// spill writes each of the given prefixes, followed by the remainder of r, to
+// a temporary file, which is removed when closed.
+func spill(b, chunk []byte, r io.Reader) (rc readAtSeekCloser, err error) {
+	file, err := createTemp("")
+	if err != nil {
+		return nil, err
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+	if _, err = file.Write(b); err != nil {
+		return nil, err
+	}
+	if _, err = file.Write(chunk); err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(file, r); err != nil {
+		return nil, err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	keep = true
+	return tempReadCloser{file}, nil
+}
+
+// applyMemory applies a memory budget to the chain of filters.
+func applyMemory(filter iofl.Filter, m *MemoryBudget) {
+	type budgeter interface {
+		iofl.Filter
+		SetMemory(m *MemoryBudget)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(budgeter); ok {
+			f.SetMemory(m)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,157 @@
+package rbxfetch
+
+import (
+	"expvar"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/anaminus/iofl"
+)
+
+// Metrics receives measurements of the fetches performed by a client, such as
+// to be exported to a monitoring system. Each measurement is attributed to a
+// method and one of its chains. Methods may be called concurrently.
+type Metrics interface {
+	// AddRequests is called with the number of HTTP requests made by a
+	// fetch, including retries and requests to mirrors.
+	AddRequests(method, chain string, n int)
+	// AddBytes is called with the number of bytes downloaded by a fetch.
+	AddBytes(method, chain string, n int64)
+	// AddCacheHit is called when a fetch is served from the cache.
+	AddCacheHit(method, chain string)
+	// AddCacheMiss is called when a fetch is not found in the cache.
+	AddCacheMiss(method, chain string)
+	// AddError is called when a fetch fails.
+	AddError(method, chain string)
+	// ObserveLatency is called with the total duration of a fetch.
+	ObserveLatency(method, chain string, d time.Duration)
+}
+
+// NopMetrics is a Metrics that discards all measurements. A client with a nil
+// Metrics behaves as if it had NopMetrics.
+type NopMetrics struct{}
+
+func (NopMetrics) AddRequests(method, chain string, n int)              {}
+func (NopMetrics) AddBytes(method, chain string, n int64)               {}
+func (NopMetrics) AddCacheHit(method, chain string)                     {}
+func (NopMetrics) AddCacheMiss(method, chain string)                    {}
+func (NopMetrics) AddError(method, chain string)                        {}
+func (NopMetrics) ObserveLatency(method, chain string, d time.Duration) {}
+
+// networkCounter is implemented by filters that make network requests.
+type networkCounter interface {
+	iofl.Filter
+	// NetworkStats returns the number of requests made, and the number of
+	// bytes read from responses.
+	NetworkStats() (requests int, bytes int64)
+}
+
+// cacheReporter is implemented by filters that cache content.
+type cacheReporter interface {
+	iofl.Filter
+	// CacheHit returns whether the content was retrieved from the cache. ok
+	// is false if the cache was not consulted.
+	CacheHit() (hit, ok bool)
+}
+
+// recordMetrics reports the measurements of f, a fetch by chain of method
+// that took d and failed with err, to the client's Metrics.
+func (client *Client) recordMetrics(method, chain string, f iofl.Filter, d time.Duration, err error) {
+	m := client.Metrics
+	if m == nil {
+		return
+	}
+	iofl.Apply(f, func(f io.ReadCloser) error {
+		if f, ok := f.(networkCounter); ok {
+			requests, bytes := f.NetworkStats()
+			if requests > 0 {
+				m.AddRequests(method, chain, requests)
+			}
+			if bytes > 0 {
+				m.AddBytes(method, chain, bytes)
+			}
+		}
+		if f, ok := f.(cacheReporter); ok {
+			if hit, ok := f.CacheHit(); ok {
+				if hit {
+					m.AddCacheHit(method, chain)
+				} else {
+					m.AddCacheMiss(method, chain)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		m.AddError(method, chain)
+	}
+	m.ObserveLatency(method, chain, d)
+}
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets of
+// ExpvarMetrics.
+var LatencyBuckets = []time.Duration{
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	60 * time.Second,
+}
+
+// ExpvarMetrics is a Metrics that publishes measurements as an expvar.Map.
+// Each counter is keyed by "<name>:<method>/<chain>", where name is one of
+// "requests", "bytes", "cache_hits", "cache_misses", "errors", or
+// "fetches". Latencies are recorded as a cumulative histogram keyed by
+// "latency:<method>/<chain>:le=<bound>", with a bound of "+Inf" counting
+// every fetch, along with a "latency_sum" counter of total milliseconds.
+type ExpvarMetrics struct {
+	Map *expvar.Map
+}
+
+// NewExpvarMetrics returns an ExpvarMetrics with a map published under the
+// given name. Panics if the name is already published, as with expvar.NewMap.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	return &ExpvarMetrics{Map: expvar.NewMap(name)}
+}
+
+func metricKey(name, method, chain string) string {
+	return name + ":" + method + "/" + chain
+}
+
+func (m *ExpvarMetrics) AddRequests(method, chain string, n int) {
+	m.Map.Add(metricKey("requests", method, chain), int64(n))
+}
+
+func (m *ExpvarMetrics) AddBytes(method, chain string, n int64) {
+	m.Map.Add(metricKey("bytes", method, chain), n)
+}
+
+func (m *ExpvarMetrics) AddCacheHit(method, chain string) {
+	m.Map.Add(metricKey("cache_hits", method, chain), 1)
+}
+
+func (m *ExpvarMetrics) AddCacheMiss(method, chain string) {
+	m.Map.Add(metricKey("cache_misses", method, chain), 1)
+}
+
+func (m *ExpvarMetrics) AddError(method, chain string) {
+	m.Map.Add(metricKey("errors", method, chain), 1)
+}
+
+func (m *ExpvarMetrics) ObserveLatency(method, chain string, d time.Duration) {
+	m.Map.Add(metricKey("fetches", method, chain), 1)
+	m.Map.Add(metricKey("latency_sum", method, chain), d.Milliseconds())
+	key := metricKey("latency", method, chain) + ":le="
+	for _, bound := range LatencyBuckets {
+		if d <= bound {
+			m.Map.Add(key+strconv.FormatInt(bound.Milliseconds(), 10)+"ms", 1)
+		}
+	}
+	m.Map.Add(key+"+Inf", 1)
+}
@@ -0,0 +1,51 @@
+package rbxfetch
+
+import (
+	"io"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// DefaultMirrors lists known mirrors of the deployment CDN.
+var DefaultMirrors = []string{
+	"https://setup.rbxcdn.com/",
+	"https://setup-aws.rbxcdn.com/",
+	"https://setup-ak.rbxcdn.com/",
+	"https://roblox-setup.cachefly.net/",
+	"https://s3.amazonaws.com/setup.roblox.com/",
+}
+
+// mirrorCandidates returns the URLs to attempt for u. If u begins with one of
+// the mirror base URLs, then the URL is followed by the same path under each
+// other mirror, in order. Otherwise, only u is returned.
+func mirrorCandidates(u string, mirrors []string) []string {
+	candidates := []string{u}
+	for i, base := range mirrors {
+		if !strings.HasPrefix(u, base) {
+			continue
+		}
+		path := strings.TrimPrefix(u, base)
+		for j, other := range mirrors {
+			if j != i {
+				candidates = append(candidates, other+path)
+			}
+		}
+		break
+	}
+	return candidates
+}
+
+// applyMirrors applies mirrors to the chain of filters.
+func applyMirrors(filter iofl.Filter, mirrors []string) {
+	type mirrorer interface {
+		iofl.Filter
+		SetMirrors(mirrors []string)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(mirrorer); ok {
+			f.SetMirrors(mirrors)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// ResolvedChain describes a chain of a method with its parameters resolved.
+type ResolvedChain struct {
+	// Chain is the name of the chain.
+	Chain string
+	// Links contains each link of the chain, in order.
+	Links iofl.Chain
+}
+
+// expandParam expands the variables within a parameter value. Strings, and
+// strings within lists and maps, are expanded. Other values are returned
+// unchanged.
+func expandParam(v interface{}, guid string, vars map[string]string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return expandVars(v, guid, vars)
+	case []string:
+		s := make([]string, len(v))
+		for i, v := range v {
+			s[i] = expandVars(v, guid, vars)
+		}
+		return s
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, v := range v {
+			s[i] = expandParam(v, guid, vars)
+		}
+		return s
+	case map[string]string:
+		m := make(map[string]string, len(v))
+		for k, v := range v {
+			m[k] = expandVars(v, guid, vars)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, v := range v {
+			m[k] = expandParam(v, guid, vars)
+		}
+		return m
+	case iofl.Params:
+		return iofl.Params(expandParam(map[string]interface{}(v), guid, vars).(map[string]interface{}))
+	}
+	return v
+}
+
+// ResolveParams returns the chains of the given method, in the order they
+// would be attempted, with the variables of each parameter expanded for guid.
+// vars contains extra variables that take precedence over the client's
+// variables, such as $PACKAGE. The method is not executed, allowing the
+// requests that it would make to be audited or mirrored.
+func (client *Client) ResolveParams(method, guid string, vars map[string]string) (chains []ResolvedChain, err error) {
+	snapshot := client.snapshot()
+	if _, ok := snapshot.methods[method]; !ok {
+		return nil, fmt.Errorf("unknown method %q", method)
+	}
+	all := client.vars()
+	for k, v := range vars {
+		all[strings.ToUpper(k)] = v
+	}
+	config := snapshot.chainSet.Config()
+	for _, name := range client.chains(method) {
+		chain, ok := config.Chains[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown chain %q", name)
+		}
+		resolved := ResolvedChain{Chain: name, Links: make(iofl.Chain, len(chain))}
+		for i, link := range chain {
+			resolved.Links[i] = iofl.LinkDef{
+				Filter: link.Filter,
+				Params: expandParam(link.Params, guid, all).(iofl.Params),
+			}
+		}
+		chains = append(chains, resolved)
+	}
+	return chains, nil
+}
@@ -0,0 +1,122 @@
+package rbxfetch
+
+import (
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// partialSuffix is appended to the path of a cache file to produce the path
+// of the partially downloaded content of the file. The offset and validator of
+// the partial content are recorded in a file with the additional metaSuffix.
+const (
+	partialSuffix = ".partial"
+	metaSuffix    = ".meta"
+)
+
+// offsetter is implemented by sources that can begin reading from an offset,
+// such as FilterURL.
+type offsetter interface {
+	io.Reader
+	SetOffset(offset int64)
+}
+
+// validatedOffsetter is implemented by sources that can begin reading from an
+// offset only if the content preceding the offset has not changed, such as
+// FilterURL.
+type validatedOffsetter interface {
+	offsetter
+	SetValidator(validator string, restart func() error)
+	Validator() string
+}
+
+// resumePartial returns a temporary file within dir to which the content of
+// path is written. If the source supports offsets, and partial content of path
+// remains from an interrupted download, then the partial content is claimed
+// and returned, the source is set to continue from the end of the content,
+// and the content is written to h. Should the content of the source have
+// changed since, then the file and h are reset before the source is read from
+// the beginning.
+func (f *FilterCache) resumePartial(dir, path string, h hash.Hash) (file *os.File, err error) {
+	src, ok := f.r.(validatedOffsetter)
+	if !ok {
+		return createTemp(dir)
+	}
+	partial := path + partialSuffix
+	b, err := os.ReadFile(partial + metaSuffix)
+	if err != nil {
+		return createTemp(dir)
+	}
+	meta := strings.SplitN(strings.TrimSpace(string(b)), "\n", 2)
+	offset, err := strconv.ParseInt(meta[0], 10, 64)
+	if err != nil || offset <= 0 || len(meta) < 2 || meta[1] == "" {
+		return createTemp(dir)
+	}
+	// Claim the partial content by moving it to a unique temp file, so that
+	// concurrent fetches do not resume the same content.
+	temp, err := createTemp(dir)
+	if err != nil {
+		return nil, err
+	}
+	temp.Close()
+	if err := os.Rename(partial, temp.Name()); err != nil {
+		os.Remove(temp.Name())
+		return createTemp(dir)
+	}
+	os.Remove(partial + metaSuffix)
+	if file, err = os.OpenFile(temp.Name(), os.O_RDWR, 0644); err != nil {
+		os.Remove(temp.Name())
+		return createTemp(dir)
+	}
+	n, err := io.CopyN(h, file, offset)
+	if err == nil && n == offset {
+		err = file.Truncate(offset)
+	}
+	if err != nil {
+		// The partial content is not as recorded; start over.
+		h.Reset()
+		file.Close()
+		os.Remove(temp.Name())
+		return createTemp(dir)
+	}
+	src.SetOffset(offset)
+	src.SetValidator(meta[1], func() error {
+		h.Reset()
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return file.Truncate(0)
+	})
+	return file, nil
+}
+
+// keepPartial retains the content of file, which is being written for path,
+// after the download has been interrupted, so that it can be resumed later.
+// Content without a validator is not retained. Returns whether the content was
+// retained.
+func (f *FilterCache) keepPartial(file *os.File, path string) bool {
+	src, ok := f.r.(validatedOffsetter)
+	if !ok || src.Validator() == "" {
+		return false
+	}
+	if err := file.Sync(); err != nil {
+		return false
+	}
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil || offset <= 0 {
+		return false
+	}
+	file.Close()
+	partial := path + partialSuffix
+	if err := os.Rename(file.Name(), partial); err != nil {
+		os.Remove(file.Name())
+		return true
+	}
+	meta := strconv.FormatInt(offset, 10) + "\n" + src.Validator() + "\n"
+	if err := saveFile(partial+metaSuffix, strings.NewReader(meta)); err != nil {
+		os.Remove(partial)
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package rbxfetch_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anaminus/iofl"
+	"github.com/robloxapi/rbxfetch"
+	"github.com/robloxapi/rbxfetch/rbxfetchtest"
+)
+
+func TestCachePartial(t *testing.T) {
+	const name = "setup.rbxcdn.com/" + rbxfetchtest.GUID + "-API-Dump.json"
+	const key = "API-Dump.json"
+	changed := strings.Replace(rbxfetchtest.APIDump, "Instance", "Changed!", 1)
+	tests := []struct {
+		name    string
+		change  bool
+		content string
+	}{
+		{name: "unchanged", content: rbxfetchtest.APIDump},
+		{name: "changed", change: true, content: changed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := rbxfetchtest.NewServer()
+			defer s.Close()
+			dir := t.TempDir()
+			// fetch downloads the file to the cache, returning the Range
+			// header of the last request.
+			fetch := func() (content []byte, ranged string, err error) {
+				src := &rbxfetch.FilterURL{
+					URL:    s.URL + "/" + name,
+					Client: s.Client(),
+					Hooks: rbxfetch.RequestHooks{
+						BeforeRequest: func(req *http.Request) error {
+							ranged = req.Header.Get("Range")
+							return nil
+						},
+					},
+				}
+				f, err := rbxfetch.NewFilterCache(iofl.Params{"Key": key}, src)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.(*rbxfetch.FilterCache).SetCache(rbxfetch.CacheCustom, dir)
+				defer f.Close()
+				content, err = io.ReadAll(f)
+				return content, ranged, err
+			}
+
+			s.Interrupt(name, 100)
+			if _, _, err := fetch(); err == nil {
+				t.Fatal("expected interrupted download to fail")
+			}
+			info, err := os.Stat(filepath.Join(dir, key+".partial"))
+			if err != nil {
+				t.Fatalf("partial content not retained: %v", err)
+			}
+			if info.Size() != 100 {
+				t.Errorf("expected 100 bytes of partial content, got %d", info.Size())
+			}
+
+			if tt.change {
+				s.Set(name, []byte(changed))
+			}
+			content, ranged, err := fetch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ranged != "bytes=100-" {
+				t.Errorf("expected download to resume from offset 100, got range %q", ranged)
+			}
+			if string(content) != tt.content {
+				t.Errorf("unexpected content: got %d bytes", len(content))
+			}
+			cached, err := os.ReadFile(filepath.Join(dir, key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(cached) != tt.content {
+				t.Errorf("unexpected cached content: got %d bytes", len(cached))
+			}
+			for _, suffix := range []string{".partial", ".partial.meta"} {
+				if _, err := os.Stat(filepath.Join(dir, key+suffix)); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be removed", key+suffix)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Build types that appear in the deployment history.
+const (
+	BuildStudio        = "Studio"
+	BuildStudio64      = "Studio64"
+	BuildWindowsPlayer = "WindowsPlayer"
+	BuildClient        = "Client"
+	BuildRCCService    = "RccService"
+)
+
+// LatestPlayer returns the GUID of the latest player build. LatestPlayer uses
+// the result of the first chain that does not error. Returns an empty string
+// if no "LatestPlayer" method is configured.
+//
+// The content of a chain is expected to be a raw GUID.
+func (client *Client) LatestPlayer() (guid string, err error) {
+	return client.latest("LatestPlayer", nil)
+}
+
+// LivePlayer returns the GUIDs of the current live player builds, in the same
+// manner as Live. Returns an empty slice if no "LivePlayer" method is
+// configured.
+func (client *Client) LivePlayer() (guids []string, err error) {
+	return client.live("LivePlayer", nil)
+}
+
+// LatestBuild returns the most recent build of the given type, such as
+// BuildRCCService, as listed by Builds. Types are compared
+// case-insensitively.
+func (client *Client) LatestBuild(buildType string) (build Build, err error) {
+	builds, err := client.Builds()
+	if err != nil {
+		return build, err
+	}
+	for i := len(builds) - 1; i >= 0; i-- {
+		if strings.EqualFold(builds[i].Type, buildType) {
+			return builds[i], nil
+		}
+	}
+	return build, fmt.Errorf("no builds of type %q", buildType)
+}
+
+// LatestRCC returns the GUID of the most recent RCC service build listed by
+// Builds.
+func (client *Client) LatestRCC() (guid string, err error) {
+	build, err := client.LatestBuild(BuildRCCService)
+	return build.GUID, err
+}
+
+// Player returns the player application package of the given GUID. Returns
+// nil if no "Player" method is configured.
+func (client *Client) Player(guid string) (rc io.ReadCloser, err error) {
+	return client.method("Player", guid)
+}
+
+// RCCService returns the RCC service package of the given GUID. Returns nil if
+// no "RCCService" method is configured.
+func (client *Client) RCCService(guid string) (rc io.ReadCloser, err error) {
+	return client.method("RCCService", guid)
+}
@@ -0,0 +1,260 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// ArchiveMirrorHost is the base URL of a mirror of the deployment CDN that
+// retains builds which have expired from setup.rbxcdn.com.
+const ArchiveMirrorHost = "https://s3.amazonaws.com/setup.roblox.com/"
+
+// Base URLs of hosts used by the default chains.
+const (
+	setupHost                = "https://setup.rbxcdn.com/"
+	clientSettingsHost       = "https://clientsettings.roblox.com/"
+	versionCompatibilityHost = "https://versioncompatibility.api.roblox.com/"
+)
+
+// TestSiteDomain is the domain under which the test environments of Roblox,
+// such as sitetest1, are hosted.
+const TestSiteDomain = "robloxlabs.com"
+
+// Base URLs of the Luobu (Roblox China) deployment infrastructure.
+const (
+	LuobuSetupHost          = "https://setup.rbxcdn.qq.com/"
+	LuobuClientSettingsHost = "https://clientsettings.roblox.qq.com/"
+)
+
+// ClientTrackerHost is the base URL of the content of the Roblox-Client-Tracker
+// repository, which tracks the content of the latest build of each of several
+// deployment channels within separate branches.
+const ClientTrackerHost = "https://raw.githubusercontent.com/MaximumADHD/Roblox-Client-Tracker/"
+
+// PresetDefault returns the configuration used by NewClient. It is equivalent
+// to DefaultConfig.
+func PresetDefault() Config {
+	return DefaultConfig()
+}
+
+// PresetArchiveMirror returns the default configuration, with deployment
+// artifacts retrieved from ArchiveMirrorHost instead of setup.rbxcdn.com.
+func PresetArchiveMirror() Config {
+	config := DefaultConfig()
+	rehost(config, setupHost, ArchiveMirrorHost)
+	return config
+}
+
+// PresetOffline returns the default configuration, modified so that content is
+// retrieved only from the cache. Chains that are not cached, such as those
+// that fetch the latest builds, are removed, along with methods that no longer
+// have any chains.
+func PresetOffline() Config {
+	config := DefaultConfig()
+	for name, chain := range config.Chains {
+		i := len(chain) - 1
+		for ; i >= 0; i-- {
+			if chain[i].Filter == "cache" {
+				break
+			}
+		}
+		if i < 0 {
+			delete(config.Chains, name)
+			continue
+		}
+		config.Chains[name] = chain[i:]
+	}
+	for name, method := range config.Methods {
+		chains := method[:0]
+		for _, chain := range method {
+			if _, ok := config.Chains[chain]; ok {
+				chains = append(chains, chain)
+			}
+		}
+		if len(chains) == 0 {
+			delete(config.Methods, name)
+			continue
+		}
+		config.Methods[name] = chains
+	}
+	return config
+}
+
+// PresetChannel returns the default configuration, targeting the given
+// deployment channel, such as "zcanary". The channel is specified by the Vars
+// of the configuration, and so takes precedence over the Channel of the
+// client.
+func PresetChannel(name string) Config {
+	config := DefaultConfig()
+	config.Vars = map[string]string{}
+	channelVars(name, config.Vars)
+	return config
+}
+
+// PresetLuobu returns the default configuration, with requests directed to the
+// Luobu deployment infrastructure instead of the global infrastructure.
+func PresetLuobu() Config {
+	config := DefaultConfig()
+	rehost(config, setupHost, LuobuSetupHost)
+	rehost(config, clientSettingsHost, LuobuClientSettingsHost)
+	return config
+}
+
+// PresetClientTracker returns a configuration that retrieves content from the
+// given branch of the Roblox-Client-Tracker repository, such as "roblox" or
+// "rbxcdn-preview". If branch is empty, then "roblox" is used. The branch is
+// specified by the TRACKERBRANCH variable.
+//
+// The repository contains only the content of the latest build of a branch,
+// so the GUID passed to a method is ignored. The configuration includes the
+// following methods:
+//
+//   - Latest: The GUID of the latest build of the branch.
+//   - APIDump: The API dump.
+//   - FullAPIDump: The API dump, including default property values.
+//   - ReflectionMetadata: The reflection metadata.
+//   - FVariables: A list of the fast variables defined by the build.
+func PresetClientTracker(branch string) Config {
+	if branch == "" {
+		branch = "roblox"
+	}
+	url := func(file string) iofl.Chain {
+		return iofl.Chain{{Filter: "url", Params: iofl.Params{"URL": ClientTrackerHost + "${TRACKERBRANCH}/" + file}}}
+	}
+	return Config{
+		Methods: map[string][]string{
+			"Latest":             {"TrackerLatest"},
+			"APIDump":            {"TrackerAPIDump"},
+			"FullAPIDump":        {"TrackerFullAPIDump"},
+			"ReflectionMetadata": {"TrackerReflectionMetadata"},
+			"FVariables":         {"TrackerFVariables"},
+		},
+		Vars: map[string]string{"TRACKERBRANCH": branch},
+		Config: iofl.Config{Chains: map[string]iofl.Chain{
+			"TrackerLatest":             url("version-guid.txt"),
+			"TrackerAPIDump":            url("API-Dump.json"),
+			"TrackerFullAPIDump":        url("Full-API-Dump.json"),
+			"TrackerReflectionMetadata": url("ReflectionMetadata.xml"),
+			"TrackerFVariables":         url("FVariables.txt"),
+		}},
+	}
+}
+
+// PresetTestSite returns the default configuration, with requests directed to
+// the given test environment, such as "sitetest1" or "gametest2". Deployment
+// artifacts are retrieved from setup.<site>.robloxlabs.com, and live versions
+// and settings from the clientsettings and versioncompatibility hosts of the
+// site.
+func PresetTestSite(site string) Config {
+	site = strings.ToLower(site)
+	config := DefaultConfig()
+	rehost(config, setupHost, "https://setup."+site+"."+TestSiteDomain+"/")
+	rehost(config, clientSettingsHost, "https://clientsettings."+site+"."+TestSiteDomain+"/")
+	rehost(config, versionCompatibilityHost, "https://versioncompatibility.api."+site+"."+TestSiteDomain+"/")
+	return config
+}
+
+// presets maps the name of each preset returned by Presets to the function
+// that returns its configuration.
+var presets = map[string]func() Config{
+	"default":       PresetDefault,
+	"archive":       PresetArchiveMirror,
+	"offline":       PresetOffline,
+	"luobu":         PresetLuobu,
+	"clienttracker": func() Config { return PresetClientTracker("") },
+	"canary":        func() Config { return PresetChannel("zcanary") },
+	"macstudio":     func() Config { return PresetMacStudio("") },
+}
+
+// Presets returns the configuration of each named preset, allowing an
+// application to offer a choice of sources. The following presets are
+// included:
+//
+//   - "default": PresetDefault
+//   - "archive": PresetArchiveMirror
+//   - "offline": PresetOffline
+//   - "luobu": PresetLuobu
+//   - "clienttracker": PresetClientTracker, using the default branch
+//   - "canary": PresetChannel with the "zcanary" channel
+//   - "macstudio": PresetMacStudio, using the installed bundle
+//
+// Each call returns new copies of the configurations.
+func Presets() map[string]Config {
+	m := make(map[string]Config, len(presets))
+	for name, preset := range presets {
+		m[name] = preset()
+	}
+	return m
+}
+
+// NewClientWithPreset returns a client in the same manner as NewClient, configured
+// with the preset of the given name. Each name returned by Presets is
+// recognized, along with the following:
+//
+//   - "tracker": Equivalent to "clienttracker".
+//   - "sitetest1", "sitetest2", "sitetest3", "gametest1", etc.:
+//     PresetTestSite with the given site
+//
+// Names are case-insensitive. Returns an error if the name is not recognized.
+func NewClientWithPreset(name string) (*Client, error) {
+	var config Config
+	name = strings.ToLower(name)
+	if name == "tracker" {
+		name = "clienttracker"
+	}
+	if preset, ok := presets[name]; ok {
+		config = preset()
+	} else if isTestSite(name) {
+		config = PresetTestSite(name)
+	} else {
+		return nil, fmt.Errorf("unknown preset %q", name)
+	}
+	client := NewClient()
+	if err := client.SetConfig(config); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// isTestSite returns whether name is the name of a test environment, such as
+// "sitetest1".
+func isTestSite(name string) bool {
+	name = strings.ToLower(name)
+	for _, prefix := range []string{"sitetest", "gametest"} {
+		if n := strings.TrimPrefix(name, prefix); n != name && n != "" && strings.Trim(n, "0123456789") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// rehost replaces the prefix from with to in the URL and Variants parameters
+// of each link within config.
+func rehost(config Config, from, to string) {
+	for _, chain := range config.Chains {
+		for i, link := range chain {
+			u := link.Params.GetString("URL")
+			if !strings.HasPrefix(u, from) {
+				continue
+			}
+			params := make(iofl.Params, len(link.Params))
+			for k, v := range link.Params {
+				params[k] = v
+			}
+			params["URL"] = to + strings.TrimPrefix(u, from)
+			if variants := getStrings(link.Params, "Variants"); variants != nil {
+				rehosted := make([]string, len(variants))
+				for j, v := range variants {
+					if strings.HasPrefix(v, from) {
+						v = to + strings.TrimPrefix(v, from)
+					}
+					rehosted[j] = v
+				}
+				params["Variants"] = rehosted
+			}
+			chain[i].Params = params
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ProxyDirect is a value of Client.HostProxies indicating that requests to a
+// host are made without a proxy.
+const ProxyDirect = "direct"
+
+// proxyKey returns a string that identifies the proxy configuration of the
+// client.
+func (client *Client) proxyKey() string {
+	hosts := make([]string, 0, len(client.HostProxies))
+	for host, proxy := range client.HostProxies {
+		hosts = append(hosts, strings.ToLower(host)+"="+proxy)
+	}
+	sort.Strings(hosts)
+	return client.Proxy + "|" + strings.Join(hosts, ",")
+}
+
+// proxyFunc returns a function that selects the proxy of a request according
+// to Proxy and HostProxies. Invalid proxy URLs cause requests that would use
+// them to fail.
+func (client *Client) proxyFunc() func(req *http.Request) (*url.URL, error) {
+	parse := func(s string) (*url.URL, error) {
+		if s == "" || strings.EqualFold(s, ProxyDirect) {
+			return nil, nil
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+		return u, nil
+	}
+	type proxy struct {
+		u   *url.URL
+		err error
+	}
+	hosts := make(map[string]proxy, len(client.HostProxies))
+	for host, p := range client.HostProxies {
+		u, err := parse(p)
+		hosts[strings.ToLower(host)] = proxy{u, err}
+	}
+	env := client.Proxy == ""
+	def, defErr := parse(client.Proxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if p, ok := hosts[strings.ToLower(req.URL.Hostname())]; ok {
+			return p.u, p.err
+		}
+		if env {
+			return http.ProxyFromEnvironment(req)
+		}
+		return def, defErr
+	}
+}
@@ -0,0 +1,130 @@
+package rbxfetch
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/anaminus/iofl"
+)
+
+// Priority values of requests made by a Client. A Priority is specified by
+// the PRIORITY variable.
+const (
+	// PriorityInteractive is the priority of requests that are waited on by a
+	// user. It is the default priority.
+	PriorityInteractive = "interactive"
+	// PriorityBackground is the priority of batch requests, such as those
+	// that prefetch or archive builds.
+	PriorityBackground = "background"
+)
+
+// fetchQueue limits the number of concurrent requests. Waiting interactive
+// requests are admitted before waiting background requests.
+type fetchQueue struct {
+	mu          sync.Mutex
+	active      int
+	interactive []chan struct{}
+	background  []chan struct{}
+}
+
+// acquire waits until fewer than limit requests are active, then admits a
+// request of the given priority. Returns a function that releases the
+// request. If limit is less than 1, then the request is admitted immediately.
+// If ctx is done before the request is admitted, then the request leaves the
+// queue, and the error of ctx is returned.
+func (q *fetchQueue) acquire(ctx context.Context, limit int, priority string) (release func(), err error) {
+	if limit < 1 {
+		return func() {}, nil
+	}
+	q.mu.Lock()
+	if q.active < limit {
+		q.active++
+		q.mu.Unlock()
+		return q.releaser(), nil
+	}
+	c := make(chan struct{})
+	if strings.EqualFold(priority, PriorityBackground) {
+		q.background = append(q.background, c)
+	} else {
+		q.interactive = append(q.interactive, c)
+	}
+	q.mu.Unlock()
+	// The slot of the releasing request is handed over directly.
+	select {
+	case <-c:
+		return q.releaser(), nil
+	case <-ctx.Done():
+	}
+	q.mu.Lock()
+	waiting := q.remove(c)
+	q.mu.Unlock()
+	if !waiting {
+		// The slot was handed over while leaving, so it is passed on.
+		q.release()
+	}
+	return nil, ctx.Err()
+}
+
+// remove removes the waiting request c. Returns false if c is not waiting.
+func (q *fetchQueue) remove(c chan struct{}) bool {
+	for _, list := range []*[]chan struct{}{&q.interactive, &q.background} {
+		for i, w := range *list {
+			if w == c {
+				*list = append((*list)[:i], (*list)[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// releaser returns a function that releases an admitted request once.
+func (q *fetchQueue) releaser() func() {
+	var once sync.Once
+	return func() { once.Do(q.release) }
+}
+
+func (q *fetchQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var c chan struct{}
+	switch {
+	case len(q.interactive) > 0:
+		c, q.interactive = q.interactive[0], q.interactive[1:]
+	case len(q.background) > 0:
+		c, q.background = q.background[0], q.background[1:]
+	default:
+		q.active--
+		return
+	}
+	close(c)
+}
+
+// applyQueue applies a function that admits requests to the chain of filters.
+func applyQueue(filter iofl.Filter, acquire func(ctx context.Context) (release func(), err error)) {
+	type queuer interface {
+		iofl.Filter
+		SetQueue(acquire func(ctx context.Context) (release func(), err error))
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(queuer); ok {
+			f.SetQueue(acquire)
+		}
+		return nil
+	})
+}
+
+// WithPriority returns a Scope that makes requests with the given priority,
+// such as PriorityBackground. The priority has an effect only when the
+// client's MaxFetches is set.
+func (client *Client) WithPriority(priority string) *Scope {
+	return client.WithVars(map[string]string{"PRIORITY": priority})
+}
+
+// WithPriority returns a copy of the Scope that makes requests with the given
+// priority.
+func (s *Scope) WithPriority(priority string) *Scope {
+	return s.WithVars(map[string]string{"PRIORITY": priority})
+}
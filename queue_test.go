@@ -0,0 +1,121 @@
+package rbxfetch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waiting returns the number of requests waiting in q.
+func (q *fetchQueue) waiting() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.interactive) + len(q.background)
+}
+
+// waitFor waits until n requests are waiting in q.
+func waitFor(t *testing.T, q *fetchQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for q.waiting() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiting requests, got %d", n, q.waiting())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueuePriority(t *testing.T) {
+	var q fetchQueue
+	release, err := q.acquire(context.Background(), 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admitted := make(chan string, 3)
+	for i, priority := range []string{PriorityBackground, PriorityInteractive, PriorityBackground} {
+		go func(priority string) {
+			release, err := q.acquire(context.Background(), 1, priority)
+			if err != nil {
+				admitted <- err.Error()
+				return
+			}
+			admitted <- priority
+			release()
+		}(priority)
+		// Requests are enqueued in order.
+		waitFor(t, &q, i+1)
+	}
+	release()
+	var order []string
+	for range []int{0, 1, 2} {
+		order = append(order, <-admitted)
+	}
+	expected := []string{PriorityInteractive, PriorityBackground, PriorityBackground}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected order %q, got %q", expected, order)
+		}
+	}
+}
+
+func TestQueueCancel(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		held  int
+		err   error
+	}{
+		{name: "unlimited", limit: 0, held: 1},
+		{name: "available", limit: 2, held: 1},
+		{name: "cancelled", limit: 1, held: 1, err: context.Canceled},
+		{name: "deadline", limit: 2, held: 2, err: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var q fetchQueue
+			var releases []func()
+			for i := 0; i < tt.held; i++ {
+				release, err := q.acquire(context.Background(), tt.limit, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				releases = append(releases, release)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			switch tt.err {
+			case context.DeadlineExceeded:
+				var stop context.CancelFunc
+				ctx, stop = context.WithTimeout(ctx, 10*time.Millisecond)
+				defer stop()
+			case context.Canceled:
+				go func() {
+					for q.waiting() == 0 {
+						time.Sleep(time.Millisecond)
+					}
+					cancel()
+				}()
+			}
+			release, err := q.acquire(ctx, tt.limit, "")
+			if tt.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				release()
+			} else if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if n := q.waiting(); n != 0 {
+				t.Fatalf("expected no waiting requests, got %d", n)
+			}
+			// The slots of the held requests remain available once released.
+			for _, release := range releases {
+				release()
+			}
+			if q.active != 0 {
+				t.Fatalf("expected no active requests, got %d", q.active)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package rbxfetch
+
+import (
+	"context"
+	"io"
+
+	"github.com/anaminus/iofl"
+)
+
+// applyContext applies ctx to the chain of filters.
+func applyContext(filter iofl.Filter, ctx context.Context) {
+	type contexter interface {
+		iofl.Filter
+		SetContext(ctx context.Context)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(contexter); ok {
+			f.SetContext(ctx)
+		}
+		return nil
+	})
+}
+
+// latestRace visits every chain of method concurrently, returning the GUID of
+// the first chain to succeed. The remaining chains are cancelled. If every
+// chain fails, then the error of the first chain in order is returned.
+func (client *Client) latestRace(method string, vars map[string]string) (guid string, err error) {
+	type result struct {
+		i    int
+		guid string
+		err  error
+	}
+	chains := client.chains(method)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan result, len(chains))
+	for i, chain := range chains {
+		go func(i int, chain string) {
+			guid, err := client.latestChain(ctx, method, chain, vars)
+			results <- result{i: i, guid: guid, err: err}
+		}(i, chain)
+	}
+	errs := make([]error, len(chains))
+	for range chains {
+		r := <-results
+		if r.err == nil {
+			return r.guid, nil
+		}
+		errs[r.i] = r.err
+	}
+	if len(errs) > 0 {
+		err = errs[0]
+	}
+	return "", err
+}
+
+// liveRace visits every chain of method concurrently, returning the version
+// of each in the order of their chains. If a chain fails, then the remaining
+// chains are cancelled, and its error is returned.
+func (client *Client) liveRace(method string, vars map[string]string) (versions []ClientVersion, err error) {
+	type result struct {
+		i   int
+		v   ClientVersion
+		err error
+	}
+	chains := client.liveChains(method)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan result, len(chains))
+	for i, chain := range chains {
+		go func(i int, chain string) {
+			v, err := client.liveChain(ctx, method, chain, vars)
+			results <- result{i: i, v: v, err: err}
+		}(i, chain)
+	}
+	versions = make([]ClientVersion, len(chains))
+	for range chains {
+		r := <-results
+		if r.err != nil {
+			return nil, r.err
+		}
+		versions[r.i] = r.v
+	}
+	return versions, nil
+}
@@ -0,0 +1,98 @@
+package rbxfetch
+
+import (
+	"errors"
+	"io"
+)
+
+// RandomReader is implemented by the results of a Client's methods when the
+// chain that produces the content supports random access, such as when the
+// last link of the chain is a cache filter. When the content is cached, reads
+// are made directly from the cached file, without buffering. Otherwise, the
+// content is read into memory upon the first random access.
+//
+// Size returns the total size of the content. For example, a zip archive may
+// be opened with:
+//
+//	rc, err := client.Method("ContentFonts", guid)
+//	if r, ok := rc.(rbxfetch.RandomReader); ok {
+//		size, err := r.Size()
+//		z, err := zip.NewReader(r, size)
+//	}
+type RandomReader interface {
+	io.ReadCloser
+	io.ReaderAt
+	io.Seeker
+	Size() (int64, error)
+}
+
+// errNotRandom is returned by a randomResult when the result has fallen back
+// to a chain that does not support random access.
+var errNotRandom = errors.New("chain does not support random access")
+
+// randomSource is implemented by filters that support random access.
+type randomSource interface {
+	io.ReaderAt
+	io.Seeker
+}
+
+// randomResult is a result whose filter supports random access.
+type randomResult struct {
+	*result
+}
+
+// random returns r as a RandomReader if its filter supports random access, or
+// r otherwise.
+func (r *result) random() io.ReadCloser {
+	if _, ok := r.Filter.(randomSource); ok {
+		return randomResult{r}
+	}
+	return r
+}
+
+func (r randomResult) source() (randomSource, error) {
+	if src, ok := r.Filter.(randomSource); ok {
+		return src, nil
+	}
+	return nil, errNotRandom
+}
+
+func (r randomResult) ReadAt(p []byte, off int64) (n int, err error) {
+	src, err := r.source()
+	if err != nil {
+		return 0, err
+	}
+	return src.ReadAt(p, off)
+}
+
+func (r randomResult) Seek(offset int64, whence int) (n int64, err error) {
+	src, err := r.source()
+	if err != nil {
+		return 0, err
+	}
+	if whence == io.SeekCurrent {
+		// Account for content read ahead by begin.
+		offset -= int64(len(r.pending))
+	}
+	r.pending, r.pendingErr = nil, nil
+	return src.Seek(offset, whence)
+}
+
+// Size implements RandomReader. The current offset is preserved.
+func (r randomResult) Size() (size int64, err error) {
+	src, err := r.source()
+	if err != nil {
+		return 0, err
+	}
+	cur, err := src.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if size, err = src.Seek(0, io.SeekEnd); err != nil {
+		return 0, err
+	}
+	if _, err = src.Seek(cur, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
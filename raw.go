@@ -0,0 +1,151 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/anaminus/iofl"
+)
+
+// resolveLinks produces a Filter that applies each link in order, in the same
+// manner as iofl.ChainSet.Resolve. name is used to identify the links in
+// errors. If src is non-nil, then it is used as the source of the first
+// filter.
+func (client *Client) resolveLinks(name string, links iofl.Chain, src io.ReadCloser) (filter iofl.Filter, err error) {
+	if _, ok := src.(iofl.Filter); !ok {
+		if f, ok := src.(readAtSeekCloser); ok {
+			src = rootSeeker{f}
+		}
+	}
+	// A copy of the ChainSet shares the registered filters, while setting
+	// its configuration replaces only the chains of the copy.
+	set := *client.snapshot().chainSet
+	set.SetConfig(iofl.Config{Chains: map[string]iofl.Chain{name: links}})
+	return set.Resolve(name, src)
+}
+
+// hasFilter returns whether a filter of the given name is registered with the
+// client.
+func (client *Client) hasFilter(name string) bool {
+	for _, filter := range client.snapshot().filters {
+		if filter.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rootSeeker wraps a random-access source to be used as a Filter, retaining
+// its ability to be accessed randomly.
+type rootSeeker struct {
+	readAtSeekCloser
+}
+
+// Source implements iofl.Filter. Returns nil.
+func (rootSeeker) Source() io.ReadCloser { return nil }
+
+// rawSplit returns the number of leading links of chain that produce the raw
+// artifact. This includes the first link, and each subsequent cache link.
+func rawSplit(chain iofl.Chain) int {
+	n := 1
+	for n < len(chain) && chain[n].Filter == "cache" {
+		n++
+	}
+	return n
+}
+
+// RawArtifact is a temporary file containing the raw upstream artifact of a
+// chain. Closing the artifact removes the file.
+type RawArtifact struct {
+	*os.File
+}
+
+// Close closes and removes the file.
+func (r RawArtifact) Close() error {
+	err := r.File.Close()
+	os.Remove(r.File.Name())
+	return err
+}
+
+// copyTemp copies the content of upstream to the temporary file, closing
+// upstream. On failure, including if reading upstream panics, the file is
+// closed and removed.
+func copyTemp(file *os.File, upstream io.ReadCloser) (err error) {
+	keep := false
+	defer func() {
+		upstream.Close()
+		if !keep {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+	if _, err = io.Copy(file, upstream); err != nil {
+		return err
+	}
+	keep = true
+	return nil
+}
+
+// MethodRaw runs the configured method for the given GUID in the same manner
+// as Method, and also returns the raw artifact from which the result is
+// produced. For example, the raw artifact of the ReflectionMetadata method is
+// the entire Studio zip, rather than only the extracted file.
+//
+// The raw artifact is the content produced by the first filter of the chain,
+// and any cache filters that immediately follow. It is fetched immediately and
+// written to a temporary file, which the remainder of the chain reads from,
+// so that the artifact is fetched only once. The caller is responsible for
+// closing both rc and raw.
+func (client *Client) MethodRaw(method, guid string) (rc io.ReadCloser, raw RawArtifact, err error) {
+	chains := client.snapshot().chainSet.Config().Chains
+	for _, name := range client.chains(method) {
+		chain, ok := chains[name]
+		if !ok {
+			err = fmt.Errorf("unknown chain %q", name)
+			continue
+		}
+		if len(chain) == 0 {
+			err = fmt.Errorf("chain %q is empty", name)
+			continue
+		}
+		n := rawSplit(chain)
+
+		// Fetch the raw artifact into a temporary file.
+		var upstream iofl.Filter
+		if upstream, err = client.resolveLinks(name, chain[:n], nil); err != nil {
+			continue
+		}
+		client.prepare(upstream, method, name, guid, nil)
+		var file *os.File
+		if file, err = createTemp(""); err != nil {
+			upstream.Close()
+			return nil, raw, err
+		}
+		if err = copyTemp(file, upstream); err != nil {
+			continue
+		}
+		raw = RawArtifact{File: file}
+
+		// Run the remainder of the chain on a separate handle to the file.
+		var src *os.File
+		if src, err = os.Open(file.Name()); err != nil {
+			raw.Close()
+			return nil, RawArtifact{}, err
+		}
+		var downstream iofl.Filter
+		if downstream, err = client.resolveLinks(name, chain[n:], src); err != nil {
+			src.Close()
+			raw.Close()
+			return nil, RawArtifact{}, err
+		}
+		client.prepare(downstream, method, name, guid, nil)
+		if _, err = raw.Seek(0, io.SeekStart); err != nil {
+			downstream.Close()
+			raw.Close()
+			return nil, RawArtifact{}, err
+		}
+		return client.newResult(method, name, downstream, false), raw, nil
+	}
+	return nil, raw, err
+}
@@ -0,0 +1,192 @@
+package rbxfetchtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Builds of the sample files. GUID is the latest build, and PreviousGUID is the
+// build deployed before it.
+const (
+	GUID            = "version-0123456789abcdef"
+	Version         = "0.601.0.6010529"
+	PreviousGUID    = "version-fedcba9876543210"
+	PreviousVersion = "0.600.0.6000862"
+)
+
+// DeployHistory is the content of the sample deployment history.
+const DeployHistory = "" +
+	"New Studio64 " + PreviousGUID + " at 11/1/2023 10:00:00 AM, file version: 0, 600, 0, 6000862...Done!\r\n" +
+	"New WindowsPlayer version-00000000000000aa at 11/1/2023 10:05:00 AM, file version: 0, 600, 0, 6000862...Done!\r\n" +
+	"New Studio64 " + GUID + " at 11/8/2023 10:00:00 AM, file version: 0, 601, 0, 6010529...Done!\r\n" +
+	"New WindowsPlayer version-00000000000000bb at 11/8/2023 10:05:00 AM, file version: 0, 601, 0, 6010529...Done!\r\n"
+
+// APIDump is the content of the sample API dump of each build.
+const APIDump = `{
+	"Version": 1,
+	"Classes": [
+		{
+			"Name": "Instance",
+			"Superclass": "<<<ROOT>>>",
+			"MemoryCategory": "Instances",
+			"Members": [
+				{
+					"MemberType": "Property",
+					"Name": "Name",
+					"Category": "Data",
+					"Security": {"Read": "None", "Write": "None"},
+					"Serialization": {"CanLoad": true, "CanSave": true},
+					"ThreadSafety": "ReadSafe",
+					"ValueType": {"Category": "Primitive", "Name": "string"},
+					"Tags": []
+				}
+			],
+			"Tags": ["NotCreatable"]
+		},
+		{
+			"Name": "Part",
+			"Superclass": "Instance",
+			"MemoryCategory": "BaseParts",
+			"Members": [],
+			"Tags": []
+		}
+	],
+	"Enums": [
+		{
+			"Name": "Material",
+			"Items": [
+				{"Name": "Plastic", "Value": 256},
+				{"Name": "Wood", "Value": 512}
+			]
+		}
+	]
+}
+`
+
+// ReflectionMetadata is the content of the sample reflection metadata of each
+// build.
+const ReflectionMetadata = `<roblox xmlns:xmime="http://www.w3.org/2005/05/xmlmime" version="4">
+	<Item class="ReflectionMetadataClasses">
+		<Properties><string name="Name">ReflectionMetadataClasses</string></Properties>
+		<Item class="ReflectionMetadataClass">
+			<Properties>
+				<string name="Name">Instance</string>
+				<string name="summary">The base class of all classes.</string>
+				<string name="ExplorerImageIndex">0</string>
+				<string name="ExplorerOrder">0</string>
+			</Properties>
+			<Item class="ReflectionMetadataProperties">
+				<Item class="ReflectionMetadataMember">
+					<Properties>
+						<string name="Name">Name</string>
+						<string name="summary">A non-unique identifier of the instance.</string>
+					</Properties>
+				</Item>
+			</Item>
+		</Item>
+		<Item class="ReflectionMetadataClass">
+			<Properties>
+				<string name="Name">Part</string>
+				<string name="ExplorerImageIndex">1</string>
+				<string name="ExplorerOrder">2</string>
+				<string name="ClassCategory">Parts</string>
+				<string name="PreferredParent">Workspace</string>
+			</Properties>
+		</Item>
+	</Item>
+	<Item class="ReflectionMetadataEnums">
+		<Item class="ReflectionMetadataEnum">
+			<Properties><string name="Name">Material</string></Properties>
+			<Item class="ReflectionMetadataEnumItem">
+				<Properties><string name="Name">Plastic</string></Properties>
+			</Item>
+		</Item>
+	</Item>
+</roblox>
+`
+
+// ClientSettings is the content of the sample fast flags of each application.
+const ClientSettings = `{"applicationSettings":{"FFlagSample":"True","FIntSample":"10","FStringSample":"sample"}}`
+
+// ClassImages returns the content of the sample class icons of each build: a
+// PNG image containing two 16x16 icons.
+func ClassImages() []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 8), G: uint8(y * 16), A: 255})
+		}
+	}
+	var b bytes.Buffer
+	png.Encode(&b, img)
+	return b.Bytes()
+}
+
+// zipFiles returns a zip archive containing the given files, in order.
+func zipFiles(files ...string) []byte {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for i := 0; i+1 < len(files); i += 2 {
+		f, _ := w.Create(files[i])
+		f.Write([]byte(files[i+1]))
+	}
+	w.Close()
+	return b.Bytes()
+}
+
+// manifest returns the content of a package manifest listing the given
+// packages, mapped by name.
+func manifest(names []string, packages map[string][]byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("v0\r\n")
+	for _, name := range names {
+		sum := md5.Sum(packages[name])
+		fmt.Fprintf(&b, "%s\r\n%s\r\n%d\r\n%d\r\n", name, hex.EncodeToString(sum[:]), len(packages[name]), len(packages[name]))
+	}
+	return b.Bytes()
+}
+
+// Files returns the sample files loaded by NewServer, mapped by name. The
+// files include the deployment history, the latest version, the live version
+// of each of rbxfetch.DefaultLiveBinaryTypes, the fast flags of
+// ApplicationPCStudioApp, and, for both GUID and PreviousGUID, the API dump,
+// the package manifest, and the RobloxStudio.zip and content-textures2.zip
+// packages, which contain the reflection metadata and class icons. Each call
+// returns new copies of the files.
+func Files() map[string][]byte {
+	const setup = "setup.rbxcdn.com/"
+	const settings = "clientsettings.roblox.com/v2/"
+	live := []byte(`{"version":"` + Version + `","clientVersionUpload":"` + GUID + `","bootstrapperVersion":"1, 6, 0, 6010529"}`)
+	files := map[string][]byte{
+		setup + "DeployHistory.txt":                   []byte(DeployHistory),
+		setup + "versionQTStudio":                     []byte(GUID),
+		setup + "versionStudio64":                     []byte(GUID),
+		settings + "client-version/WindowsStudio":     live,
+		settings + "client-version/WindowsStudio64":   live,
+		settings + "client-version/MacStudio":         live,
+		settings + "client-version/WindowsPlayer":     []byte(`{"version":"` + Version + `","clientVersionUpload":"version-00000000000000bb","bootstrapperVersion":"1, 6, 0, 6010529"}`),
+		settings + "settings/application/PCStudioApp": []byte(ClientSettings),
+	}
+	for _, guid := range []string{GUID, PreviousGUID} {
+		packages := map[string][]byte{
+			"RobloxStudio.zip":              zipFiles("ReflectionMetadata.xml", ReflectionMetadata),
+			"content-textures2.zip":         zipFiles("ClassImages.PNG", string(ClassImages())),
+			"content-api-docs.zip":          zipFiles("en-us.json", "{}"),
+			"content-qt_translations.zip":   zipFiles("qt_de.qm", "qm"),
+			"extracontent-translations.zip": zipFiles("de-de.json", "{}"),
+		}
+		names := []string{"RobloxStudio.zip", "content-textures2.zip", "content-api-docs.zip", "content-qt_translations.zip", "extracontent-translations.zip"}
+		for _, name := range names {
+			files[setup+guid+"-"+name] = packages[name]
+		}
+		files[setup+guid+"-rbxPkgManifest.txt"] = manifest(names, packages)
+		files[setup+guid+"-API-Dump.json"] = []byte(APIDump)
+	}
+	return files
+}
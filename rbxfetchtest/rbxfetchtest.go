@@ -0,0 +1,235 @@
+// The rbxfetchtest package provides a fake deployment CDN for testing code
+// that uses rbxfetch, without accessing the network.
+package rbxfetchtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robloxapi/rbxfetch"
+)
+
+// Server is an HTTP server that serves files from memory in place of the hosts
+// used by rbxfetch. A file is identified by the host and path of its original
+// URL, without a scheme or query, such as
+// "setup.rbxcdn.com/DeployHistory.txt". Requests for files that do not exist
+// receive a 404 response.
+//
+// Files are served with an ETag derived from their content, and Range
+// requests are supported, including If-Range, so that resumed and segmented
+// downloads can be exercised. Fail and Interrupt inject failures.
+//
+// A Server may be used concurrently.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	files      map[string][]byte
+	requests   []string
+	failures   map[string][]int
+	interrupts map[string]int64
+}
+
+// NewServer starts and returns a Server loaded with the sample files returned
+// by Files. The server should be closed when no longer needed.
+func NewServer() *Server {
+	s := &Server{files: Files()}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// NewUnloadedServer starts and returns a Server that contains no files.
+func NewUnloadedServer() *Server {
+	s := &Server{files: map[string][]byte{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// serve serves the file of the request, which has the original host as the
+// first element of its path.
+func (s *Server) serve(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(path.Clean(req.URL.Path), "/")
+	s.mu.Lock()
+	s.requests = append(s.requests, name)
+	content, ok := s.files[name]
+	status := 0
+	if statuses := s.failures[name]; len(statuses) > 0 {
+		status = statuses[0]
+		s.failures[name] = statuses[1:]
+	}
+	limit, interrupt := s.interrupts[name]
+	if status == 0 && ok {
+		delete(s.interrupts, name)
+	} else {
+		interrupt = false
+	}
+	s.mu.Unlock()
+	if status != 0 {
+		if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "0")
+		}
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	sum := sha256.Sum256(content)
+	w.Header().Set("Content-Type", contentType(name))
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+	if interrupt {
+		w = &interruptWriter{ResponseWriter: w, limit: limit}
+	}
+	http.ServeContent(w, req, name, time.Time{}, bytes.NewReader(content))
+}
+
+// interruptWriter aborts the response once limit bytes of the body have been
+// written.
+type interruptWriter struct {
+	http.ResponseWriter
+	limit int64
+}
+
+func (w *interruptWriter) Write(p []byte) (n int, err error) {
+	if int64(len(p)) > w.limit {
+		n, _ = w.ResponseWriter.Write(p[:w.limit])
+		if f, ok := w.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+		// Closes the connection without completing the response.
+		panic(http.ErrAbortHandler)
+	}
+	w.limit -= int64(len(p))
+	return w.ResponseWriter.Write(p)
+}
+
+// Fail causes the next requests for the file of the given name to fail with
+// the given statuses, one per request, in order. Responses with a status of
+// 429 Too Many Requests or 503 Service Unavailable request a retry with a
+// Retry-After of 0.
+func (s *Server) Fail(name string, statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures == nil {
+		s.failures = map[string][]int{}
+	}
+	s.failures[name] = append(s.failures[name], statuses...)
+}
+
+// Interrupt causes the next request that serves the content of the file of the
+// given name to be interrupted once n bytes of the content have been sent, as
+// though the connection was lost. Requests that fail do not count.
+func (s *Server) Interrupt(name string, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.interrupts == nil {
+		s.interrupts = map[string]int64{}
+	}
+	s.interrupts[name] = n
+}
+
+// Set sets the content of the file of the given name, adding the file if it
+// does not exist.
+func (s *Server) Set(name string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = content
+}
+
+// Get returns the content of the file of the given name, and whether the file
+// exists.
+func (s *Server) Get(name string) (content []byte, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok = s.files[name]
+	return content, ok
+}
+
+// Remove removes the file of the given name, causing requests for it to fail.
+func (s *Server) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, name)
+}
+
+// Names returns the names of the files of the server, in sorted order.
+func (s *Server) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Requests returns the names of the files requested from the server, in the
+// order in which they were received, including files that do not exist.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ResetRequests clears the requests recorded by the server.
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// RewriteURL redirects the request of u to the server, moving the original
+// host into the path. It is suitable as the RewriteURL of an rbxfetch.Client.
+func (s *Server) RewriteURL(u *url.URL) error {
+	base, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	u.Path = "/" + u.Host + u.Path
+	u.RawPath = ""
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+	return nil
+}
+
+// NewTestClient returns a client that makes every request to s. The client
+// has the default configuration, regardless of the environment, and does not
+// cache content.
+func NewTestClient(s *Server) *rbxfetch.Client {
+	client := rbxfetch.NewClient()
+	client.SetConfig(rbxfetch.DefaultConfig())
+	client.CacheMode = rbxfetch.CacheNone
+	client.Proxy = ""
+	client.Client = s.Client()
+	client.RewriteURL = s.RewriteURL
+	return client
+}
+
+// contentType returns the content type of the file of the given name.
+func contentType(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		return "application/json"
+	case ".xml":
+		return "text/xml"
+	case ".zip":
+		return "application/zip"
+	case ".png":
+		return "image/png"
+	}
+	if strings.HasPrefix(name, "clientsettings.") {
+		return "application/json"
+	}
+	return "text/plain"
+}
@@ -0,0 +1,104 @@
+package rbxfetchtest
+
+import (
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestServerRange(t *testing.T) {
+	const name = "setup.rbxcdn.com/DeployHistory.txt"
+	s := NewServer()
+	defer s.Close()
+	resp, err := s.Client().Get(s.URL + "/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag")
+	}
+	tests := []struct {
+		name    string
+		header  map[string]string
+		status  int
+		content string
+	}{
+		{name: "entire", status: http.StatusOK, content: DeployHistory},
+		{name: "range", header: map[string]string{"Range": "bytes=4-9"}, status: http.StatusPartialContent, content: DeployHistory[4:10]},
+		{name: "suffix", header: map[string]string{"Range": "bytes=-4"}, status: http.StatusPartialContent, content: DeployHistory[len(DeployHistory)-4:]},
+		{name: "if-range", header: map[string]string{"Range": "bytes=4-", "If-Range": etag}, status: http.StatusPartialContent, content: DeployHistory[4:]},
+		{name: "stale if-range", header: map[string]string{"Range": "bytes=4-", "If-Range": `"stale"`}, status: http.StatusOK, content: DeployHistory},
+		{name: "not modified", header: map[string]string{"If-None-Match": etag}, status: http.StatusNotModified},
+		{name: "unsatisfiable", header: map[string]string{"Range": "bytes=100000-"}, status: http.StatusRequestedRangeNotSatisfiable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", s.URL+"/"+name, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			resp, err := s.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if tt.content != "" && string(b) != tt.content {
+				t.Errorf("expected %q, got %q", tt.content, b)
+			}
+		})
+	}
+}
+
+func TestServerFailures(t *testing.T) {
+	const name = "setup.rbxcdn.com/versionQTStudio"
+	s := NewServer()
+	defer s.Close()
+	s.Fail(name, http.StatusTooManyRequests, http.StatusInternalServerError)
+	s.Interrupt(name, 4)
+	tests := []struct {
+		status     int
+		retryAfter string
+		content    string
+		err        bool
+	}{
+		{status: http.StatusTooManyRequests, retryAfter: "0"},
+		{status: http.StatusInternalServerError},
+		{status: http.StatusOK, content: GUID[:4], err: true},
+		{status: http.StatusOK, content: GUID},
+	}
+	for i, tt := range tests {
+		resp, err := s.Client().Get(s.URL + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Fatalf("request %d: expected status %d, got %d", i+1, tt.status, resp.StatusCode)
+		}
+		if v := resp.Header.Get("Retry-After"); v != tt.retryAfter {
+			t.Errorf("request %d: expected Retry-After %q, got %q", i+1, tt.retryAfter, v)
+		}
+		if (err != nil) != tt.err {
+			t.Errorf("request %d: unexpected error %v", i+1, err)
+		}
+		if tt.content != "" && string(b) != tt.content {
+			t.Errorf("request %d: expected %q, got %q", i+1, tt.content, b)
+		}
+	}
+	if n := len(s.Requests()); n != len(tests) {
+		t.Errorf("expected %d requests, got %d", len(tests), n)
+	}
+}
@@ -0,0 +1,229 @@
+package rbxfetchtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/robloxapi/rbxfetch"
+)
+
+// EnvRecord is the environment variable that selects the mode of
+// ModeFromEnv. If set to a true value, such as "1", then ModeRecord is
+// selected.
+const EnvRecord = "RBXFETCH_RECORD"
+
+// Mode specifies whether a Recorder records or replays interactions.
+type Mode int
+
+const (
+	// Responses are served from the fixtures. Requests without a fixture
+	// fail.
+	ModeReplay Mode = iota
+	// Requests are made with the underlying transport, and each interaction
+	// is written to the fixtures.
+	ModeRecord
+)
+
+// ModeFromEnv returns ModeRecord if EnvRecord is set to a true value, and
+// ModeReplay otherwise, so that fixtures can be recorded by running the same
+// tests with the variable set.
+func ModeFromEnv() Mode {
+	if record, _ := strconv.ParseBool(os.Getenv(EnvRecord)); record {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// UnexpectedRequestError is returned by a replaying Recorder for a request
+// that has no fixture.
+type UnexpectedRequestError struct {
+	Method string
+	URL    string
+	Range  string
+}
+
+func (e *UnexpectedRequestError) Error() string {
+	s := "unexpected request " + e.Method + " " + e.URL
+	if e.Range != "" {
+		s += " (" + e.Range + ")"
+	}
+	return s
+}
+
+// fixture describes a recorded interaction. The body of the response is stored
+// in a separate file.
+type fixture struct {
+	Method string
+	URL    string
+	Range  string `json:",omitempty"`
+	Status int
+	Header http.Header
+}
+
+// Recorder is an http.RoundTripper that records HTTP interactions to a fixture
+// directory, or replays them from the directory, allowing a run of a Client to
+// be repeated deterministically without the network. Interactions are
+// identified by their method, URL, and Range header; for each interaction, the
+// directory contains a JSON file describing the response, and a file
+// containing the body. Recording an interaction that already exists replaces
+// it.
+//
+// A Recorder may be used concurrently.
+type Recorder struct {
+	// Dir is the fixture directory.
+	Dir string
+	// Mode specifies whether interactions are recorded or replayed.
+	Mode Mode
+	// Transport makes the requests of a recording Recorder. If nil, then
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mu         sync.Mutex
+	unexpected []*UnexpectedRequestError
+}
+
+// NewRecorder returns a Recorder that records to or replays from dir.
+func NewRecorder(dir string, mode Mode) *Recorder {
+	return &Recorder{Dir: dir, Mode: mode}
+}
+
+// fixtureName returns the base name of the files of the fixture of req.
+func fixtureName(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String() + " " + req.Header.Get("Range")))
+	return hex.EncodeToString(sum[:12])
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.Mode == ModeRecord {
+		return r.record(req)
+	}
+	return r.replay(req)
+}
+
+// replay returns the recorded response to req.
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	name := filepath.Join(r.Dir, fixtureName(req))
+	b, err := os.ReadFile(name + ".json")
+	if os.IsNotExist(err) {
+		e := &UnexpectedRequestError{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Range:  req.Header.Get("Range"),
+		}
+		r.mu.Lock()
+		r.unexpected = append(r.unexpected, e)
+		r.mu.Unlock()
+		return nil, e
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", name, err)
+	}
+	body, err := os.ReadFile(name + ".body")
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(f.Status) + " " + http.StatusText(f.Status),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// record makes req, writing the interaction to the fixture directory.
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	f := fixture{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Range:  req.Header.Get("Range"),
+		Status: resp.StatusCode,
+		Header: resp.Header.Clone(),
+	}
+	if resp.Uncompressed {
+		// The recorded body is already decoded.
+		f.Header.Del("Content-Encoding")
+	}
+	f.Header.Del("Content-Length")
+	b, err := json.MarshalIndent(f, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return nil, err
+	}
+	name := filepath.Join(r.Dir, fixtureName(req))
+	if err := os.WriteFile(name+".body", body, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(name+".json", b, 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Unexpected returns the requests made to a replaying Recorder that had no
+// fixture, in the order in which they were made.
+func (r *Recorder) Unexpected() []*UnexpectedRequestError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*UnexpectedRequestError(nil), r.unexpected...)
+}
+
+// Client returns an HTTP client that makes requests with the Recorder.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// NewRecordingClient returns a client that records its HTTP interactions to
+// dir, or replays them from dir, according to mode. The client has the
+// default configuration, regardless of the environment, and does not cache
+// content, so that every fetch is made through the Recorder. Requests are
+// not retried, so that a replayed run makes the same requests as the
+// recorded run.
+func NewRecordingClient(dir string, mode Mode) (*rbxfetch.Client, *Recorder) {
+	recorder := NewRecorder(dir, mode)
+	client := rbxfetch.NewClient()
+	client.SetConfig(rbxfetch.DefaultConfig())
+	client.CacheMode = rbxfetch.CacheNone
+	client.Proxy = ""
+	client.Retry = nil
+	client.Client = recorder.Client()
+	return client, recorder
+}
@@ -0,0 +1,233 @@
+package rbxfetch
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReflectionMetadata is the decoded content of a ReflectionMetadata.xml file,
+// which describes how classes, members, and enums are presented in Studio.
+type ReflectionMetadata struct {
+	// Classes maps the name of a class to its metadata.
+	Classes map[string]*ClassMetadata
+	// Enums maps the name of an enum to its metadata.
+	Enums map[string]*EnumMetadata
+}
+
+// ItemMetadata contains the properties common to each metadata item, decoded
+// from the raw Properties of the item.
+type ItemMetadata struct {
+	// Summary is a description of the item.
+	Summary string
+	// Browsable is whether the item is shown in the object browser. Items
+	// are browsable unless specified otherwise.
+	Browsable bool
+	// Deprecated is whether the item is deprecated.
+	Deprecated bool
+}
+
+// ClassMetadata is the metadata of a class.
+type ClassMetadata struct {
+	Name string
+	ItemMetadata
+	// ExplorerOrder is the sort order of instances of the class in the
+	// Explorer panel, or -1 if unspecified.
+	ExplorerOrder int
+	// ExplorerImageIndex is the index of the icon of the class within the
+	// class image sheet, or -1 if unspecified.
+	ExplorerImageIndex int
+	// PreferredParent is the name of the service into which instances of the
+	// class are preferably inserted.
+	PreferredParent string
+	// Category is the category of the class in the insertion menu.
+	Category string
+	// Properties contains each property of the metadata item, such as
+	// "ExplorerOrder", as a raw string.
+	Properties map[string]string
+	// Members maps the name of a member of the class to its metadata.
+	Members map[string]*MemberMetadata
+}
+
+// MemberMetadata is the metadata of a class member or enum item.
+type MemberMetadata struct {
+	Name string
+	ItemMetadata
+	// Kind is the kind of member, such as "Properties" or "Functions". Empty
+	// for enum items.
+	Kind string
+	// Properties contains each property of the metadata item as a raw
+	// string.
+	Properties map[string]string
+}
+
+// EnumMetadata is the metadata of an enum.
+type EnumMetadata struct {
+	Name string
+	ItemMetadata
+	// Properties contains each property of the metadata item as a raw
+	// string.
+	Properties map[string]string
+	// Items maps the name of an enum item to its metadata.
+	Items map[string]*MemberMetadata
+}
+
+// Copy returns a deep copy of the metadata.
+func (rmd *ReflectionMetadata) Copy() *ReflectionMetadata {
+	c := &ReflectionMetadata{
+		Classes: make(map[string]*ClassMetadata, len(rmd.Classes)),
+		Enums:   make(map[string]*EnumMetadata, len(rmd.Enums)),
+	}
+	for name, class := range rmd.Classes {
+		cc := *class
+		cc.Properties = copyProps(class.Properties)
+		cc.Members = copyMembers(class.Members)
+		c.Classes[name] = &cc
+	}
+	for name, enum := range rmd.Enums {
+		ce := *enum
+		ce.Properties = copyProps(enum.Properties)
+		ce.Items = copyMembers(enum.Items)
+		c.Enums[name] = &ce
+	}
+	return c
+}
+
+func copyProps(props map[string]string) map[string]string {
+	c := make(map[string]string, len(props))
+	for k, v := range props {
+		c[k] = v
+	}
+	return c
+}
+
+func copyMembers(members map[string]*MemberMetadata) map[string]*MemberMetadata {
+	c := make(map[string]*MemberMetadata, len(members))
+	for name, member := range members {
+		cm := *member
+		cm.Properties = copyProps(member.Properties)
+		c[name] = &cm
+	}
+	return c
+}
+
+// itemMetadata decodes the common properties of a metadata item.
+func itemMetadata(props map[string]string) ItemMetadata {
+	return ItemMetadata{
+		Summary:    props["summary"],
+		Browsable:  propBool(props, "Browsable", true),
+		Deprecated: propBool(props, "Deprecated", false),
+	}
+}
+
+// propBool returns the value of a boolean property, or def if the property is
+// absent or malformed.
+func propBool(props map[string]string, name string, def bool) bool {
+	if v, err := strconv.ParseBool(strings.TrimSpace(props[name])); err == nil {
+		return v
+	}
+	return def
+}
+
+// propInt returns the value of an integer property, or -1 if the property is
+// absent or malformed.
+func propInt(props map[string]string, name string) int {
+	if v, err := strconv.Atoi(strings.TrimSpace(props[name])); err == nil {
+		return v
+	}
+	return -1
+}
+
+// rmdItem is an Item element of a ReflectionMetadata file.
+type rmdItem struct {
+	Class      string `xml:"class,attr"`
+	Properties struct {
+		Values []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"Properties"`
+	Items []rmdItem `xml:"Item"`
+}
+
+// props returns the properties of the item as a map.
+func (item *rmdItem) props() map[string]string {
+	props := make(map[string]string, len(item.Properties.Values))
+	for _, v := range item.Properties.Values {
+		props[v.Name] = v.Value
+	}
+	return props
+}
+
+// members returns the metadata of each member item within the container
+// items of item, such as ReflectionMetadataProperties.
+func (item *rmdItem) members() map[string]*MemberMetadata {
+	members := map[string]*MemberMetadata{}
+	for _, container := range item.Items {
+		kind := strings.TrimPrefix(container.Class, "ReflectionMetadata")
+		for _, m := range container.Items {
+			props := m.props()
+			members[props["Name"]] = &MemberMetadata{
+				Name:         props["Name"],
+				ItemMetadata: itemMetadata(props),
+				Kind:         kind,
+				Properties:   props,
+			}
+		}
+	}
+	return members
+}
+
+// DecodeReflectionMetadata decodes the content of a ReflectionMetadata.xml
+// file.
+func DecodeReflectionMetadata(r io.Reader) (rmd *ReflectionMetadata, err error) {
+	var root struct {
+		Items []rmdItem `xml:"Item"`
+	}
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, classify(ErrDecode, err)
+	}
+	rmd = &ReflectionMetadata{
+		Classes: map[string]*ClassMetadata{},
+		Enums:   map[string]*EnumMetadata{},
+	}
+	for _, section := range root.Items {
+		switch section.Class {
+		case "ReflectionMetadataClasses":
+			for _, item := range section.Items {
+				props := item.props()
+				rmd.Classes[props["Name"]] = &ClassMetadata{
+					Name:               props["Name"],
+					ItemMetadata:       itemMetadata(props),
+					ExplorerOrder:      propInt(props, "ExplorerOrder"),
+					ExplorerImageIndex: propInt(props, "ExplorerImageIndex"),
+					PreferredParent:    props["PreferredParent"],
+					Category:           props["ClassCategory"],
+					Properties:         props,
+					Members:            item.members(),
+				}
+			}
+		case "ReflectionMetadataEnums":
+			for _, item := range section.Items {
+				props := item.props()
+				enum := &EnumMetadata{
+					Name:         props["Name"],
+					ItemMetadata: itemMetadata(props),
+					Properties:   props,
+					Items:        map[string]*MemberMetadata{},
+				}
+				for _, ei := range item.Items {
+					props := ei.props()
+					enum.Items[props["Name"]] = &MemberMetadata{
+						Name:         props["Name"],
+						ItemMetadata: itemMetadata(props),
+						Properties:   props,
+					}
+				}
+				rmd.Enums[enum.Name] = enum
+			}
+		}
+	}
+	return rmd, nil
+}
@@ -0,0 +1,339 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/anaminus/iofl"
+)
+
+// Timing describes the time taken to fetch the result of a method.
+type Timing struct {
+	// Start is the time at which the result was first read.
+	Start time.Time
+	// FirstByte is the duration from Start until the response to the first
+	// network request of the chain was received. Zero if no network request
+	// was made, such as when the content is cached.
+	FirstByte time.Duration
+	// Download is the duration from Start until the response to the first
+	// network request of the chain was fully read. Zero if no network request
+	// was made.
+	Download time.Duration
+	// Total is the duration from Start until the result was fully read. The
+	// difference between Total and Download approximates the time spent
+	// processing the content locally.
+	Total time.Duration
+}
+
+// Timer is implemented by the results of a Client's methods. Timing returns
+// the time taken to fetch the result. The Timing is complete once the result
+// has been fully read.
+type Timer interface {
+	Timing() Timing
+}
+
+// MethodStats contains statistics of the fetches performed by a method. Only
+// results that are read fully are included.
+type MethodStats struct {
+	// Fetches is the number of fetches recorded.
+	Fetches int
+	// Network is the number of fetches that made a network request.
+	Network int
+	// FirstByte is the average FirstByte of fetches that made a network
+	// request.
+	FirstByte time.Duration
+	// Download is the average Download of fetches that made a network
+	// request.
+	Download time.Duration
+	// Total is the average Total of all fetches.
+	Total time.Duration
+}
+
+type methodStatSet struct {
+	mu    sync.Mutex
+	stats map[string]*methodTotals
+}
+
+type methodTotals struct {
+	fetches   int
+	network   int
+	firstByte time.Duration
+	download  time.Duration
+	total     time.Duration
+}
+
+func (s *methodStatSet) record(method string, t Timing) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = map[string]*methodTotals{}
+	}
+	m := s.stats[method]
+	if m == nil {
+		m = &methodTotals{}
+		s.stats[method] = m
+	}
+	m.fetches++
+	m.total += t.Total
+	if t.FirstByte > 0 {
+		m.network++
+		m.firstByte += t.FirstByte
+		m.download += t.Download
+	}
+}
+
+func (s *methodStatSet) get(method string) (stats MethodStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.stats[method]
+	if m == nil || m.fetches == 0 {
+		return stats
+	}
+	stats.Fetches = m.fetches
+	stats.Network = m.network
+	stats.Total = m.total / time.Duration(m.fetches)
+	if m.network > 0 {
+		stats.FirstByte = m.firstByte / time.Duration(m.network)
+		stats.Download = m.download / time.Duration(m.network)
+	}
+	return stats
+}
+
+// MethodStats returns statistics of the fetches performed by the given method.
+func (client *Client) MethodStats(method string) MethodStats {
+	return client.timings.get(method)
+}
+
+// networkTimer is implemented by filters that make network requests.
+type networkTimer interface {
+	iofl.Filter
+	// NetworkTiming returns the time at which the request was made, the time
+	// at which the response was received, and the time at which the response
+	// was fully read. Times are zero if they have not occurred.
+	NetworkTiming() (start, response, done time.Time)
+}
+
+// result wraps the Filter produced by the chain of a method, recording the
+// outcome of the fetch.
+type result struct {
+	iofl.Filter
+	client *Client
+	method string
+	chain  string
+	// observe is whether the outcome of the first read is reported to the
+	// client's adaptive chain statistics.
+	observe  bool
+	created  time.Time
+	read     bool
+	produced bool
+	finished bool
+	timing   Timing
+	// fallback, if non-nil, resolves the next chain of the method, to be used
+	// when the current chain fails before producing any content. Returns a
+	// nil Filter when no chains remain.
+	fallback func() (chain string, f iofl.Filter)
+	// pending is content read by begin that has yet to be returned, followed
+	// by pendingErr.
+	pending    []byte
+	pendingErr error
+}
+
+// newResult wraps f as the result of chain for method.
+func (client *Client) newResult(method, chain string, f iofl.Filter, observe bool) *result {
+	return &result{
+		Filter:  f,
+		client:  client,
+		method:  method,
+		chain:   chain,
+		observe: observe,
+		created: time.Now(),
+	}
+}
+
+func (r *result) Read(p []byte) (n int, err error) {
+	if r.pending != nil || r.pendingErr != nil {
+		return r.readPending(p)
+	}
+	if !r.read {
+		r.read = true
+		r.timing.Start = time.Now()
+	}
+	n, err = r.Filter.Read(p)
+	var first error
+	if n == 0 && err != nil && err != io.EOF && !r.produced && r.fallback != nil {
+		first = r.client.chainError(r.method, r.chain, r.Filter, err)
+		for n == 0 && err != nil && err != io.EOF {
+			chain, f := r.fallback()
+			if f == nil {
+				break
+			}
+			if r.observe {
+				r.client.observe(r.method, r.chain, r.created, err)
+			}
+			r.client.recordMetrics(r.method, r.chain, r.Filter, time.Since(r.created), err)
+			r.Filter.Close()
+			r.Filter, r.chain, r.created = f, chain, time.Now()
+			n, err = r.Filter.Read(p)
+		}
+	}
+	if n > 0 {
+		r.produced = true
+	}
+	if r.observe {
+		r.observe = false
+		if err == io.EOF {
+			r.client.observe(r.method, r.chain, r.created, nil)
+		} else {
+			r.client.observe(r.method, r.chain, r.created, err)
+		}
+	}
+	if first != nil && n == 0 && err != nil && err != io.EOF {
+		// Every remaining chain also failed; report the original error.
+		err = first
+	} else {
+		err = r.client.chainError(r.method, r.chain, r.Filter, err)
+	}
+	if err != nil && !r.finished {
+		r.finish(err)
+	}
+	return n, err
+}
+
+// WriteTo implements io.WriterTo. Once the chain has produced content, the
+// remainder is written by the final filter of the chain, if it implements
+// io.WriterTo, so that the content is not copied through an intermediate
+// buffer.
+func (r *result) WriteTo(w io.Writer) (n int64, err error) {
+	var buf []byte
+	for {
+		if r.produced && r.pending == nil && r.pendingErr == nil && !r.finished {
+			if wt, ok := r.Filter.(io.WriterTo); ok {
+				m, err := wt.WriteTo(w)
+				n += m
+				if err != nil {
+					err = r.client.chainError(r.method, r.chain, r.Filter, err)
+					r.finish(err)
+					return n, err
+				}
+				r.finish(io.EOF)
+				return n, nil
+			}
+		}
+		if buf == nil {
+			buf = make([]byte, copyBufferSize)
+		}
+		m, err := r.Read(buf)
+		if m > 0 {
+			m, werr := w.Write(buf[:m])
+			n += int64(m)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// copyBufferSize is the size of the buffer used to copy a result that cannot
+// be written directly.
+const copyBufferSize = 32 << 10
+
+// maxVersionSize is the maximum size of the content of a chain that produces a
+// version, such as a GUID.
+const maxVersionSize = 64 << 10
+
+// readBounded reads r until EOF, failing with an error classified as ErrDecode
+// if the content exceeds limit bytes, rather than buffering content of
+// unbounded size.
+func readBounded(r io.Reader, limit int64) (b []byte, err error) {
+	b, err = io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, classify(ErrDecode, fmt.Errorf("content exceeds %d bytes", limit))
+	}
+	return b, nil
+}
+
+// finish completes the timing of the result, recording it if the result was
+// read successfully. The fetch is reported to the client's Metrics.
+func (r *result) finish(err error) {
+	r.finished = true
+	r.timing.Total = time.Since(r.timing.Start)
+	iofl.Apply(r.Filter, func(f io.ReadCloser) error {
+		if f, ok := f.(networkTimer); ok {
+			start, response, done := f.NetworkTiming()
+			if !start.IsZero() && !response.IsZero() {
+				r.timing.FirstByte = response.Sub(r.timing.Start)
+				if !done.IsZero() {
+					r.timing.Download = done.Sub(r.timing.Start)
+				}
+			}
+		}
+		return nil
+	})
+	if err == io.EOF {
+		r.client.timings.record(r.method, r.timing)
+	}
+	r.client.recordMetrics(r.method, r.chain, r.Filter, r.timing.Total, err)
+}
+
+// Close closes the result. If the result was closed before being read
+// completely, then the fetch is recorded as incomplete.
+func (r *result) Close() error {
+	if r.read && !r.finished {
+		r.finish(nil)
+	}
+	return r.client.chainError(r.method, r.chain, r.Filter, r.Filter.Close())
+}
+
+// Formatter is implemented by the results of a Client's methods. Chain
+// returns the name of the chain that produced the result, and Format returns
+// the value of the "Format" parameter of the first link of that chain that has
+// one. Format returns an empty string if no link has the parameter, indicating
+// the usual format of the method. Because a method may fall back to another
+// chain, these are determined once the result has been read.
+type Formatter interface {
+	Chain() string
+	Format() string
+}
+
+// Formats of content produced by chains.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Chain implements Formatter.
+func (r *result) Chain() string {
+	return r.chain
+}
+
+// Format implements Formatter.
+func (r *result) Format() string {
+	return r.client.chainParam(r.chain, "Format")
+}
+
+// chainParam returns the value of the first parameter of the given chain
+// named by key, or an empty string if no link has the parameter.
+func (client *Client) chainParam(chain, key string) string {
+	for _, link := range client.snapshot().chainSet.Config().Chains[chain] {
+		if v := link.Params.GetString(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Timing implements Timer.
+func (r *result) Timing() Timing {
+	return r.timing
+}
@@ -0,0 +1,150 @@
+package rbxfetch
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anaminus/iofl"
+)
+
+// RetryPolicy decides whether a failed request is retried.
+type RetryPolicy interface {
+	// Decide is called after a request fails. attempt is the number of
+	// attempts made so far, starting at 1. err is the error of the attempt.
+	// resp is the response, if one was received, and its body must not be
+	// read. Returns whether the request should be attempted again, and how
+	// long to wait before doing so.
+	Decide(attempt int, err error, resp *http.Response) (retry bool, delay time.Duration)
+}
+
+// BackoffPolicy is a RetryPolicy that retries network errors and server
+// errors with an exponentially increasing delay. Client errors, such as 404,
+// are not retried.
+//
+// Rate-limited responses, with a status of 429 Too Many Requests, or 503
+// Service Unavailable with a Retry-After header, are retried after the delay
+// requested by the Retry-After header, if present, instead of the exponential
+// delay.
+type BackoffPolicy struct {
+	// Attempts is the maximum number of attempts of a request.
+	Attempts int
+	// Delay is the delay before the first retry. Each subsequent delay is
+	// doubled.
+	Delay time.Duration
+	// MaxDelay, if greater than zero, is the maximum delay between attempts.
+	MaxDelay time.Duration
+	// RateLimitAttempts, if greater than Attempts, is the maximum number of
+	// attempts of a request that is rate-limited.
+	RateLimitAttempts int
+	// MaxRetryAfter, if greater than zero, is the longest Retry-After delay
+	// that is honored. A response requesting a longer delay is not retried.
+	// If zero, then DefaultMaxRetryAfter is used.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultMaxRetryAfter is the longest Retry-After delay honored by a
+// BackoffPolicy that does not specify MaxRetryAfter.
+const DefaultMaxRetryAfter = 1 * time.Minute
+
+// DefaultRetryPolicy is the RetryPolicy used by NewClient.
+var DefaultRetryPolicy RetryPolicy = BackoffPolicy{
+	Attempts:          3,
+	Delay:             500 * time.Millisecond,
+	MaxDelay:          10 * time.Second,
+	RateLimitAttempts: 6,
+}
+
+// rateLimited returns whether resp indicates that requests are being
+// rate-limited.
+func rateLimited(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return resp.Header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by the Retry-After header of resp,
+// which is either a number of seconds, or an HTTP date. ok is false if the
+// header is absent or invalid.
+func retryAfter(resp *http.Response) (delay time.Duration, ok bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if delay = time.Until(t); delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
+
+// Decide implements RetryPolicy.
+func (p BackoffPolicy) Decide(attempt int, err error, resp *http.Response) (retry bool, delay time.Duration) {
+	limited := rateLimited(resp)
+	attempts := p.Attempts
+	if limited && p.RateLimitAttempts > attempts {
+		attempts = p.RateLimitAttempts
+	}
+	if attempt >= attempts {
+		return false, 0
+	}
+	if resp != nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return false, 0
+	}
+	if d, ok := retryAfter(resp); ok && limited {
+		max := p.MaxRetryAfter
+		if max <= 0 {
+			max = DefaultMaxRetryAfter
+		}
+		if d > max {
+			return false, 0
+		}
+		return true, d
+	}
+	delay = p.Delay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return true, delay
+}
+
+// applyRetry applies policy to the chain of filters.
+func applyRetry(filter iofl.Filter, policy RetryPolicy) {
+	type retrier interface {
+		iofl.Filter
+		SetRetry(policy RetryPolicy)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(retrier); ok {
+			f.SetRetry(policy)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,157 @@
+package rbxfetch_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/robloxapi/rbxfetch"
+	"github.com/robloxapi/rbxfetch/rbxfetchtest"
+)
+
+func TestBackoffPolicy(t *testing.T) {
+	policy := rbxfetch.BackoffPolicy{
+		Attempts: 4,
+		Delay:    100 * time.Millisecond,
+		MaxDelay: 300 * time.Millisecond,
+	}
+	errNetwork := errors.New("connection reset")
+	tests := []struct {
+		name    string
+		attempt int
+		status  int
+		retry   bool
+		delay   time.Duration
+	}{
+		{name: "network error", attempt: 1, retry: true, delay: 100 * time.Millisecond},
+		{name: "doubled", attempt: 2, retry: true, delay: 200 * time.Millisecond},
+		{name: "capped", attempt: 3, retry: true, delay: 300 * time.Millisecond},
+		{name: "exhausted", attempt: 4, retry: false},
+		{name: "server error", attempt: 1, status: 502, retry: true, delay: 100 * time.Millisecond},
+		{name: "not found", attempt: 1, status: 404, retry: false},
+		{name: "forbidden", attempt: 1, status: 403, retry: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			if tt.status != 0 {
+				resp = &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			}
+			retry, delay := policy.Decide(tt.attempt, errNetwork, resp)
+			if retry != tt.retry || delay != tt.delay {
+				t.Errorf("expected (%v, %v), got (%v, %v)", tt.retry, tt.delay, retry, delay)
+			}
+		})
+	}
+}
+
+// recordingPolicy is a RetryPolicy that retries every failure once, recording
+// the status of each decision.
+type recordingPolicy struct {
+	statuses []int
+}
+
+func (p *recordingPolicy) Decide(attempt int, err error, resp *http.Response) (retry bool, delay time.Duration) {
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	p.statuses = append(p.statuses, status)
+	return attempt < 2, 0
+}
+
+func TestClientRetryPolicy(t *testing.T) {
+	s := rbxfetchtest.NewServer()
+	defer s.Close()
+	s.Fail("setup.rbxcdn.com/versionQTStudio", 500, 404)
+	client := rbxfetchtest.NewTestClient(s)
+	policy := &recordingPolicy{}
+	client.Retry = policy
+
+	// The first request is retried, but the policy declines the second.
+	if _, err := client.Latest(); err == nil {
+		t.Fatal("expected error")
+	} else if status, _ := rbxfetch.StatusCode(err); status != 404 {
+		t.Fatalf("expected status 404, got %v", err)
+	}
+	if len(policy.statuses) != 2 || policy.statuses[0] != 500 || policy.statuses[1] != 404 {
+		t.Errorf("expected decisions for statuses [500 404], got %v", policy.statuses)
+	}
+
+	guid, err := client.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if guid != rbxfetchtest.GUID {
+		t.Errorf("expected %s, got %s", rbxfetchtest.GUID, guid)
+	}
+}
+
+func TestBackoffPolicyRetryAfter(t *testing.T) {
+	policy := rbxfetch.BackoffPolicy{
+		Attempts:          1,
+		Delay:             100 * time.Millisecond,
+		RateLimitAttempts: 3,
+		MaxRetryAfter:     time.Minute,
+	}
+	tests := []struct {
+		name       string
+		attempt    int
+		status     int
+		retryAfter string
+		retry      bool
+		delay      time.Duration
+	}{
+		{name: "seconds", attempt: 1, status: 429, retryAfter: "2", retry: true, delay: 2 * time.Second},
+		{name: "unavailable", attempt: 1, status: 503, retryAfter: "2", retry: true, delay: 2 * time.Second},
+		{name: "past date", attempt: 1, status: 429, retryAfter: "Mon, 02 Jan 2006 15:04:05 GMT", retry: true, delay: 0},
+		{name: "without header", attempt: 2, status: 429, retry: true, delay: 200 * time.Millisecond},
+		{name: "too long", attempt: 1, status: 429, retryAfter: "120", retry: false},
+		{name: "exhausted", attempt: 3, status: 429, retryAfter: "2", retry: false},
+		// Without Retry-After, 503 is an ordinary server error.
+		{name: "not rate limited", attempt: 1, status: 503, retry: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+			retry, delay := policy.Decide(tt.attempt, errors.New(http.StatusText(tt.status)), resp)
+			if retry != tt.retry || delay != tt.delay {
+				t.Errorf("expected (%v, %v), got (%v, %v)", tt.retry, tt.delay, retry, delay)
+			}
+		})
+	}
+}
+
+func TestRateLimitedError(t *testing.T) {
+	s := rbxfetchtest.NewServer()
+	defer s.Close()
+	client := rbxfetchtest.NewTestClient(s)
+	client.Retry = rbxfetch.BackoffPolicy{Attempts: 1, RateLimitAttempts: 3}
+
+	// Rate-limited requests are retried beyond Attempts.
+	s.Fail("setup.rbxcdn.com/versionQTStudio", 429, 503)
+	if _, err := client.Latest(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.ResetRequests()
+	s.Fail("setup.rbxcdn.com/versionQTStudio", 429, 429, 429)
+	_, err := client.Latest()
+	var limited *rbxfetch.RateLimitedError
+	if !errors.As(err, &limited) {
+		t.Fatalf("expected RateLimitedError, got %v", err)
+	}
+	if limited.Attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", limited.Attempts)
+	}
+	if !errors.Is(err, rbxfetch.ErrStatus) {
+		t.Errorf("expected error classified as ErrStatus, got %v", err)
+	}
+	if n := len(s.Requests()); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
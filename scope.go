@@ -0,0 +1,110 @@
+package rbxfetch
+
+import (
+	"io"
+	"strings"
+)
+
+// Scope is a handle to a Client that applies variable overrides to each of
+// its methods. The overrides take precedence over the variables of the
+// client's configuration and Channel. Because a Scope does not modify the
+// client, several Scopes of a single client may be used concurrently, such as
+// to serve requests for different channels.
+type Scope struct {
+	client *Client
+	vars   map[string]string
+}
+
+// WithVars returns a Scope that overrides the given variables. Variable names
+// are case-insensitive.
+func (client *Client) WithVars(vars map[string]string) *Scope {
+	return (&Scope{client: client}).WithVars(vars)
+}
+
+// WithChannel returns a Scope that targets the given deployment channel
+// instead of the client's Channel.
+func (client *Client) WithChannel(channel string) *Scope {
+	return (&Scope{client: client}).WithChannel(channel)
+}
+
+// WithVars returns a copy of the Scope that additionally overrides the given
+// variables.
+func (s *Scope) WithVars(vars map[string]string) *Scope {
+	scope := &Scope{client: s.client, vars: make(map[string]string, len(s.vars)+len(vars))}
+	for name, value := range s.vars {
+		scope.vars[name] = value
+	}
+	for name, value := range vars {
+		scope.vars[strings.ToUpper(name)] = value
+	}
+	return scope
+}
+
+// WithChannel returns a copy of the Scope that targets the given deployment
+// channel.
+func (s *Scope) WithChannel(channel string) *Scope {
+	vars := map[string]string{}
+	channelVars(channel, vars)
+	return s.WithVars(vars)
+}
+
+// Vars returns a copy of the variables overridden by the Scope.
+func (s *Scope) Vars() map[string]string {
+	vars := make(map[string]string, len(s.vars))
+	for name, value := range s.vars {
+		vars[name] = value
+	}
+	return vars
+}
+
+// Latest behaves like Client.Latest with the Scope's variables.
+func (s *Scope) Latest() (guid string, err error) {
+	return s.client.latest("Latest", s.vars)
+}
+
+// Live behaves like Client.Live with the Scope's variables.
+func (s *Scope) Live() (guids []string, err error) {
+	return s.client.live("Live", s.vars)
+}
+
+// Builds behaves like Client.Builds with the Scope's variables.
+func (s *Scope) Builds() (builds []Build, err error) {
+	return s.client.builds("Builds", "Live", "", s.vars)
+}
+
+// APIDump behaves like Client.APIDump with the Scope's variables.
+func (s *Scope) APIDump(guid string) (rc io.ReadCloser, err error) {
+	return s.client.methodVars("APIDump", guid, s.vars)
+}
+
+// FullAPIDump behaves like Client.FullAPIDump with the Scope's variables.
+func (s *Scope) FullAPIDump(guid string) (rc io.ReadCloser, err error) {
+	return s.client.methodVars("FullAPIDump", guid, s.vars)
+}
+
+// ReflectionMetadata behaves like Client.ReflectionMetadata with the Scope's
+// variables.
+func (s *Scope) ReflectionMetadata(guid string) (rc io.ReadCloser, err error) {
+	return s.client.methodVars("ReflectionMetadata", guid, s.vars)
+}
+
+// ClassImages behaves like Client.ClassImages with the Scope's variables.
+func (s *Scope) ClassImages(guid string) (rc io.ReadCloser, err error) {
+	return s.client.methodVars("ClassImages", guid, s.vars)
+}
+
+// StudioImages behaves like Client.StudioImages with the Scope's variables.
+func (s *Scope) StudioImages(guid string) (rc io.ReadCloser, err error) {
+	return s.client.methodVars("StudioImages", guid, s.vars)
+}
+
+// Method behaves like Client.Method with the Scope's variables.
+func (s *Scope) Method(method, guid string) (rc io.ReadCloser, err error) {
+	return s.client.methodVars(method, guid, s.vars)
+}
+
+// ClientSettings behaves like Client.ClientSettings with the Scope's
+// variables.
+func (s *Scope) ClientSettings(application string) (settings Settings, err error) {
+	return s.client.clientSettings(application, s.vars)
+}
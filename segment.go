@@ -0,0 +1,210 @@
+package rbxfetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anaminus/iofl"
+)
+
+// SegmentMinSize is the minimum size of content that is downloaded in
+// segments.
+const SegmentMinSize = 4 << 20
+
+// tempReadCloser is a temporary file that is removed when closed.
+type tempReadCloser struct {
+	*os.File
+}
+
+func (t tempReadCloser) Close() error {
+	err := t.File.Close()
+	os.Remove(t.File.Name())
+	return err
+}
+
+// fileFiller is implemented by a source that can write its content directly
+// to a file, such as a FilterURL that downloads in segments.
+type fileFiller interface {
+	// fillFile writes the entire content to file, which must be empty.
+	// Returns false if the content cannot be written directly, in which case
+	// it is read as usual.
+	fillFile(file *os.File) (ok bool, err error)
+}
+
+// fillFile writes the content to file when it is downloaded in segments, so
+// that the segments need not be copied from a separate temporary file. The
+// content is otherwise left to be read as usual.
+func (f *FilterURL) fillFile(file *os.File) (ok bool, err error) {
+	if f.r != nil || f.err != nil || f.Segments <= 1 || f.offset != 0 {
+		return false, nil
+	}
+	f.file = file
+	defer func() { f.file = nil }()
+	if err = f.open(); err != nil {
+		return false, err
+	}
+	if f.filled == 0 {
+		return false, nil
+	}
+	// The content has been fully written, so nothing remains to be read.
+	f.r.Close()
+	f.r = io.NopCloser(strings.NewReader(""))
+	f.offset += f.filled
+	f.bytes += f.filled
+	f.finished = time.Now()
+	f.done()
+	return true, nil
+}
+
+// segmented downloads url. If the response indicates that the server supports
+// Range requests, and that the content is at least SegmentMinSize, then the
+// first segment is read from the response, and the remainder of the content
+// is downloaded over further concurrent connections, up to f.Segments in
+// total. Otherwise, the content is read from the response as usual.
+//
+// The segments are written to f.file, if set, and otherwise to a temporary
+// file, which is read from once complete.
+func (f *FilterURL) segmented(url string) (rc io.ReadCloser, err error) {
+	resp, err := f.get(url, 0)
+	if err != nil {
+		return nil, err
+	}
+	f.response = time.Now()
+	if f.validator == "" {
+		f.validator = responseValidator(resp)
+	}
+	size := resp.ContentLength
+	if size < SegmentMinSize || resp.Header.Get("Accept-Ranges") != "bytes" {
+		return resp.Body, nil
+	}
+
+	file := f.file
+	if file == nil {
+		if file, err = createTemp(""); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	keep := false
+	defer func() {
+		if !keep && file != f.file {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	n := int64(f.Segments)
+	length := (size + n - 1) / n
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := int64(1); i < n; i++ {
+		start := i * length
+		end := start + length - 1
+		if end >= size {
+			end = size - 1
+		}
+		if start > end {
+			break
+		}
+		wg.Add(1)
+		go func(i, start, end int64) {
+			defer wg.Done()
+			errs[i] = f.segment(file, url, start, end)
+		}(i, start, end)
+	}
+	// The remainder of the response is not needed, so closing it abandons
+	// the connection.
+	errs[0] = writeSegment(file, resp.Body, 0, length-1)
+	resp.Body.Close()
+	// The request of the filter is complete, so its place in the queue is
+	// given to the remaining segments.
+	if f.release != nil {
+		f.release()
+		f.release = nil
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	keep = true
+	if file == f.file {
+		f.filled = size
+		return io.NopCloser(io.NewSectionReader(file, 0, size)), nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		keep = false
+		return nil, err
+	}
+	return tempReadCloser{file}, nil
+}
+
+// segment downloads the bytes of url from start to end, inclusive, writing
+// them to the same offsets of file. Each segment is admitted by the queue
+// separately.
+func (f *FilterURL) segment(file *os.File, url string, start, end int64) error {
+	if f.Queue != nil {
+		release, err := f.Queue(f.context())
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	resp, err := f.getRange(url, start, end)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if s, ok := contentRangeStart(resp); !ok || s != start {
+		return classify(ErrNetwork, errors.New("server ignored range of segment"))
+	}
+	return writeSegment(file, resp.Body, start, end)
+}
+
+// writeSegment writes the bytes from start to end, inclusive, read from body
+// to the same offsets of file.
+func writeSegment(file *os.File, body io.Reader, start, end int64) error {
+	body = io.LimitReader(body, end-start+1)
+	buf := make([]byte, 32*1024)
+	offset := start
+	for offset <= end {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := file.WriteAt(buf[:n], offset); werr != nil {
+				return werr
+			}
+			offset += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return classify(ErrNetwork, err)
+		}
+	}
+	if offset != end+1 {
+		return classify(ErrNetwork, fmt.Errorf("segment %d-%d: short read", start, end))
+	}
+	return nil
+}
+
+// applySegments applies the number of download segments to the chain of
+// filters.
+func applySegments(filter iofl.Filter, n int) {
+	type segmenter interface {
+		iofl.Filter
+		SetSegments(n int)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(segmenter); ok {
+			f.SetSegments(n)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,204 @@
+package rbxfetch
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anaminus/iofl"
+)
+
+// Applications with settings that can be fetched by ClientSettings.
+const (
+	ApplicationPCDesktopClient      = "PCDesktopClient"
+	ApplicationPCStudioApp          = "PCStudioApp"
+	ApplicationPCClientBootstrapper = "PCClientBootstrapper"
+	ApplicationPCStudioBootstrapper = "PCStudioBootstrapper"
+	ApplicationMacDesktopClient     = "MacDesktopClient"
+	ApplicationMacStudioApp         = "MacStudioApp"
+	ApplicationAndroidApp           = "AndroidApp"
+	ApplicationiOSApp               = "iOSApp"
+	ApplicationXboxClient           = "XboxClient"
+)
+
+// Settings maps the name of a fast flag to its raw value.
+type Settings map[string]string
+
+// FlagKind returns the kind of a fast flag, determined by the prefix of its
+// name, such as "FFlag", "DFInt", or "FString". Returns an empty string if the
+// kind cannot be determined.
+func FlagKind(name string) string {
+	for _, prefix := range []string{"DF", "SF", "F"} {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := name[len(prefix):]
+		for _, kind := range []string{"Flag", "Int", "String", "Log"} {
+			if strings.HasPrefix(rest, kind) {
+				return prefix + kind
+			}
+		}
+	}
+	return ""
+}
+
+// Bool returns the value of a flag as a bool. ok is false if the flag is not
+// present or is not a bool.
+func (s Settings) Bool(name string) (v bool, ok bool) {
+	raw, ok := s[name]
+	if !ok {
+		return false, false
+	}
+	v, err := strconv.ParseBool(raw)
+	return v, err == nil
+}
+
+// Int returns the value of a flag as an integer. ok is false if the flag is
+// not present or is not an integer.
+func (s Settings) Int(name string) (v int64, ok bool) {
+	raw, ok := s[name]
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	return v, err == nil
+}
+
+// String returns the value of a flag as a string. ok is false if the flag is
+// not present.
+func (s Settings) String(name string) (v string, ok bool) {
+	v, ok = s[name]
+	return v, ok
+}
+
+// Names returns the names of each flag in s, in sorted order.
+func (s Settings) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SettingChange describes a flag whose value differs between two Settings.
+type SettingChange struct {
+	Name string
+	Prev string
+	Next string
+}
+
+// SettingsDiff describes the differences between two Settings.
+type SettingsDiff struct {
+	// Added maps flags present only in the next Settings to their values.
+	Added Settings
+	// Removed maps flags present only in the previous Settings to their
+	// values.
+	Removed Settings
+	// Changed lists flags present in both, but with different values, sorted
+	// by name.
+	Changed []SettingChange
+}
+
+// Empty returns whether the diff contains no differences.
+func (d SettingsDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSettings returns the differences from prev to next.
+func DiffSettings(prev, next Settings) SettingsDiff {
+	diff := SettingsDiff{Added: Settings{}, Removed: Settings{}}
+	for name, p := range prev {
+		if n, ok := next[name]; !ok {
+			diff.Removed[name] = p
+		} else if n != p {
+			diff.Changed = append(diff.Changed, SettingChange{Name: name, Prev: p, Next: n})
+		}
+	}
+	for name, n := range next {
+		if _, ok := prev[name]; !ok {
+			diff.Added[name] = n
+		}
+	}
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Name < diff.Changed[j].Name
+	})
+	return diff
+}
+
+// ClientSettings returns the fast flags of the given application, such as
+// ApplicationPCStudioApp. ClientSettings uses the result of the first chain
+// that does not error. Returns nil if no "ClientSettings" method is
+// configured.
+//
+// The application is available to chains as the $APPLICATION variable. The
+// content of a chain is expected to be a JSON object containing an
+// "applicationSettings" object that maps flag names to values.
+//
+// The settings of the client's Channel are fetched, which may differ from
+// those of the production channel.
+func (client *Client) ClientSettings(application string) (settings Settings, err error) {
+	return client.clientSettings(application, nil)
+}
+
+// clientSettings returns the fast flags of application, with extra variables
+// passed to each chain.
+func (client *Client) clientSettings(application string, extra map[string]string) (settings Settings, err error) {
+	vars := map[string]string{}
+	for k, v := range extra {
+		vars[k] = v
+	}
+	vars["APPLICATION"] = application
+	for _, chain := range client.chains("ClientSettings") {
+		start := time.Now()
+		var f iofl.Filter
+		if f, err = client.resolveVars("ClientSettings", chain, "", vars); err != nil {
+			continue
+		}
+		var v struct {
+			ApplicationSettings map[string]json.RawMessage `json:"applicationSettings"`
+		}
+		err = json.NewDecoder(client.newResult("ClientSettings", chain, f, false)).Decode(&v)
+		f.Close()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if _, ok := err.(*ChainError); !ok {
+			// Errors of the chain are already classified.
+			err = classify(ErrDecode, err)
+		}
+		client.observe("ClientSettings", chain, start, err)
+		if err != nil {
+			continue
+		}
+		settings = make(Settings, len(v.ApplicationSettings))
+		for name, raw := range v.ApplicationSettings {
+			var s string
+			if json.Unmarshal(raw, &s) != nil {
+				// Retain non-string values in their raw form.
+				s = string(raw)
+			}
+			settings[name] = s
+		}
+		return settings, nil
+	}
+	return nil, err
+}
+
+// DiffChannelSettings returns the differences between the fast flags of
+// application on the prev and next deployment channels, such as "LIVE" and
+// "zcanary", which reveals flags being tested ahead of production.
+func (client *Client) DiffChannelSettings(application, prev, next string) (diff SettingsDiff, err error) {
+	p, err := client.WithChannel(prev).ClientSettings(application)
+	if err != nil {
+		return diff, err
+	}
+	n, err := client.WithChannel(next).ClientSettings(application)
+	if err != nil {
+		return diff, err
+	}
+	return DiffSettings(p, n), nil
+}
@@ -0,0 +1,137 @@
+package rbxfetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sink receives the builds reported by a Watcher, such as to announce new
+// deployments.
+type Sink interface {
+	// Notify is called with each new build.
+	Notify(ctx context.Context, event BuildEvent) error
+}
+
+// SinkFunc is a function that implements Sink.
+type SinkFunc func(ctx context.Context, event BuildEvent) error
+
+// Notify implements Sink.
+func (f SinkFunc) Notify(ctx context.Context, event BuildEvent) error {
+	return f(ctx, event)
+}
+
+// Message returns a short description of the event, suitable for a
+// notification.
+func (e BuildEvent) Message() string {
+	msg := "New build " + e.Build.GUID
+	if e.Build.Version != (Version{}) {
+		msg += " (" + e.Build.Version.String() + ")"
+	}
+	if e.Build.Channel != "" && !isLive(e.Build.Channel) {
+		msg += " on channel " + e.Build.Channel
+	}
+	return msg + " observed from " + e.Source
+}
+
+// WebhookSink is a Sink that posts each event to a URL. By default, the
+// request body is the event encoded as JSON. Format adapts the body to the
+// payload expected by a service; MessagePayload produces a payload suitable
+// for chat services such as Discord or Slack.
+//
+// A failed request is retried according to Retry. Rate-limited responses,
+// such as the 429 Too Many Requests of Discord or Slack, are retried after
+// the delay requested by their Retry-After header.
+type WebhookSink struct {
+	// URL is the URL to which events are posted.
+	URL string
+	// Client is the HTTP client used to make requests. If nil, then
+	// http.DefaultClient is used.
+	Client *http.Client
+	// Header contains headers added to each request.
+	Header http.Header
+	// Format, if not nil, returns the body of the request for an event.
+	Format func(event BuildEvent) (body []byte, err error)
+	// Retry decides whether a failed request is retried. If nil, then
+	// DefaultRetryPolicy is used.
+	Retry RetryPolicy
+}
+
+// MessagePayload returns a Format function for WebhookSink that produces a
+// JSON object with a single field containing the Message of the event. The
+// field is "content" for Discord, and "text" for Slack.
+func MessagePayload(field string) func(event BuildEvent) (body []byte, err error) {
+	return func(event BuildEvent) (body []byte, err error) {
+		return json.Marshal(map[string]string{field: event.Message()})
+	}
+}
+
+// Notify implements Sink.
+func (s *WebhookSink) Notify(ctx context.Context, event BuildEvent) error {
+	var body []byte
+	var err error
+	if s.Format != nil {
+		body, err = s.Format(event)
+	} else {
+		body, err = json.Marshal(event)
+	}
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	policy := s.Retry
+	if policy == nil {
+		policy = DefaultRetryPolicy
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := s.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		retry, delay := policy.Decide(attempt, err, resp)
+		if !retry {
+			if rateLimited(resp) {
+				after, _ := retryAfter(resp)
+				err = &RateLimitedError{URL: s.URL, RetryAfter: after, Attempts: attempt, Err: err}
+			}
+			return fmt.Errorf("webhook: %w", err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("webhook: %w", err)
+		}
+	}
+}
+
+// post makes a single request with body. If the request fails, then resp is
+// the response, if one was received, with its body discarded.
+func (s *WebhookSink) post(ctx context.Context, body []byte) (resp *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range s.Header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", DefaultUserAgent)
+	}
+	c := s.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	if resp, err = c.Do(req); err != nil {
+		return nil, classify(ErrNetwork, err)
+	}
+	discardBody(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, statusError{status: resp.StatusCode, msg: resp.Status}
+	}
+	return resp, nil
+}
@@ -0,0 +1,145 @@
+package rbxfetch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robloxapi/rbxfetch"
+)
+
+func TestWebhookSink(t *testing.T) {
+	event := rbxfetch.BuildEvent{Source: rbxfetch.WatchLive, Build: rbxfetch.Build{GUID: "version-a"}}
+	tests := []struct {
+		name     string
+		statuses []int
+		format   func(event rbxfetch.BuildEvent) ([]byte, error)
+		body     string
+		err      bool
+	}{
+		{name: "event", statuses: []int{204}, body: `"GUID":"version-a"`},
+		{name: "message", statuses: []int{204}, format: rbxfetch.MessagePayload("content"), body: `{"content":"`},
+		{name: "rate limited", statuses: []int{429, 204}, body: `"GUID":"version-a"`},
+		{name: "rejected", statuses: []int{400}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				var body json.RawMessage
+				if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+					t.Errorf("invalid JSON body: %v", err)
+				}
+				bodies = append(bodies, string(body))
+				status := tt.statuses[len(bodies)-1]
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+			}))
+			defer s.Close()
+			sink := &rbxfetch.WebhookSink{
+				URL:    s.URL,
+				Client: s.Client(),
+				Format: tt.format,
+				Retry:  rbxfetch.BackoffPolicy{Attempts: 1, RateLimitAttempts: 2},
+			}
+			err := sink.Notify(context.Background(), event)
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(bodies) != len(tt.statuses) {
+				t.Fatalf("expected %d posts, got %d", len(tt.statuses), len(bodies))
+			}
+			for _, body := range bodies {
+				if !strings.Contains(body, tt.body) {
+					t.Errorf("expected body containing %s, got %s", tt.body, body)
+				}
+			}
+		})
+	}
+}
+
+// countingSource is a WatchSource that reports the same live build on every
+// poll, calling polled with the number of polls so far.
+type countingSource struct {
+	polls  int
+	polled func(n int)
+}
+
+func (s *countingSource) Latest() (guid string, err error) {
+	return "", nil
+}
+
+func (s *countingSource) Live() (guids []string, err error) {
+	s.polls++
+	s.polled(s.polls)
+	return []string{"version-a"}, nil
+}
+
+func (s *countingSource) Builds() (builds []rbxfetch.Build, err error) {
+	return nil, nil
+}
+
+func TestWatcherRenotify(t *testing.T) {
+	errSink := errors.New("sink unavailable")
+	tests := []struct {
+		name     string
+		failures int
+		attempts int
+		errors   int
+		dropped  bool
+	}{
+		{name: "recovered", failures: 1, attempts: 2, errors: 1},
+		{name: "dropped", failures: 10, attempts: 3, errors: 3, dropped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			// Polling continues past the last attempt, so that an attempt
+			// beyond NotifyAttempts would be observed.
+			source := &countingSource{polled: func(n int) {
+				if n > 6 {
+					cancel()
+				}
+			}}
+			attempts := 0
+			var errs []error
+			w := rbxfetch.NewWatcher(source)
+			w.Interval = time.Millisecond
+			w.ReportExisting = true
+			w.NotifyAttempts = 3
+			w.Sinks = []rbxfetch.Sink{rbxfetch.SinkFunc(func(ctx context.Context, event rbxfetch.BuildEvent) error {
+				if attempts++; attempts <= tt.failures {
+					return errSink
+				}
+				return nil
+			})}
+			w.OnError = func(source string, err error) {
+				errs = append(errs, err)
+			}
+			if err := w.Run(ctx, nil); !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected run to be cancelled, got %v", err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("expected %d attempts, got %d", tt.attempts, attempts)
+			}
+			if len(errs) != tt.errors {
+				t.Fatalf("expected %d errors, got %v", tt.errors, errs)
+			}
+			last := errs[len(errs)-1]
+			if !errors.Is(last, errSink) {
+				t.Errorf("expected sink error, got %v", last)
+			}
+			if dropped := strings.Contains(last.Error(), "dropped"); dropped != tt.dropped {
+				t.Errorf("expected dropped %v, got error %v", tt.dropped, last)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package rbxfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// sizer is implemented by filters that can report the size of their content
+// without fetching it.
+type sizer interface {
+	iofl.Filter
+	Size() (size int64, err error)
+}
+
+// cachedSizer is implemented by filters that can report the size of their
+// content when it is cached.
+type cachedSizer interface {
+	iofl.Filter
+	CachedSize() (size int64, ok bool)
+}
+
+// Size returns the size of the content of the URL, as reported by a HEAD
+// request, without fetching the content. The request is made in the same
+// manner as a request for the content, falling back to variants and mirrors.
+// If the server does not allow HEAD requests, then the size is probed with a
+// request for the first byte of the content. Returns -1 if the server does not
+// report the size. As with the content, the request is admitted by Queue, and
+// bounded by Timeout.
+func (f *FilterURL) Size() (size int64, err error) {
+	if f.Queue != nil {
+		release, err := f.Queue(f.context())
+		if err != nil {
+			return -1, err
+		}
+		defer release()
+	}
+	if f.Timeout > 0 {
+		ctx := f.context()
+		var cancel context.CancelFunc
+		f.Context, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer func(ctx context.Context) {
+			cancel()
+			f.Context = ctx
+		}(f.Context)
+	}
+	var last error
+	for i, u := range f.variants() {
+		candidates := []string{u}
+		if i == 0 {
+			candidates = mirrorCandidates(u, f.Mirrors)
+		}
+		for _, candidate := range candidates {
+			n, e := f.size(candidate)
+			if e == nil {
+				return n, nil
+			}
+			if err == nil {
+				err = e
+			}
+			last = e
+		}
+		if !missing(last) {
+			break
+		}
+	}
+	return -1, err
+}
+
+// contentLength returns the total length of the content from the
+// Content-Range header of a partial response. Returns -1 if unknown.
+func contentLength(resp *http.Response) int64 {
+	cr := resp.Header.Get("Content-Range")
+	i := strings.LastIndexByte(cr, '/')
+	if resp.StatusCode != http.StatusPartialContent || i < 0 {
+		return -1
+	}
+	n, err := strconv.ParseInt(cr[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// size returns the size of the content of url.
+func (f *FilterURL) size(url string) (size int64, err error) {
+	resp, err := f.request("HEAD", url, 0, -1)
+	var status interface{ Status() int }
+	if errors.As(err, &status) &&
+		(status.Status() == http.StatusMethodNotAllowed || status.Status() == http.StatusNotImplemented) {
+		if resp, err = f.getRange(url, 0, 0); err != nil {
+			return -1, err
+		}
+		discardBody(resp.Body)
+		if n := contentLength(resp); n >= 0 || resp.StatusCode == http.StatusPartialContent {
+			return n, nil
+		}
+		// Ranges are not supported, so the response has the entire content.
+		return resp.ContentLength, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	resp.Body.Close()
+	return resp.ContentLength, nil
+}
+
+// Size returns the size of the content that would be downloaded by method for
+// guid, without fetching the content, so that the user can be informed, or
+// disk space checked, before a large download. The size is requested by the
+// first link of each chain that can report it, such as a FilterURL, which
+// makes a HEAD request. The size of the first chain that reports it is
+// returned. For chains that extract a file from an archive, this is the size
+// of the archive. Returns -1 if the size is not reported.
+//
+// If a link of the chain, such as a FilterCache, already has its content, then
+// the size of that content is returned without making a request. Of several
+// such links, the first in the chain is used.
+func (client *Client) Size(method, guid string) (size int64, err error) {
+	chains := client.chains(method)
+	if len(chains) == 0 {
+		return -1, fmt.Errorf("method %q is not configured", method)
+	}
+	for _, chain := range chains {
+		var f iofl.Filter
+		if f, err = client.resolveVars(method, chain, guid, nil); err != nil {
+			continue
+		}
+		var s sizer
+		cached := int64(-1)
+		iofl.Apply(f, func(r io.ReadCloser) error {
+			if r, ok := r.(sizer); ok {
+				s = r
+			}
+			if r, ok := r.(cachedSizer); ok {
+				if n, ok := r.CachedSize(); ok {
+					cached = n
+				}
+			}
+			return nil
+		})
+		if cached >= 0 {
+			f.Close()
+			return cached, nil
+		}
+		if s == nil {
+			f.Close()
+			err = fmt.Errorf("chain %s cannot report the size of its content", chain)
+			continue
+		}
+		size, err = s.Size()
+		f.Close()
+		if err == nil {
+			return size, nil
+		}
+	}
+	return -1, err
+}
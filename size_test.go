@@ -0,0 +1,77 @@
+package rbxfetch_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/robloxapi/rbxfetch"
+	"github.com/robloxapi/rbxfetch/rbxfetchtest"
+)
+
+func TestSize(t *testing.T) {
+	s := rbxfetchtest.NewServer()
+	defer s.Close()
+	zip, _ := s.Get("setup.rbxcdn.com/" + rbxfetchtest.GUID + "-RobloxStudio.zip")
+	tests := []struct {
+		method string
+		size   int64
+	}{
+		{method: "APIDump", size: int64(len(rbxfetchtest.APIDump))},
+		// The size of an extracted file is that of its archive.
+		{method: "ReflectionMetadata", size: int64(len(zip))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			client := rbxfetchtest.NewTestClient(s)
+			var methods []string
+			client.Hooks.BeforeRequest = func(req *http.Request) error {
+				methods = append(methods, req.Method)
+				return nil
+			}
+			size, err := client.Size(tt.method, rbxfetchtest.GUID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != tt.size {
+				t.Errorf("expected size %d, got %d", tt.size, size)
+			}
+			if len(methods) != 1 || methods[0] != http.MethodHead {
+				t.Errorf("expected a single HEAD request, got %q", methods)
+			}
+		})
+	}
+}
+
+func TestSizeCached(t *testing.T) {
+	s := rbxfetchtest.NewServer()
+	defer s.Close()
+	client := rbxfetchtest.NewTestClient(s)
+	client.CacheMode = rbxfetch.CacheCustom
+	client.CacheLocation = t.TempDir()
+	// Cache a different size than the server reports, as though the content
+	// was cached from elsewhere.
+	const cached = `{"Version":1,"Classes":[],"Enums":[]}`
+	s.Set("setup.rbxcdn.com/"+rbxfetchtest.GUID+"-API-Dump.json", []byte(cached))
+	rc, err := client.Method("APIDump", rbxfetchtest.GUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Set("setup.rbxcdn.com/"+rbxfetchtest.GUID+"-API-Dump.json", []byte(rbxfetchtest.APIDump))
+	s.ResetRequests()
+	size, err := client.Size("APIDump", rbxfetchtest.GUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(cached)) {
+		t.Errorf("expected size %d of cached content, got %d", len(cached), size)
+	}
+	if requests := s.Requests(); len(requests) != 0 {
+		t.Errorf("expected no requests, got %q", requests)
+	}
+}
@@ -0,0 +1,153 @@
+package rbxfetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/anaminus/iofl"
+)
+
+// StudioVersionsDir returns the directory in which Studio installs each build
+// on the current system, or an empty string if the location is not known. On
+// Windows, this is "%LOCALAPPDATA%\Roblox\Versions", which contains a
+// directory named after the GUID of each installed build.
+func StudioVersionsDir() string {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, "Roblox", "Versions")
+		}
+	}
+	return ""
+}
+
+// FilterStudio is an iofl.Filter that fetches a file from a build of Studio
+// installed on the local system. Root is the directory that contains the
+// installed builds, or empty to use StudioVersionsDir. File is the path of
+// the file within the directory of the build, with slash separators, such as
+// "content/textures/ClassImages.PNG". Both may contain variables such as
+// $GUID.
+//
+// If the build is not installed, then the returned error wraps
+// os.ErrNotExist.
+type FilterStudio struct {
+	Root string
+	File string
+	GUID string
+	Vars map[string]string
+
+	r   io.ReadCloser
+	err error
+}
+
+// NewFilterStudio is an iofl.NewFilter that returns a FilterStudio.
+func NewFilterStudio(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error) {
+	return &FilterStudio{r: r,
+		Root: params.GetString("Root"),
+		File: params.GetString("File"),
+	}, nil
+}
+
+func (f *FilterStudio) SetGUID(guid string) {
+	f.GUID = guid
+}
+
+func (f *FilterStudio) SetVars(vars map[string]string) {
+	f.Vars = vars
+}
+
+func (f *FilterStudio) Source() io.ReadCloser {
+	return f.r
+}
+
+func (f *FilterStudio) Failure() error {
+	return failure(f.err)
+}
+
+func (f *FilterStudio) Close() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.r == nil {
+		// Nothing was opened.
+		f.err = iofl.Closed
+		return nil
+	}
+	if f.err = f.r.Close(); f.err == nil {
+		f.err = iofl.Closed
+		return nil
+	}
+	return f.err
+}
+
+// open opens the file within the directory of the installed build.
+func (f *FilterStudio) open() (io.ReadCloser, error) {
+	root := expandVars(f.Root, f.GUID, f.Vars)
+	if root == "" {
+		if root = StudioVersionsDir(); root == "" {
+			return nil, fmt.Errorf("studio: no installation directory on %s: %w", runtime.GOOS, os.ErrNotExist)
+		}
+	}
+	if f.GUID == "" {
+		return nil, errors.New("studio: GUID required")
+	}
+	dir := filepath.Join(root, f.GUID)
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("studio: build %s is not installed: %w", f.GUID, err)
+	}
+	return os.Open(filepath.Join(dir, filepath.FromSlash(expandVars(f.File, f.GUID, f.Vars))))
+}
+
+func (f *FilterStudio) Read(p []byte) (n int, err error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.r == nil {
+		if f.r, err = f.open(); err != nil {
+			f.err = err
+			return 0, err
+		}
+	}
+	return f.r.Read(p)
+}
+
+// studioChains returns the chains that fetch the content of methods from the
+// installed builds within root.
+func studioChains(root string) map[string]iofl.Chain {
+	return map[string]iofl.Chain{
+		// Studio does not include the API dump, but writes it to the
+		// directory of the build when run with the -API option.
+		"APIDump": {
+			{Filter: "studio", Params: iofl.Params{"Root": root, "File": "API-Dump.json"}},
+		},
+		"ReflectionMetadata": {
+			{Filter: "studio", Params: iofl.Params{"Root": root, "File": "ReflectionMetadata.xml"}},
+		},
+		"ReflectionMetadataClasses": {
+			{Filter: "studio", Params: iofl.Params{"Root": root, "File": "ReflectionMetadata.xml"}},
+			{Filter: "xmlpath", Params: iofl.Params{"Path": "roblox/Item[@class=ReflectionMetadataClasses]"}},
+		},
+		"ClassImages": {
+			{Filter: "studio", Params: iofl.Params{"Root": root, "File": "content/textures/ClassImages.PNG"}},
+		},
+	}
+}
+
+// PreferStudio configures the client to fetch the API dump, reflection
+// metadata, and class images of a build from the installation of Studio on the
+// local system, if the build is installed, falling back to the existing chains
+// otherwise. This produces content that matches exactly the installed Studio,
+// without accessing the network. Root is the directory containing installed
+// builds, or empty to use StudioVersionsDir.
+//
+// For each method, a chain named "<method>Local:<root>" is defined and
+// prepended to the method, unless it already exists.
+func (client *Client) PreferStudio(root string) error {
+	config := client.Config()
+	preferChains(config, "Local:"+root, studioChains(root))
+	return client.SetConfig(config)
+}
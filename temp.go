@@ -0,0 +1,85 @@
+package rbxfetch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempPrefix is the prefix of the names of temporary files created by the
+// package. Files with this prefix are removed by SweepTemp.
+const tempPrefix = "rbxfetch-temp-"
+
+// createTemp creates a temporary file within dir. If dir is empty, then the
+// default temporary directory is used.
+func createTemp(dir string) (*os.File, error) {
+	return os.CreateTemp(dir, tempPrefix)
+}
+
+// sweepDir removes temporary files within dir that were last modified before
+// cutoff. Returns the number of files removed.
+func sweepDir(dir string, cutoff time.Time) (removed int, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), tempPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// SweepTemp removes orphaned temporary files that were last modified longer
+// than age ago. Such files are normally removed when a fetch completes, but
+// may remain if the process is terminated. Both the client's cache directory
+// and the default temporary directory are swept. Returns the number of files
+// removed.
+func (client *Client) SweepTemp(age time.Duration) (removed int, err error) {
+	cutoff := time.Now().Add(-age)
+	dirs := []string{os.TempDir()}
+	if dir := cacheDir(client.CacheMode, client.CacheLocation); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		n, e := sweepDir(dir, cutoff)
+		removed += n
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return removed, err
+}
+
+// StartSweeper calls SweepTemp with age every interval, until the returned
+// function is called.
+func (client *Client) StartSweeper(interval, age time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				client.SweepTemp(age)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
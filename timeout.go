@@ -0,0 +1,67 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/anaminus/iofl"
+)
+
+// getDuration returns the value of key from params as a duration. The value
+// may be a string parsed by time.ParseDuration, such as "30s", or a number of
+// seconds. Returns zero if the parameter is not present.
+func getDuration(params iofl.Params, key string) (time.Duration, error) {
+	switch v := params[key].(type) {
+	case nil:
+		return 0, nil
+	case string:
+		if v == "" {
+			return 0, nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("parameter %s: %w", key, err)
+		}
+		return d, nil
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	case time.Duration:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("parameter %s must be a duration string or a number of seconds", key)
+	}
+}
+
+// timeoutError wraps an error caused by a fetch exceeding its timeout, so that
+// it is detected as ErrTimeout.
+type timeoutError struct {
+	timeout time.Duration
+	err     error
+}
+
+func (e *timeoutError) Error() string {
+	return "exceeded timeout of " + e.timeout.String() + ": " + e.err.Error()
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// applyTimeout applies a timeout to the chain of filters.
+func applyTimeout(filter iofl.Filter, d time.Duration) {
+	type timeouter interface {
+		iofl.Filter
+		SetTimeout(d time.Duration)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(timeouter); ok {
+			f.SetTimeout(d)
+		}
+		return nil
+	})
+}
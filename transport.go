@@ -0,0 +1,131 @@
+package rbxfetch
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultMaxConnsPerHost is the number of connections per host used by
+// BulkTransport when no limit is given.
+const DefaultMaxConnsPerHost = 16
+
+// drainLimit is the maximum number of bytes read from the body of a response
+// that is discarded, so that the connection can be reused. Bodies larger than
+// this are closed without draining, which closes the connection.
+const drainLimit = 64 << 10
+
+// BulkTransport returns an http.Transport tuned for fetching many artifacts
+// from a small number of CDN hosts. Connections are kept alive and pooled,
+// with up to maxConnsPerHost connections to each host, all of which are
+// retained when idle, so that successive requests reuse connections instead
+// of dialing new ones. HTTP/2 is attempted, and responses are transparently
+// decompressed. If maxConnsPerHost is zero or less, then
+// DefaultMaxConnsPerHost is used.
+//
+// The default transport retains only two idle connections per host, so a bulk
+// crawl with more concurrency than that continually dials and abandons
+// connections, which may exhaust the ephemeral ports of the system. The
+// returned transport can be used by setting the Client field of a Client:
+//
+//	client.Client = &http.Client{Transport: rbxfetch.BulkTransport(0)}
+func BulkTransport(maxConnsPerHost int) *http.Transport {
+	if maxConnsPerHost <= 0 {
+		maxConnsPerHost = DefaultMaxConnsPerHost
+	}
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          4 * maxConnsPerHost,
+		MaxIdleConnsPerHost:   maxConnsPerHost,
+		MaxConnsPerHost:       maxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+	}
+}
+
+// discardBody closes the body of a response that will not be read. Small
+// remainders are drained first, so that the connection can be reused.
+func discardBody(body io.ReadCloser) error {
+	io.CopyN(io.Discard, body, drainLimit)
+	return body.Close()
+}
+
+// httpClientCache retains the HTTP client derived from the configuration of a
+// Client, so that connections are reused between requests.
+type httpClientCache struct {
+	mu     sync.Mutex
+	key    string
+	client *http.Client
+}
+
+// httpClient returns the HTTP client used to make requests. If the client
+// configures a proxy or TLS, then the returned client is derived from Client
+// with a copy of its transport that applies the configuration.
+func (client *Client) httpClient() *http.Client {
+	if client.Proxy == "" && len(client.HostProxies) == 0 && client.TLSConfig == nil {
+		return client.Client
+	}
+	base := client.Client
+	if base == nil {
+		base = http.DefaultClient
+	}
+	transport, ok := base.Transport.(*http.Transport)
+	if base.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok {
+		// The transport cannot be configured.
+		return client.Client
+	}
+	key := fmt.Sprintf("%p|%p|%p|%s", base, transport, client.TLSConfig, client.proxyKey())
+	cache := &client.derived
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.client != nil && cache.key == key {
+		return cache.client
+	}
+	derived := *base
+	t := transport.Clone()
+	if client.Proxy != "" || len(client.HostProxies) > 0 {
+		t.Proxy = client.proxyFunc()
+	}
+	if client.TLSConfig != nil {
+		t.TLSClientConfig = client.TLSConfig.Clone()
+	}
+	derived.Transport = t
+	cache.key, cache.client = key, &derived
+	return cache.client
+}
+
+// CertPoolFromFiles returns a pool containing the system's root certificates,
+// along with the PEM-encoded certificates of each of the given files, such as
+// the certificate authority of a TLS-intercepting proxy, or of a private
+// mirror. The pool may be used as the RootCAs of Client.TLSConfig.
+func CertPoolFromFiles(paths ...string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("%s: no certificates found", path)
+		}
+	}
+	return pool, nil
+}
@@ -1,35 +1,155 @@
 package rbxfetch
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
-	"net/url"
+	neturl "net/url"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/anaminus/iofl"
 )
 
 // FilterURL is an iofl.Filter that fetches from a URL.
+//
+// Variants lists alternative URLs of the same content, such as the URLs used
+// by the CDN in earlier eras, which differ in the casing of file names or the
+// presence of a channel prefix. Should the URL respond with 403 Forbidden or
+// 404 Not Found, then each variant is requested in order, until one succeeds
+// or fails with another status or error. Other failures of the URL are
+// returned without requesting variants. Each variant is requested once,
+// without mirrors or retries. Variants may contain variables such as $GUID.
+// Variants that expand to a URL already attempted are skipped. The error of the
+// URL is returned if every variant fails.
+//
+// If the URL begins with one of the base URLs in Mirrors, then, should the
+// request fail, the request is attempted with the base URL substituted with
+// each other mirror, in order. The error of the original request is returned if
+// every mirror fails.
+//
+// If reading the response fails partway through, then the request is resumed
+// from the failed offset using a Range request, up to Resume times. The range
+// is requested only if the content has not changed since, as determined by the
+// ETag or Last-Modified header of the response, and fails otherwise. Content
+// without either header, and requests whose Context is done, are not resumed.
+//
+// If Retry is not nil, then it decides whether each failed request is
+// attempted again. Of the mirrors, only requests to the last are attempted
+// again. Waiting between attempts stops once Context is done.
+//
+// DefaultHeader specifies headers added to each request, such as User-Agent.
+// Header and Cookie specify headers and cookies added to each request after
+// DefaultHeader. Their values may contain variables such as $GUID. If
+// Credentials is not nil, then it authorizes each request after the headers
+// and cookies are added.
+//
+// If Rewrite is not nil, then it is called with each URL before it is
+// requested, and may modify the URL, such as to add a token to the query. The
+// request fails if it returns an error.
+//
+// If Gate is not nil, then it is called with each URL before it is requested,
+// after it has been rewritten, and with the URL of each redirect. The request
+// fails if it returns an error.
+//
+// If Context is not nil, then it is the context of each request, allowing the
+// request to be cancelled.
+//
+// If Timeout is greater than zero, then it bounds the time taken to fetch and
+// read the content, including retries, resumes, and mirrors. Exceeding the
+// timeout fails the filter with an error detected as ErrTimeout. The Timeout
+// parameter may be a duration string such as "30s", or a number of seconds.
+//
+// Hooks are called around each request, after the request has been gated.
+//
+// If Segments is greater than 1, and the response indicates that the server
+// supports Range requests, then content larger than SegmentMinSize is
+// downloaded over Segments concurrent connections, the first of which is the
+// response itself. The segments are written into a temporary file, which is
+// read from once complete. When the filter is the source of a FilterCache,
+// the segments are written directly into the file of the cache instead.
+//
+// If Queue is not nil, then it is called with Context before the content is
+// requested, and the returned function is called once the content has been
+// fully read, or the filter is closed. Each additional segment is admitted by
+// Queue separately. The filter fails with the error of Queue, such as when
+// Context is done while waiting. Time spent waiting does not count against
+// Timeout.
 type FilterURL struct {
-	URL           string
-	GUID          string
-	Client        *http.Client
-	CacheMode     CacheMode
-	CacheLocation string
+	URL      string
+	Variants []string
+	GUID     string
+	Vars     map[string]string
+	Client   *http.Client
+	Mirrors  []string
+	Resume   int
+	Segments int
+	Retry    RetryPolicy
+	Queue    func(ctx context.Context) (release func(), err error)
+	Context  context.Context
+	Timeout  time.Duration
 
-	r   io.ReadCloser
-	err error
+	DefaultHeader http.Header
+	Header        map[string]string
+	Cookie        map[string]string
+	Credentials   CredentialProvider
+	Rewrite       func(u *neturl.URL) error
+	Gate          func(url string) error
+	Hooks         RequestHooks
+
+	r       io.ReadCloser
+	err     error
+	release func()
+	cancel  context.CancelFunc
+
+	// url is the URL from which the content is being read.
+	url string
+	// offset is the number of bytes read from the response.
+	offset int64
+	// failed is the error that interrupted reading the response.
+	failed error
+	// resumes is the number of times the response has been resumed.
+	resumes int
+	// failover indicates that failed requests are not retried, because
+	// another mirror remains to be attempted.
+	failover bool
+	// validator identifies the version of the content, and restart is called
+	// when the content preceding the offset turns out to have changed.
+	validator string
+	restart   func() error
+	// requests is the number of requests made, accessed atomically.
+	requests int64
+	// bytes is the number of bytes read from responses.
+	bytes int64
+	// file is the file to which segments are written, and filled is the
+	// number of bytes written to it.
+	file   *os.File
+	filled int64
+
+	start    time.Time
+	response time.Time
+	finished time.Time
 }
 
 // NewFilterURL is an iofl.NewFilter that returns a FilterURL.
 func NewFilterURL(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error) {
+	timeout, err := getDuration(params, "Timeout")
+	if err != nil {
+		return nil, err
+	}
 	return &FilterURL{r: r,
-		URL: params.GetString("URL"),
+		URL:      params.GetString("URL"),
+		Variants: getStrings(params, "Variants"),
+		Resume:   params.GetInt("Resume"),
+		Segments: params.GetInt("Segments"),
+		Timeout:  timeout,
+		Header:   getStringMap(params, "Header"),
+		Cookie:   getStringMap(params, "Cookie"),
 	}, nil
 }
 
@@ -37,23 +157,126 @@ func (f *FilterURL) SetGUID(guid string) {
 	f.GUID = guid
 }
 
+func (f *FilterURL) SetVars(vars map[string]string) {
+	f.Vars = vars
+}
+
+func (f *FilterURL) SetMirrors(mirrors []string) {
+	f.Mirrors = mirrors
+}
+
+func (f *FilterURL) SetResume(n int) {
+	if n > f.Resume {
+		f.Resume = n
+	}
+}
+
+func (f *FilterURL) SetSegments(n int) {
+	if n > f.Segments {
+		f.Segments = n
+	}
+}
+
+// SetOffset sets the offset from which the content is read, before any
+// content has been read. The content is requested using a Range request.
+func (f *FilterURL) SetOffset(offset int64) {
+	if f.r == nil {
+		f.offset = offset
+	}
+}
+
+// SetValidator sets the validator of the content preceding the offset, as
+// previously returned by Validator, before any content has been read. The
+// range is then requested only if the content has not changed. If it has,
+// then restart is called, and the content is read from the beginning.
+func (f *FilterURL) SetValidator(validator string, restart func() error) {
+	if f.r == nil {
+		f.validator = validator
+		f.restart = restart
+	}
+}
+
+// Validator returns the ETag or Last-Modified header of the response, which
+// identifies the version of the content. Returns an empty string if the
+// content has not been requested, or the response has neither header.
+func (f *FilterURL) Validator() string {
+	return f.validator
+}
+
+func (f *FilterURL) SetRetry(policy RetryPolicy) {
+	f.Retry = policy
+}
+
+func (f *FilterURL) SetQueue(acquire func(ctx context.Context) (release func(), err error)) {
+	f.Queue = acquire
+}
+
+func (f *FilterURL) SetCredentials(provider CredentialProvider) {
+	f.Credentials = provider
+}
+
+func (f *FilterURL) SetRewrite(rewrite func(u *neturl.URL) error) {
+	f.Rewrite = rewrite
+}
+
+func (f *FilterURL) SetGate(gate func(url string) error) {
+	f.Gate = gate
+}
+
+func (f *FilterURL) SetDefaultHeader(header http.Header) {
+	f.DefaultHeader = header
+}
+
+func (f *FilterURL) SetHooks(hooks RequestHooks) {
+	f.Hooks = hooks
+}
+
+func (f *FilterURL) SetContext(ctx context.Context) {
+	f.Context = ctx
+}
+
+func (f *FilterURL) SetTimeout(d time.Duration) {
+	f.Timeout = d
+}
+
 func (f *FilterURL) SetClient(client *http.Client) {
 	f.Client = client
 }
 
-func (f *FilterURL) SetCache(mode CacheMode, loc string) {
-	f.CacheMode = mode
-	f.CacheLocation = loc
+// NetworkTiming returns the time at which the request was made, the time at
+// which the response was received, and the time at which the response was
+// fully read.
+func (f *FilterURL) NetworkTiming() (start, response, done time.Time) {
+	return f.start, f.response, f.finished
+}
+
+// NetworkStats returns the number of requests made, and the number of bytes
+// read from responses.
+func (f *FilterURL) NetworkStats() (requests int, bytes int64) {
+	return int(atomic.LoadInt64(&f.requests)), f.bytes
 }
 
 func (f *FilterURL) Source() io.ReadCloser {
 	return f.r
 }
 
+func (f *FilterURL) Failure() error {
+	if err := failure(f.err); err != nil {
+		return err
+	}
+	return f.failed
+}
+
 func (f *FilterURL) Close() error {
+	f.done()
 	if f.err != nil {
 		return f.err
 	}
+	if f.r == nil {
+		// Nothing was fetched.
+		f.err = iofl.Closed
+		return nil
+	}
 	if f.err = f.r.Close(); f.err == nil {
 		f.err = iofl.Closed
 		return nil
@@ -77,115 +300,344 @@ func (s statusError) Status() int {
 func hasStatusError(resp *http.Response) error {
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		err := statusError{status: resp.StatusCode, msg: resp.Status}
-		return fmt.Errorf("download from %s: %w", resp.Request.URL.String(), err)
+		return classify(ErrStatus, fmt.Errorf("download from %s: %w", resp.Request.URL.String(), err))
 	}
 	return nil
 }
 
 func (f *FilterURL) download(url string) (rc io.ReadCloser, err error) {
-	c := f.Client
-	if c == nil {
-		c = http.DefaultClient
+	f.start = time.Now()
+	if f.Segments > 1 && f.offset == 0 {
+		return f.segmented(url)
 	}
-	resp, err := c.Get(url)
+	resp, err := f.get(url, f.offset)
 	if err != nil {
 		return nil, err
 	}
-	if err := hasStatusError(resp); err != nil {
-		return nil, err
+	f.response = time.Now()
+	if f.offset > 0 {
+		if err := f.continues(resp, f.restart); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	if f.validator == "" {
+		f.validator = responseValidator(resp)
 	}
 	return resp.Body, nil
 }
 
-const cacheDirName = "roblox-fetch"
+// responseValidator returns the strong ETag of resp, or its Last-Modified
+// header if it has no strong ETag.
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// contentRangeStart returns the position of the first byte in the
+// Content-Range header of a partial response.
+func contentRangeStart(resp *http.Response) (start int64, ok bool) {
+	cr := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	i := strings.IndexByte(cr, '-')
+	if resp.StatusCode != http.StatusPartialContent || i < 0 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(cr[:i], 10, 64)
+	return start, err == nil
+}
 
-func expandGUID(s, guid string) string {
-	return os.Expand(s, func(v string) string {
-		switch strings.ToLower(v) {
-		case "guid":
-			return guid
+// continues prepares resp, the response to a request for the content from
+// f.offset, to continue the content preceding the offset. If the content has
+// changed, and restart is not nil, then restart is called and the content is
+// read from the beginning. Otherwise, an error is returned.
+func (f *FilterURL) continues(resp *http.Response, restart func() error) error {
+	if resp.StatusCode == http.StatusPartialContent {
+		if start, ok := contentRangeStart(resp); !ok || start != f.offset {
+			return classify(ErrNetwork, fmt.Errorf("download from %s: range %q does not begin at offset %d", resp.Request.URL, resp.Header.Get("Content-Range"), f.offset))
+		}
+		return nil
+	}
+	if f.validator == "" {
+		// The range was ignored; skip to the offset.
+		if _, err := io.CopyN(io.Discard, resp.Body, f.offset); err != nil {
+			return classify(ErrNetwork, err)
 		}
-		return ""
-	})
+		return nil
+	}
+	// The condition of If-Range failed, so the entire content was returned.
+	if restart == nil {
+		return classify(ErrNetwork, fmt.Errorf("download from %s: content changed", resp.Request.URL))
+	}
+	if err := restart(); err != nil {
+		return err
+	}
+	f.offset = 0
+	f.validator = responseValidator(resp)
+	return nil
+}
+
+// get requests url, starting from offset.
+func (f *FilterURL) get(url string, offset int64) (resp *http.Response, err error) {
+	return f.getRange(url, offset, -1)
+}
+
+// getRange requests the bytes of url from start to end, inclusive. If end is
+// less than zero, then the range extends to the end of the content. If the
+// range covers the entire content, then no range is requested.
+func (f *FilterURL) getRange(url string, start, end int64) (resp *http.Response, err error) {
+	return f.request("GET", url, start, end)
 }
 
-func (f *FilterURL) fetch() (rc io.ReadCloser, err error) {
-	u := expandGUID(f.URL, f.GUID)
-	loc, err := url.Parse(u)
+// request makes a request of the given method for the bytes of url from start
+// to end, as with getRange.
+func (f *FilterURL) request(method, url string, start, end int64) (resp *http.Response, err error) {
+	c := f.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	if f.Gate != nil {
+		c = gatedClient(c, f.Gate)
+	}
+	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
 		return nil, err
 	}
-
-	var cacheDir string
-	var cachedFilePath string
-	var downloaded bool
-
-	switch f.CacheMode {
-	case CacheTemp:
-		cacheDir = filepath.Join(os.TempDir(), cacheDirName)
-	case CachePerm:
-		dir, err := os.UserCacheDir()
-		if err != nil {
-			dir = os.TempDir()
+	if f.Context != nil {
+		req = req.WithContext(f.Context)
+	}
+	if f.Rewrite != nil {
+		if err := f.Rewrite(req.URL); err != nil {
+			return nil, err
+		}
+		req.Host = req.URL.Host
+	}
+	if f.Gate != nil {
+		if err := f.Gate(req.URL.String()); err != nil {
+			return nil, err
 		}
-		cacheDir = filepath.Join(dir, cacheDirName)
-	case CacheCustom:
-		cacheDir = f.CacheLocation
-	default:
-		goto direct
 	}
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+	if end >= 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(end, 10))
+	} else if start > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(start, 10)+"-")
+	}
+	if req.Header.Get("Range") != "" && f.validator != "" {
+		req.Header.Set("If-Range", f.validator)
+	}
+	for name, values := range f.DefaultHeader {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	for name, value := range f.Header {
+		req.Header.Set(name, expandVars(value, f.GUID, f.Vars))
+	}
+	for name, value := range f.Cookie {
+		req.AddCookie(&http.Cookie{Name: name, Value: expandVars(value, f.GUID, f.Vars)})
+	}
+	if f.Credentials != nil {
+		if err := f.Credentials.Authorize(req); err != nil {
+			return nil, err
+		}
+	}
+	if req, err = f.Hooks.before(req); err != nil {
 		return nil, err
 	}
-	cachedFilePath = filepath.Join(cacheDir, url.PathEscape(loc.Host+loc.Path))
+	for attempt := 1; ; attempt++ {
+		atomic.AddInt64(&f.requests, 1)
+		resp, err = c.Do(req)
+		f.Hooks.after(req, resp, err)
+		if errors.Is(err, ErrForbidden) {
+			// A redirect was not permitted by Gate.
+			return nil, err
+		} else if err != nil {
+			err = classify(ErrNetwork, err)
+		} else if err = hasStatusError(resp); err == nil {
+			return resp, nil
+		} else if f.expired(req.URL, resp) {
+			err = &ExpiredBuildError{GUID: f.GUID, URL: req.URL.String(), Err: err}
+		}
+		retry := false
+		var delay time.Duration
+		if f.Retry != nil && !f.failover {
+			retry, delay = f.Retry.Decide(attempt, err, resp)
+		}
+		if resp != nil {
+			discardBody(resp.Body)
+		}
+		if !retry {
+			if rateLimited(resp) {
+				after, _ := retryAfter(resp)
+				err = &RateLimitedError{URL: req.URL.String(), RetryAfter: after, Attempts: attempt, Err: err}
+			}
+			return nil, err
+		}
+		ctx := f.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
 
-tryCache:
-	if cachedFile, err := os.Open(cachedFilePath); err == nil {
-		return cachedFile, nil
+// expired returns whether resp indicates that the build of the filter has
+// expired. The deployment CDN responds to requests for the files of builds it
+// no longer retains with 403 Forbidden, so such a response to a URL containing
+// the GUID of the filter is presumed to be an expired build rather than a
+// problem with authorization.
+func (f *FilterURL) expired(u *neturl.URL, resp *http.Response) bool {
+	return resp.StatusCode == http.StatusForbidden &&
+		f.GUID != "" &&
+		strings.Contains(strings.ToLower(u.Path), strings.ToLower(f.GUID))
+}
+
+// resume attempts to reconnect to the current URL, continuing from the current
+// offset. Content without a validator cannot be resumed, since a change to the
+// content could not be detected.
+func (f *FilterURL) resume() error {
+	if f.validator == "" {
+		return errors.New("content cannot be resumed")
 	}
+	resp, err := f.get(f.url, f.offset)
+	if err != nil {
+		return err
+	}
+	// Content has already been read, so the request cannot be restarted.
+	if err := f.continues(resp, nil); err != nil {
+		resp.Body.Close()
+		return err
+	}
+	f.r.Close()
+	f.r = resp.Body
+	return nil
+}
 
-	if !downloaded {
-		if tempFile, err := ioutil.TempFile(cacheDir, "temp"); err == nil {
-			tempName := tempFile.Name()
+// resumable returns whether the content may be resumed after a failure.
+func (f *FilterURL) resumable() bool {
+	return f.resumes < f.Resume && (f.Context == nil || f.Context.Err() == nil)
+}
 
-			// Download response body.
-			rc, err := f.download(u)
-			if err != nil {
-				tempFile.Close()
-				os.Remove(tempFile.Name())
-				return nil, err
-			}
+// fetch downloads from u, falling back to mirrors. Only the last mirror is
+// retried, so that the delays of the retry policy are not multiplied by the
+// number of mirrors.
+func (f *FilterURL) fetch(u string) (rc io.ReadCloser, err error) {
+	candidates := mirrorCandidates(u, f.Mirrors)
+	defer func() { f.failover = false }()
+	for i, candidate := range candidates {
+		f.failover = i < len(candidates)-1
+		r, e := f.download(candidate)
+		if e == nil {
+			f.url = candidate
+			return r, nil
+		}
+		if i == 0 {
+			err = e
+		}
+	}
+	return nil, err
+}
 
-			// Write to temp file.
-			_, err = io.Copy(tempFile, rc)
-			rc.Close()
-			if err != nil {
-				tempFile.Close()
-				os.Remove(tempFile.Name())
-				return nil, err
-			}
+// variants returns the expanded URL followed by each distinct expanded
+// variant.
+func (f *FilterURL) variants() (urls []string) {
+	for _, u := range append([]string{f.URL}, f.Variants...) {
+		u = expandVars(u, f.GUID, f.Vars)
+		if !containsString(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
 
-			// Sync temp file.
-			err = tempFile.Sync()
-			tempFile.Close()
-			if err != nil {
-				os.Remove(tempFile.Name())
-				return nil, err
-			}
-			downloaded = true
+// missing returns whether err indicates that the content does not exist at
+// the requested URL, rather than a transient failure.
+func missing(err error) bool {
+	var s statusError
+	if !errors.As(err, &s) {
+		return false
+	}
+	return s.status == http.StatusNotFound || s.status == http.StatusForbidden
+}
 
-			// Attempt to relocate temp file to cache file.
-			if err := os.Rename(tempName, cachedFilePath); err != nil {
-				// Rename failed. Data is still in temp file, so we'll reuse that.
-				cachedFilePath = tempName
-			}
-			goto tryCache
+// fetchVariants downloads from the URL, falling back to each variant while
+// the content is missing.
+func (f *FilterURL) fetchVariants() (rc io.ReadCloser, err error) {
+	urls := f.variants()
+	if rc, err = f.fetch(urls[0]); err == nil || !missing(err) {
+		return rc, err
+	}
+	// Variants are not retried, so that a missing file does not multiply
+	// requests.
+	f.failover = true
+	defer func() { f.failover = false }()
+	for _, u := range urls[1:] {
+		r, e := f.download(u)
+		if e == nil {
+			f.url = u
+			return r, nil
+		}
+		if !missing(e) {
+			break
 		}
 	}
+	return nil, err
+}
 
-direct:
-	// Return response body directly.
-	return f.download(u)
+// done releases the request from the queue, and releases the resources of
+// the timeout.
+func (f *FilterURL) done() {
+	if f.release != nil {
+		f.release()
+		f.release = nil
+	}
+	if f.cancel != nil {
+		f.cancel()
+	}
+}
+
+// timedOut wraps err as a timeout error if the timeout has been exceeded.
+func (f *FilterURL) timedOut(err error) error {
+	if err == nil || err == io.EOF || f.cancel == nil {
+		return err
+	}
+	if f.Context.Err() != context.DeadlineExceeded {
+		return err
+	}
+	return &timeoutError{timeout: f.Timeout, err: err}
+}
+
+// context returns Context, or the background context if Context is nil.
+func (f *FilterURL) context() context.Context {
+	if f.Context == nil {
+		return context.Background()
+	}
+	return f.Context
+}
+
+// open requests the content.
+func (f *FilterURL) open() (err error) {
+	if f.Queue != nil {
+		if f.release, err = f.Queue(f.context()); err != nil {
+			f.err = err
+			return err
+		}
+	}
+	if f.Timeout > 0 {
+		f.Context, f.cancel = context.WithTimeout(f.context(), f.Timeout)
+	}
+	f.r, err = f.fetchVariants()
+	if err != nil {
+		err = f.timedOut(err)
+		f.done()
+		f.err = err
+	}
+	return err
 }
 
 func (f *FilterURL) Read(p []byte) (n int, err error) {
@@ -193,11 +645,36 @@ func (f *FilterURL) Read(p []byte) (n int, err error) {
 		return 0, f.err
 	}
 	if f.r == nil {
-		f.r, err = f.fetch()
-		if err != nil {
-			f.err = err
+		if err = f.open(); err != nil {
 			return 0, err
 		}
 	}
-	return f.r.Read(p)
+	n, err = f.r.Read(p)
+	f.offset += int64(n)
+	f.bytes += int64(n)
+	for err != nil && err != io.EOF && f.resumable() {
+		// Transient failure; reconnect and continue from the same offset.
+		f.resumes++
+		if f.resume() != nil {
+			break
+		}
+		if n > 0 {
+			return n, nil
+		}
+		n, err = f.r.Read(p)
+		f.offset += int64(n)
+		f.bytes += int64(n)
+	}
+	if err != nil {
+		err = f.timedOut(err)
+		f.done()
+	}
+	if err == io.EOF && f.finished.IsZero() {
+		f.finished = time.Now()
+	}
+	err = classify(ErrNetwork, err)
+	if err != nil && err != io.EOF {
+		f.failed = err
+	}
+	return n, err
 }
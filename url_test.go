@@ -0,0 +1,164 @@
+package rbxfetch_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/robloxapi/rbxfetch"
+	"github.com/robloxapi/rbxfetch/rbxfetchtest"
+)
+
+func TestFilterURLResume(t *testing.T) {
+	const name = "setup.rbxcdn.com/" + rbxfetchtest.GUID + "-API-Dump.json"
+	changed := strings.Replace(rbxfetchtest.APIDump, "Instance", "Changed!", 1)
+	tests := []struct {
+		name   string
+		resume int
+		change bool
+		// ranges lists the Range header of each request.
+		ranges []string
+		err    error
+	}{
+		{name: "resumed", resume: 1, ranges: []string{"", "bytes=100-"}},
+		{name: "not resumed", resume: 0, ranges: []string{""}, err: rbxfetch.ErrNetwork},
+		{name: "changed", resume: 1, change: true, ranges: []string{"", "bytes=100-"}, err: rbxfetch.ErrNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := rbxfetchtest.NewServer()
+			defer s.Close()
+			s.Interrupt(name, 100)
+			// etag is the validator of the interrupted response, and
+			// ifRange is that of the resumed request.
+			var ranges []string
+			var etag, ifRange string
+			f := &rbxfetch.FilterURL{
+				URL:    s.URL + "/" + name,
+				Client: s.Client(),
+				Resume: tt.resume,
+				Hooks: rbxfetch.RequestHooks{
+					BeforeRequest: func(req *http.Request) error {
+						ranges = append(ranges, req.Header.Get("Range"))
+						if len(ranges) == 2 {
+							ifRange = req.Header.Get("If-Range")
+							if tt.change {
+								s.Set(name, []byte(changed))
+							}
+						}
+						return nil
+					},
+					AfterResponse: func(req *http.Request, resp *http.Response, err error) {
+						if resp != nil && req.Header.Get("Range") == "" {
+							etag = resp.Header.Get("ETag")
+						}
+					},
+				},
+			}
+			b, err := io.ReadAll(f)
+			f.Close()
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if string(b) != rbxfetchtest.APIDump {
+				t.Fatalf("resumed content differs: got %d bytes", len(b))
+			}
+			if strings.Join(ranges, ",") != strings.Join(tt.ranges, ",") {
+				t.Fatalf("expected ranges %q, got %q", tt.ranges, ranges)
+			}
+			// A range is requested only if the content is that of the
+			// interrupted response.
+			if len(ranges) == 2 && (etag == "" || ifRange != etag) {
+				t.Errorf("expected If-Range %q, got %q", etag, ifRange)
+			}
+		})
+	}
+}
+
+func TestFilterURLVariants(t *testing.T) {
+	const (
+		file   = "setup.rbxcdn.com/version-a-API-Dump.json"
+		lower  = "setup.rbxcdn.com/version-a-api-dump.json"
+		common = "setup.rbxcdn.com/channel/common/version-a-API-Dump.json"
+	)
+	tests := []struct {
+		name     string
+		file     string
+		fail     int
+		requests []string
+		status   int
+	}{
+		{name: "url", file: file, requests: []string{file}},
+		{name: "variant", file: lower, requests: []string{file, lower}},
+		{name: "last variant", file: common, requests: []string{file, lower, common}},
+		{name: "missing", requests: []string{file, lower, common}, status: 404},
+		// Only missing files fall back to variants.
+		{name: "server error", file: lower, fail: 500, requests: []string{file}, status: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := rbxfetchtest.NewUnloadedServer()
+			defer s.Close()
+			if tt.file != "" {
+				s.Set(tt.file, []byte(rbxfetchtest.APIDump))
+			}
+			if tt.fail != 0 {
+				s.Fail(file, tt.fail)
+			}
+			f := &rbxfetch.FilterURL{
+				URL: s.URL + "/" + file,
+				// A variant that is the URL itself is skipped.
+				Variants: []string{s.URL + "/" + lower, s.URL + "/" + file, s.URL + "/" + common},
+				Client:   s.Client(),
+			}
+			b, err := io.ReadAll(f)
+			f.Close()
+			if tt.status != 0 {
+				if status, _ := rbxfetch.StatusCode(err); status != tt.status {
+					t.Fatalf("expected status %d, got %v", tt.status, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if string(b) != rbxfetchtest.APIDump {
+				t.Fatal("unexpected content")
+			}
+			if requests := s.Requests(); !reflect.DeepEqual(requests, tt.requests) {
+				t.Errorf("expected requests %q, got %q", tt.requests, requests)
+			}
+		})
+	}
+}
+
+func TestSetupVariants(t *testing.T) {
+	const guid = "version-00000000000000cc"
+	s := rbxfetchtest.NewUnloadedServer()
+	defer s.Close()
+	// Builds of old channels are found in the common directory.
+	s.Set("setup.rbxcdn.com/channel/common/"+guid+"-API-Dump.json", []byte(rbxfetchtest.APIDump))
+	client := rbxfetchtest.NewTestClient(s)
+	client.Channel = "zcanary"
+	client.SetMethod("APIDump", "APIDump")
+	rc, err := client.Method("APIDump", guid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"setup.rbxcdn.com/channel/zcanary/" + guid + "-API-Dump.json",
+		"setup.rbxcdn.com/channel/zcanary/" + guid + "-api-dump.json",
+		"setup.rbxcdn.com/channel/common/" + guid + "-API-Dump.json",
+	}
+	if requests := s.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %q, got %q", expected, requests)
+	}
+}
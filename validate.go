@@ -0,0 +1,254 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// ConfigError describes the problems found in a Config by Validate.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Kinds of parameter values.
+const (
+	paramString = "string"
+	paramNumber = "number"
+)
+
+// requiredParams lists the parameters required by each default filter, and
+// the kind of each parameter's value.
+var requiredParams = map[string]map[string]string{
+	"url":       {"URL": paramString},
+	"file":      {"Path": paramString},
+	"zip":       {"File": paramString},
+	"xmlpath":   {"Path": paramString},
+	"iconscan":  {"Size": paramNumber},
+	"archive":   {"Location": paramString, "Artifact": paramString},
+	"studio":    {"File": paramString},
+	"macstudio": {"File": paramString},
+}
+
+// sourcedFilters lists default filters that must have a source, and so cannot
+// be the first link of a chain.
+var sourcedFilters = map[string]bool{
+	"zip":      true,
+	"zipglob":  true,
+	"iconscan": true,
+	"xmlpath":  true,
+	"gzip":     true,
+}
+
+// Validate verifies that the configuration is well-formed, without performing
+// any I/O. It verifies that each method refers to defined chains, that each
+// link of a chain refers to a default filter, that the parameters required by
+// each filter are present, and that each URL parses. Returns a *ConfigError
+// describing every problem found.
+//
+// Filters registered in addition to the default filters are not known to
+// Validate; use Client.Validate to verify a configuration set on a client.
+func (config Config) Validate() error {
+	filters := map[string]bool{}
+	for _, def := range DefaultFilters() {
+		filters[def.Name] = true
+	}
+	return validateConfig(config, func(name string) bool { return filters[name] })
+}
+
+// Validate verifies the client's configuration, in the same manner as
+// Config.Validate, including the filters registered with the client.
+func (client *Client) Validate() error {
+	return validateConfig(client.Config(), client.hasFilter)
+}
+
+// validateConfig validates config, with known reporting whether a filter of
+// the given name is known.
+func validateConfig(config Config, known func(name string) bool) error {
+	var problems []string
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	for _, name := range sortedKeys(config.Methods) {
+		chains := config.Methods[name]
+		if len(chains) == 0 {
+			report("method %s: no chains", name)
+		}
+		for _, chain := range chains {
+			if _, ok := config.Chains[chain]; !ok {
+				report("method %s: unknown chain %q", name, chain)
+			}
+		}
+	}
+	for _, name := range sortedKeys(config.Chains) {
+		chain := config.Chains[name]
+		if len(chain) == 0 {
+			report("chain %s: no links", name)
+		}
+		for i, link := range chain {
+			if !known(link.Filter) {
+				report("%s[%d]: unknown filter %q", name, i, link.Filter)
+				continue
+			}
+			if i == 0 && sourcedFilters[link.Filter] {
+				report("%s[%d]%s: filter requires a source", name, i, link.Filter)
+			}
+			for _, param := range sortedKeys(requiredParams[link.Filter]) {
+				if problem := checkParam(link.Params, param, requiredParams[link.Filter][param]); problem != "" {
+					report("%s[%d]%s: %s", name, i, link.Filter, problem)
+				}
+			}
+			if _, err := getDuration(link.Params, "Timeout"); err != nil {
+				report("%s[%d]%s: %s", name, i, link.Filter, err)
+			}
+			if u, ok := link.Params["URL"].(string); ok {
+				if err := checkURL(u); err != nil {
+					report("%s[%d]%s: %s", name, i, link.Filter, err)
+				}
+			}
+			for _, u := range getStrings(link.Params, "Variants") {
+				if err := checkURL(u); err != nil {
+					report("%s[%d]%s: variant: %s", name, i, link.Filter, err)
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// methodConfig returns the part of config used by method, which is the method
+// and each of its chains.
+func (config Config) methodConfig(method string) Config {
+	sub := Config{
+		Methods: map[string][]string{},
+		Config:  iofl.Config{Chains: map[string]iofl.Chain{}},
+	}
+	chains, ok := config.Methods[method]
+	if !ok {
+		return sub
+	}
+	sub.Methods[method] = chains
+	for _, chain := range chains {
+		if c, ok := config.Chains[chain]; ok {
+			sub.Chains[chain] = c
+		}
+	}
+	return sub
+}
+
+// checkParam returns a description of the problem with the given parameter,
+// or an empty string if the parameter is a non-empty value of the given kind.
+func checkParam(params iofl.Params, name, kind string) string {
+	v, ok := params[name]
+	if !ok {
+		return fmt.Sprintf("missing parameter %s", name)
+	}
+	switch kind {
+	case paramString:
+		if s, ok := v.(string); !ok || s == "" {
+			return fmt.Sprintf("parameter %s must be a non-empty string", name)
+		}
+	case paramNumber:
+		if _, ok := v.(float64); !ok {
+			return fmt.Sprintf("parameter %s must be a number", name)
+		}
+	}
+	return ""
+}
+
+// checkURL verifies that u parses as an absolute URL, with each variable
+// replaced by a placeholder.
+func checkURL(u string) error {
+	p, err := url.Parse(os.Expand(u, func(string) string { return "x" }))
+	if err != nil {
+		return err
+	}
+	if p.Scheme == "" || p.Host == "" {
+		return fmt.Errorf("URL %q is not absolute", u)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m, which must be a map with string keys, in
+// sorted order.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	s := make([]string, len(keys))
+	for i, k := range keys {
+		s[i] = k.String()
+	}
+	sort.Strings(s)
+	return s
+}
+
+// PlannedChain describes a chain that would be attempted by a method.
+type PlannedChain struct {
+	ResolvedChain
+	// URLs lists the URLs that would be requested by the chain, in the order
+	// they would be attempted, including mirrors and variants, after being
+	// rewritten by the client's RewriteURL. Variants are requested only if
+	// the preceding URLs respond with 403 or 404.
+	URLs []string
+}
+
+// DryRun returns the chains of the given method, in the order they would be
+// attempted, with their parameters resolved for guid, along with the URLs each
+// chain would request. No network or cache I/O is performed. Returns an error
+// if the method or its chains fail validation, or a URL fails to be
+// rewritten. Problems with other methods and chains are not reported.
+func (client *Client) DryRun(method, guid string) (plan []PlannedChain, err error) {
+	if err := validateConfig(client.Config().methodConfig(method), client.hasFilter); err != nil {
+		return nil, err
+	}
+	chains, err := client.ResolveParams(method, guid, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, chain := range chains {
+		planned := PlannedChain{ResolvedChain: chain}
+		for _, link := range chain.Links {
+			if link.Filter != "url" {
+				continue
+			}
+			var variants []string
+			for _, variant := range append([]string{link.Params.GetString("URL")}, getStrings(link.Params, "Variants")...) {
+				if containsString(variants, variant) {
+					continue
+				}
+				variants = append(variants, variant)
+				// Only the URL falls back to mirrors.
+				candidates := []string{variant}
+				if len(variants) == 1 {
+					candidates = mirrorCandidates(variant, client.Mirrors)
+				}
+				for _, candidate := range candidates {
+					if client.RewriteURL != nil {
+						u, err := url.Parse(candidate)
+						if err != nil {
+							return nil, err
+						}
+						if err := client.RewriteURL(u); err != nil {
+							return nil, fmt.Errorf("chain %s: rewrite %s: %w", chain.Chain, candidate, err)
+						}
+						candidate = u.String()
+					}
+					planned.URLs = append(planned.URLs, candidate)
+				}
+			}
+		}
+		plan = append(plan, planned)
+	}
+	return plan, nil
+}
@@ -0,0 +1,97 @@
+package rbxfetch
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// ChannelLive is the name of the production deployment channel.
+const ChannelLive = "LIVE"
+
+// isLive returns whether channel refers to the production channel.
+func isLive(channel string) bool {
+	return channel == "" || strings.EqualFold(channel, ChannelLive)
+}
+
+// sameChannel returns whether a and b refer to the same channel.
+func sameChannel(a, b string) bool {
+	return isLive(a) && isLive(b) || strings.EqualFold(a, b)
+}
+
+// channelVars returns the variables that describe channel:
+//
+//   - CHANNEL: The name of the channel, or "LIVE".
+//   - CHANNELPATH: For non-production channels, "channel/<name>/", where name
+//     is lowercase. Empty otherwise.
+//   - CHANNELCOMMONPATH: For non-production channels, "channel/common/", the
+//     location of files shared by every non-production channel. Empty
+//     otherwise.
+//   - CHANNELSUFFIX: For non-production channels, "/channel/<name>". Empty
+//     otherwise.
+//   - CHANNELBUCKET: For non-production channels, "/bucket/<name>", which
+//     selects the fast flags of the channel. Empty otherwise.
+func channelVars(channel string, vars map[string]string) {
+	if isLive(channel) {
+		vars["CHANNEL"] = ChannelLive
+		vars["CHANNELPATH"] = ""
+		vars["CHANNELCOMMONPATH"] = ""
+		vars["CHANNELSUFFIX"] = ""
+		vars["CHANNELBUCKET"] = ""
+		return
+	}
+	name := strings.ToLower(channel)
+	vars["CHANNEL"] = channel
+	vars["CHANNELPATH"] = "channel/" + name + "/"
+	vars["CHANNELCOMMONPATH"] = "channel/common/"
+	vars["CHANNELSUFFIX"] = "/channel/" + name
+	vars["CHANNELBUCKET"] = "/bucket/" + name
+}
+
+// expandVars replaces $var or ${var} in s. The GUID variable expands to guid,
+// while other variables are looked up in vars. Variable names are
+// case-insensitive. Unknown variables expand to the empty string.
+func expandVars(s, guid string, vars map[string]string) string {
+	return os.Expand(s, func(v string) string {
+		v = strings.ToUpper(v)
+		if v == "GUID" {
+			return guid
+		}
+		return vars[v]
+	})
+}
+
+// vars returns the variables to be applied to the filters of a chain.
+func (client *Client) vars() map[string]string {
+	vars := map[string]string{}
+	channelVars(client.Channel, vars)
+	for name, value := range client.snapshot().vars {
+		vars[name] = value
+	}
+	return vars
+}
+
+// channel returns the name of the channel targeted by the client, as
+// overridden by vars.
+func (client *Client) channel(vars map[string]string) string {
+	if channel, ok := vars["CHANNEL"]; ok {
+		return channel
+	}
+	return client.vars()["CHANNEL"]
+}
+
+// applyVars applies vars to the chain of filters.
+func applyVars(filter iofl.Filter, vars map[string]string) {
+	type varser interface {
+		iofl.Filter
+		SetVars(vars map[string]string)
+	}
+	iofl.Apply(filter, func(f io.ReadCloser) error {
+		if f, ok := f.(varser); ok {
+			f.SetVars(vars)
+		}
+		return nil
+	})
+}
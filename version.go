@@ -0,0 +1,129 @@
+package rbxfetch
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseVersion parses a version from its string form, which is four decimal
+// components separated by either "." or ", ", such as "0.600.0.6000862" or
+// "0, 600, 0, 6000862". Surrounding spaces are ignored.
+//
+// The String method of a Version returns the components separated by ".",
+// without padding, so that ParseVersion(v.String()) always returns v.
+func ParseVersion(s string) (v Version, err error) {
+	components, err := parseVersionComponents(s)
+	if err != nil {
+		return v, err
+	}
+	if len(components) != 4 {
+		return v, fmt.Errorf("version %q: expected 4 components", s)
+	}
+	return versionOf(components, 0), nil
+}
+
+// parseVersionComponents parses a version of one to four components.
+func parseVersionComponents(s string) (components []int, err error) {
+	s = strings.TrimSpace(s)
+	sep := "."
+	if strings.Contains(s, ",") {
+		sep = ", "
+	}
+	fields := strings.Split(s, sep)
+	if len(fields) > 4 {
+		return nil, fmt.Errorf("version %q: too many components", s)
+	}
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 || field[0] == '+' {
+			return nil, fmt.Errorf("version %q: invalid component %q", s, field)
+		}
+		components = append(components, n)
+	}
+	return components, nil
+}
+
+// versionOf returns the version with the given components, with omitted
+// components set to fill.
+func versionOf(components []int, fill int) Version {
+	c := [4]int{fill, fill, fill, fill}
+	copy(c[:], components)
+	return Version{Major: c[0], Minor: c[1], Maint: c[2], Build: c[3]}
+}
+
+// CompareVersions returns -1 if a is less than b, 1 if a is greater than b,
+// and 0 if they are equal. Components are compared numerically, from most to
+// least significant.
+func CompareVersions(a, b Version) int {
+	return a.Compare(b)
+}
+
+// VersionLess returns whether a is less than b. It can be used to sort builds
+// by version:
+//
+//	sort.Slice(builds, func(i, j int) bool {
+//		return rbxfetch.VersionLess(builds[i].Version, builds[j].Version)
+//	})
+func VersionLess(a, b Version) bool {
+	return a.Compare(b) < 0
+}
+
+// VersionRange is an inclusive range of versions.
+type VersionRange struct {
+	Min, Max Version
+}
+
+// maxVersion is greater than or equal to every version.
+var maxVersion = Version{Major: math.MaxInt32, Minor: math.MaxInt32, Maint: math.MaxInt32, Build: math.MaxInt32}
+
+// ParseVersionRange parses a range of versions of the form "min-max", where
+// the bounds are separated by "-", "–", or "..". Either bound may be omitted,
+// leaving that side of the range unbounded. A bound may have fewer than four
+// components, in which case it matches every version beginning with those
+// components. For example, "0.590-0.600" contains every version from
+// 0.590.0.0 to 0.600.x.x, inclusive. A single version without a separator is
+// a range containing only the versions that begin with it.
+func ParseVersionRange(s string) (r VersionRange, err error) {
+	min, max := s, s
+	for _, sep := range []string{"..", "–", "-"} {
+		if i := strings.Index(s, sep); i >= 0 {
+			min, max = s[:i], s[i+len(sep):]
+			break
+		}
+	}
+	r.Max = maxVersion
+	if strings.TrimSpace(min) != "" {
+		components, err := parseVersionComponents(min)
+		if err != nil {
+			return VersionRange{}, fmt.Errorf("range %q: %w", s, err)
+		}
+		r.Min = versionOf(components, 0)
+	}
+	if strings.TrimSpace(max) != "" {
+		components, err := parseVersionComponents(max)
+		if err != nil {
+			return VersionRange{}, fmt.Errorf("range %q: %w", s, err)
+		}
+		r.Max = versionOf(components, math.MaxInt32)
+	}
+	return r, nil
+}
+
+// Contains returns whether v is within the range.
+func (r VersionRange) Contains(v Version) bool {
+	return r.Min.Compare(v) <= 0 && v.Compare(r.Max) <= 0
+}
+
+// BuildsInRange returns the builds whose version is within r, in the same
+// order.
+func BuildsInRange(builds []Build, r VersionRange) []Build {
+	var result []Build
+	for _, build := range builds {
+		if r.Contains(build.Version) {
+			result = append(result, build)
+		}
+	}
+	return result
+}
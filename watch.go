@@ -0,0 +1,286 @@
+package rbxfetch
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sources of builds polled by a Watcher.
+const (
+	WatchLatest = "Latest"
+	WatchLive   = "Live"
+	WatchBuilds = "Builds"
+)
+
+// DefaultWatchInterval is the interval of a Watcher that does not specify one.
+const DefaultWatchInterval = 5 * time.Minute
+
+// DefaultNotifyAttempts is the NotifyAttempts of a Watcher that does not
+// specify one.
+const DefaultNotifyAttempts = 10
+
+// WatchSource is the source of the builds polled by a Watcher. It is
+// implemented by Client and Scope.
+type WatchSource interface {
+	Latest() (guid string, err error)
+	Live() (guids []string, err error)
+	Builds() (builds []Build, err error)
+}
+
+// BuildEvent describes a build newly observed by a Watcher.
+type BuildEvent struct {
+	// Source is the source from which the build was first observed, such as
+	// WatchLive.
+	Source string
+	// Build is the observed build. Only the GUID is known for builds
+	// observed from WatchLatest or WatchLive.
+	Build Build
+	// Time is the time at which the build was observed.
+	Time time.Time
+}
+
+// Watcher polls a WatchSource for new builds, such as to notify when a new
+// version of Studio is deployed. Each GUID is reported at most once, in the
+// order the sources are polled. Errors do not stop the watcher; the failing
+// source is polled again on the next interval.
+type Watcher struct {
+	// Source is the source of builds.
+	Source WatchSource
+	// Sources lists the sources that are polled, in order. If empty, then
+	// WatchLive is polled.
+	Sources []string
+	// Interval is the duration between polls. If zero, then
+	// DefaultWatchInterval is used.
+	Interval time.Duration
+	// Jitter is the fraction of Interval by which each interval is randomly
+	// lengthened or shortened, so that many watchers do not poll in unison.
+	Jitter float64
+	// ReportExisting specifies whether the builds observed by the first
+	// successful poll of each source are reported. If false, then the first
+	// poll only records the existing builds, and only builds deployed
+	// afterwards are reported. Once Seen has been called, the first poll
+	// reports each build not marked as seen, regardless of ReportExisting.
+	ReportExisting bool
+	// Sinks are notified of each new build reported by Run, in order. A build
+	// that a sink fails to receive is passed to that sink again after each
+	// subsequent poll, until it succeeds, so that a failure does not lose the
+	// notification.
+	Sinks []Sink
+	// NotifyAttempts is the number of times a build is passed to a sink that
+	// fails to receive it, after which the notification is dropped, and an
+	// error is passed to OnError. If zero, then DefaultNotifyAttempts is used.
+	NotifyAttempts int
+	// OnError, if not nil, is called with each error that occurs while
+	// polling a source. Errors returned by Sinks are passed with a source of
+	// "sink".
+	OnError func(source string, err error)
+
+	mu   sync.Mutex
+	seen map[string]bool
+	// polled records each source that has been polled successfully.
+	polled map[string]bool
+	// resumed is whether Seen has been called.
+	resumed bool
+	// failed contains the notifications that failed, to be retried.
+	failed []failedNotify
+}
+
+// failedNotify is a build that was not received by some sinks.
+type failedNotify struct {
+	event BuildEvent
+	sinks []Sink
+	// attempts is the number of times the sinks have been notified.
+	attempts int
+}
+
+// NewWatcher returns a Watcher that polls the live builds of source every
+// DefaultWatchInterval, with 10% jitter.
+func NewWatcher(source WatchSource) *Watcher {
+	return &Watcher{
+		Source:   source,
+		Interval: DefaultWatchInterval,
+		Jitter:   0.1,
+	}
+}
+
+// Seen marks the given GUIDs as observed, so that they are not reported, such
+// as to resume watching with state persisted by a previous process. The first
+// poll then reports the builds that were deployed since, rather than only
+// recording them.
+func (w *Watcher) Seen(guids ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.seen == nil {
+		w.seen = map[string]bool{}
+		w.polled = map[string]bool{}
+	}
+	for _, guid := range guids {
+		w.seen[strings.ToLower(guid)] = true
+	}
+	w.resumed = true
+}
+
+// poll returns the builds currently reported by source.
+func (w *Watcher) poll(source string) (builds []Build, err error) {
+	switch source {
+	case WatchLatest:
+		guid, err := w.Source.Latest()
+		if err != nil || guid == "" {
+			return nil, err
+		}
+		return []Build{{GUID: guid}}, nil
+	case WatchLive:
+		guids, err := w.Source.Live()
+		for _, guid := range guids {
+			builds = append(builds, Build{GUID: guid})
+		}
+		return builds, err
+	case WatchBuilds:
+		return w.Source.Builds()
+	}
+	return nil, nil
+}
+
+// Poll polls each source once, returning the builds that have not been
+// observed before. The error of each source that fails is passed to OnError,
+// and the first such error is returned after the remaining sources have been
+// polled. Builds returned by a failing source are reported as usual, but
+// builds that would only be recorded are left to a later successful poll.
+func (w *Watcher) Poll() (events []BuildEvent, err error) {
+	sources := w.Sources
+	if len(sources) == 0 {
+		sources = []string{WatchLive}
+	}
+	now := time.Now()
+	for _, source := range sources {
+		builds, e := w.poll(source)
+		if e != nil {
+			if w.OnError != nil {
+				w.OnError(source, e)
+			}
+			if err == nil {
+				err = e
+			}
+		}
+		w.mu.Lock()
+		if w.seen == nil {
+			w.seen = map[string]bool{}
+			w.polled = map[string]bool{}
+		}
+		report := w.polled[source] || w.ReportExisting || w.resumed
+		if e == nil {
+			w.polled[source] = true
+		}
+		// The builds of a failing source may be incomplete, so they are
+		// recorded only if reported.
+		for _, build := range builds {
+			key := strings.ToLower(build.GUID)
+			if w.seen[key] || !report && e != nil {
+				continue
+			}
+			w.seen[key] = true
+			if report {
+				events = append(events, BuildEvent{Source: source, Build: build, Time: now})
+			}
+		}
+		w.mu.Unlock()
+	}
+	return events, err
+}
+
+// next returns the duration until the next poll.
+func (w *Watcher) next() time.Duration {
+	d := w.Interval
+	if d <= 0 {
+		d = DefaultWatchInterval
+	}
+	if w.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * w.Jitter * float64(d))
+	}
+	return d
+}
+
+// Run polls the sources immediately, and then every interval, notifying each
+// of Sinks, and then calling fn, if not nil, with each new build, until ctx is
+// done. Returns the error of ctx.
+func (w *Watcher) Run(ctx context.Context, fn func(event BuildEvent)) error {
+	for {
+		events, _ := w.Poll()
+		w.renotify(ctx)
+		for _, event := range events {
+			w.notify(ctx, event, w.Sinks, 1)
+			if fn != nil {
+				fn(event)
+			}
+		}
+		timer := time.NewTimer(w.next())
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// notify notifies each of sinks of event, which is attempt number attempts.
+// The sinks that fail are recorded to be notified again by renotify, until
+// NotifyAttempts is reached.
+func (w *Watcher) notify(ctx context.Context, event BuildEvent, sinks []Sink, attempts int) {
+	limit := w.NotifyAttempts
+	if limit <= 0 {
+		limit = DefaultNotifyAttempts
+	}
+	var failed []Sink
+	for _, sink := range sinks {
+		err := sink.Notify(ctx, event)
+		if err == nil {
+			continue
+		}
+		if attempts < limit {
+			failed = append(failed, sink)
+		} else {
+			err = fmt.Errorf("build %s: notification dropped after %d attempts: %w", event.Build.GUID, attempts, err)
+		}
+		if w.OnError != nil {
+			w.OnError("sink", err)
+		}
+	}
+	if len(failed) > 0 {
+		w.mu.Lock()
+		w.failed = append(w.failed, failedNotify{event: event, sinks: failed, attempts: attempts})
+		w.mu.Unlock()
+	}
+}
+
+// renotify notifies the sinks of each previously failed notification again,
+// in order.
+func (w *Watcher) renotify(ctx context.Context) {
+	w.mu.Lock()
+	failed := w.failed
+	w.failed = nil
+	w.mu.Unlock()
+	for _, f := range failed {
+		w.notify(ctx, f.event, f.sinks, f.attempts+1)
+	}
+}
+
+// Watch runs the watcher in a separate goroutine, returning a channel that
+// receives each new build. The channel is closed once ctx is done.
+func (w *Watcher) Watch(ctx context.Context) <-chan BuildEvent {
+	c := make(chan BuildEvent)
+	go func() {
+		defer close(c)
+		w.Run(ctx, func(event BuildEvent) {
+			select {
+			case c <- event:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return c
+}
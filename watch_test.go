@@ -0,0 +1,122 @@
+package rbxfetch_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/robloxapi/rbxfetch"
+)
+
+// poll is the result of polling a source once.
+type poll struct {
+	guids []string
+	err   error
+}
+
+// scriptedSource is a WatchSource that returns a scripted result for each
+// poll of each source.
+type scriptedSource struct {
+	live   []poll
+	builds []poll
+}
+
+// nextPoll removes and returns the first of polls, or an empty poll if there
+// are none.
+func nextPoll(polls *[]poll) poll {
+	if len(*polls) == 0 {
+		return poll{}
+	}
+	p := (*polls)[0]
+	*polls = (*polls)[1:]
+	return p
+}
+
+func (s *scriptedSource) Latest() (guid string, err error) {
+	return "", nil
+}
+
+func (s *scriptedSource) Live() (guids []string, err error) {
+	p := nextPoll(&s.live)
+	return p.guids, p.err
+}
+
+func (s *scriptedSource) Builds() (builds []rbxfetch.Build, err error) {
+	p := nextPoll(&s.builds)
+	for _, guid := range p.guids {
+		builds = append(builds, rbxfetch.Build{GUID: guid})
+	}
+	return builds, p.err
+}
+
+func TestWatcherPoll(t *testing.T) {
+	errPartial := errors.New("chain failed")
+	tests := []struct {
+		name     string
+		source   scriptedSource
+		sources  []string
+		existing bool
+		seen     []string
+		// events lists the "source:guid" events of each poll.
+		events []string
+	}{
+		{
+			name:   "baseline",
+			source: scriptedSource{live: []poll{{guids: []string{"a"}}, {guids: []string{"a", "b"}}}},
+			events: []string{"", "Live:b"},
+		},
+		{
+			name:     "existing",
+			source:   scriptedSource{live: []poll{{guids: []string{"a"}}, {guids: []string{"a", "b"}}}},
+			existing: true,
+			events:   []string{"Live:a", "Live:b"},
+		},
+		{
+			name:   "resumed",
+			source: scriptedSource{live: []poll{{guids: []string{"A", "b"}}, {guids: []string{"a", "b"}}}},
+			seen:   []string{"a"},
+			events: []string{"Live:b", ""},
+		},
+		{
+			name: "recovered",
+			source: scriptedSource{live: []poll{
+				{err: errPartial},
+				{guids: []string{"a"}},
+				{guids: []string{"a", "c"}},
+			}},
+			events: []string{"", "", "Live:c"},
+		},
+		{
+			// The builds of a failing source do not hide builds newly
+			// reported by another source.
+			name:    "failed source",
+			sources: []string{rbxfetch.WatchLive, rbxfetch.WatchBuilds},
+			source: scriptedSource{
+				live:   []poll{{guids: []string{"a"}}, {guids: []string{"a", "b"}}},
+				builds: []poll{{guids: []string{"a", "b"}, err: errPartial}, {guids: []string{"a", "b"}}},
+			},
+			events: []string{"", "Live:b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.source
+			w := rbxfetch.NewWatcher(&source)
+			w.Sources = tt.sources
+			w.ReportExisting = tt.existing
+			if tt.seen != nil {
+				w.Seen(tt.seen...)
+			}
+			for i, expected := range tt.events {
+				events, _ := w.Poll()
+				var got []string
+				for _, event := range events {
+					got = append(got, event.Source+":"+event.Build.GUID)
+				}
+				if strings.Join(got, ",") != expected {
+					t.Errorf("poll %d: expected events %q, got %q", i+1, expected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package rbxfetch
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ChainStats contains statistics observed for a chain when used by a method.
+type ChainStats struct {
+	// Successes is the number of fetches that succeeded.
+	Successes int
+	// Failures is the number of fetches that failed.
+	Failures int
+	// Latency is a moving average of the total duration of successful
+	// fetches, from the start of the request until the content has been
+	// entirely read. The chains of a method produce the same content, so
+	// their durations are comparable.
+	Latency time.Duration
+	// LastSuccess is the time of the most recent success.
+	LastSuccess time.Time
+}
+
+// score returns the adaptive multiplier of the stats. The success rate is
+// smoothed so that untried chains aren't penalized.
+func (s ChainStats) score() float64 {
+	rate := float64(s.Successes+1) / float64(s.Successes+s.Failures+2)
+	return rate / (1 + s.Latency.Seconds())
+}
+
+// stickyBonus is the multiplier applied to the chain of a method that most
+// recently succeeded.
+const stickyBonus = 1.5
+
+// latencyAlpha is the weight given to new samples in the latency average.
+const latencyAlpha = 0.3
+
+type chainStatSet struct {
+	mu     sync.Mutex
+	stats  map[string]map[string]*ChainStats
+	sticky map[string]string
+}
+
+func (s *chainStatSet) observe(method, chain string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = map[string]map[string]*ChainStats{}
+		s.sticky = map[string]string{}
+	}
+	m := s.stats[method]
+	if m == nil {
+		m = map[string]*ChainStats{}
+		s.stats[method] = m
+	}
+	c := m[chain]
+	if c == nil {
+		c = &ChainStats{}
+		m[chain] = c
+	}
+	if err != nil {
+		c.Failures++
+		if s.sticky[method] == chain {
+			delete(s.sticky, method)
+		}
+		return
+	}
+	if c.Successes == 0 {
+		c.Latency = latency
+	} else {
+		c.Latency = time.Duration(latencyAlpha*float64(latency) + (1-latencyAlpha)*float64(c.Latency))
+	}
+	c.Successes++
+	c.LastSuccess = time.Now()
+	s.sticky[method] = chain
+}
+
+// get returns a copy of the stats of method, and the chain that most recently
+// succeeded.
+func (s *chainStatSet) get(method string) (stats map[string]ChainStats, sticky string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats = make(map[string]ChainStats, len(s.stats[method]))
+	for chain, c := range s.stats[method] {
+		stats[chain] = *c
+	}
+	return stats, s.sticky[method]
+}
+
+func (s *chainStatSet) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = nil
+	s.sticky = nil
+}
+
+// ChainStats returns the statistics observed for each chain of the given
+// method. Statistics are only recorded when the client is Adaptive.
+func (client *Client) ChainStats(method string) map[string]ChainStats {
+	stats, _ := client.stats.get(method)
+	return stats
+}
+
+// ResetChainStats discards all observed chain statistics.
+func (client *Client) ResetChainStats() {
+	client.stats.reset()
+}
+
+// chains returns the chains of the given method in the order they should be
+// attempted. Chains are ordered by their configured weight and, when the client
+// is Adaptive, by their observed success rate and latency. The configured order
+// is retained between chains of equal score.
+func (client *Client) chains(method string) []string {
+	config := client.snapshot()
+	chains := config.methods[method]
+	if !client.Adaptive && len(config.weights) == 0 {
+		return chains
+	}
+
+	var stats map[string]ChainStats
+	var sticky string
+	if client.Adaptive {
+		stats, sticky = client.stats.get(method)
+	}
+	scores := make(map[string]float64, len(chains))
+	for _, chain := range chains {
+		score := 1.0
+		if w, ok := config.weights[chain]; ok {
+			score = w
+		}
+		if client.Adaptive {
+			score *= stats[chain].score()
+			if chain == sticky {
+				score *= stickyBonus
+			}
+		}
+		scores[chain] = score
+	}
+
+	ordered := make([]string, len(chains))
+	copy(ordered, chains)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return scores[ordered[i]] > scores[ordered[j]]
+	})
+	return ordered
+}
+
+// observe records the result of a fetch by chain for method, which started
+// at start and has completed.
+func (client *Client) observe(method, chain string, start time.Time, err error) {
+	if !client.Adaptive {
+		return
+	}
+	client.stats.observe(method, chain, time.Since(start), err)
+}
@@ -0,0 +1,171 @@
+package rbxfetch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// xmlStep is one element of a parsed xmlpath.
+type xmlStep struct {
+	name  string
+	attr  string
+	value string
+}
+
+func (s xmlStep) match(e xml.StartElement) bool {
+	if s.name != "*" && s.name != e.Name.Local {
+		return false
+	}
+	if s.attr == "" {
+		return true
+	}
+	for _, a := range e.Attr {
+		if a.Name.Local == s.attr {
+			return a.Value == s.value
+		}
+	}
+	return false
+}
+
+// parseXMLPath parses a path of the form "a/b[@attr=value]/*".
+func parseXMLPath(path string) (steps []xmlStep, err error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, fmt.Errorf("empty xml path")
+	}
+	for _, part := range strings.Split(path, "/") {
+		var step xmlStep
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			pred := part[i:]
+			part = part[:i]
+			if !strings.HasPrefix(pred, "[@") || !strings.HasSuffix(pred, "]") {
+				return nil, fmt.Errorf("malformed predicate %q", pred)
+			}
+			pred = pred[2 : len(pred)-1]
+			j := strings.IndexByte(pred, '=')
+			if j < 0 {
+				return nil, fmt.Errorf("malformed predicate %q", pred)
+			}
+			step.attr = pred[:j]
+			step.value = strings.Trim(pred[j+1:], `"'`)
+		}
+		if part == "" {
+			return nil, fmt.Errorf("empty element name in %q", path)
+		}
+		step.name = part
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// FilterXMLPath is an iofl.Filter that extracts an element from an XML source.
+//
+// Path selects the element as a slash-separated list of element names,
+// starting from the root element. A name of "*" matches any element. A name
+// may be followed by a predicate of the form [@attr=value], which matches only
+// elements that have the given attribute value. The first matching element is
+// produced, including its start and end tags, exactly as it appears in the
+// source.
+type FilterXMLPath struct {
+	Path string
+
+	r   io.ReadCloser
+	buf *bytes.Reader
+	err error
+}
+
+// NewFilterXMLPath is an iofl.NewFilter that returns a FilterXMLPath.
+func NewFilterXMLPath(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error) {
+	return &FilterXMLPath{r: r,
+		Path: params.GetString("Path"),
+	}, nil
+}
+
+func (f *FilterXMLPath) Source() io.ReadCloser {
+	return f.r
+}
+
+func (f *FilterXMLPath) Failure() error {
+	return failure(f.err)
+}
+
+func (f *FilterXMLPath) Close() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.err = f.r.Close(); f.err == nil {
+		f.err = iofl.Closed
+		return nil
+	}
+	return f.err
+}
+
+// extractXML returns the portion of b selected by steps.
+func extractXML(b []byte, steps []xmlStep) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	d.Strict = false
+	// matched is the number of leading steps matched by the current ancestry.
+	// depth is the depth of the current element.
+	var matched, depth int
+	var start int64
+	for {
+		offset := d.InputOffset()
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no element matches xml path")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if matched == depth-1 && matched < len(steps) && steps[matched].match(tok) {
+				matched++
+				if matched == len(steps) {
+					start = offset
+				}
+			}
+		case xml.EndElement:
+			if matched == len(steps) && depth == matched {
+				return b[start:d.InputOffset()], nil
+			}
+			if matched == depth {
+				matched--
+			}
+			depth--
+		}
+	}
+}
+
+func (f *FilterXMLPath) Read(p []byte) (n int, err error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.buf == nil {
+		steps, err := parseXMLPath(f.Path)
+		if err != nil {
+			f.err = err
+			f.r.Close()
+			return 0, err
+		}
+		b, err := io.ReadAll(f.r)
+		if err != nil {
+			f.err = err
+			f.r.Close()
+			return 0, err
+		}
+		if b, err = extractXML(b, steps); err != nil {
+			f.err = classify(ErrDecode, err)
+			f.r.Close()
+			return 0, f.err
+		}
+		f.buf = bytes.NewReader(b)
+	}
+	return f.buf.Read(p)
+}
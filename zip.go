@@ -2,10 +2,8 @@ package rbxfetch
 
 import (
 	"archive/zip"
-	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 
 	"github.com/anaminus/iofl"
 )
@@ -48,9 +46,16 @@ func (r *wrapZipCloser) Read(p []byte) (n int, err error) {
 	return r.zf.Read(p)
 }
 
-// FilterZip is an iofl.Filter that reads a file within a zip source.
+// FilterZip is an iofl.Filter that reads a file within a zip source. File may
+// contain variables such as $GUID.
+//
+// If the source does not support random access, then it is buffered entirely
+// within Memory, which may be nil.
 type FilterZip struct {
-	File string
+	File   string
+	GUID   string
+	Vars   map[string]string
+	Memory *MemoryBudget
 
 	r   io.ReadCloser
 	zr  io.ReadCloser
@@ -64,10 +69,26 @@ func NewFilterZip(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error
 	}, nil
 }
 
+func (f *FilterZip) SetGUID(guid string) {
+	f.GUID = guid
+}
+
+func (f *FilterZip) SetVars(vars map[string]string) {
+	f.Vars = vars
+}
+
+func (f *FilterZip) SetMemory(m *MemoryBudget) {
+	f.Memory = m
+}
+
 func (f *FilterZip) Source() io.ReadCloser {
 	return f.r
 }
 
+func (f *FilterZip) Failure() error {
+	return failure(f.err)
+}
+
 func (f *FilterZip) Close() error {
 	if f.err != nil {
 		return f.err
@@ -133,18 +154,17 @@ func (f *FilterZip) Read(p []byte) (n int, err error) {
 		case readAtSeekCloser:
 			rc = r
 		default:
-			b, err := ioutil.ReadAll(f.r)
+			rc, err = f.Memory.buffer(f.r)
 			f.r.Close()
 			if err != nil {
 				f.err = err
 				return 0, err
 			}
-			rc = nopCloser{bytes.NewReader(b)}
 		}
-		if f.zr, err = unzip(rc, f.File); err != nil {
-			f.err = err
+		if f.zr, err = unzip(rc, expandVars(f.File, f.GUID, f.Vars)); err != nil {
+			f.err = classify(ErrDecode, err)
 			f.r.Close()
-			return 0, err
+			return 0, f.err
 		}
 	}
 	return f.zr.Read(p)
@@ -0,0 +1,224 @@
+package rbxfetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/anaminus/iofl"
+)
+
+// getStrings returns the value of key as a list of strings. The value may be a
+// single string, or a list of strings.
+func getStrings(params iofl.Params, key string) []string {
+	switch v := params[key].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		s := make([]string, 0, len(v))
+		for _, v := range v {
+			if v, ok := v.(string); ok {
+				s = append(s, v)
+			}
+		}
+		return s
+	}
+	return nil
+}
+
+// compileGlob converts a glob pattern to a case-insensitive regular expression.
+// "**" matches any sequence of characters, "*" matches any sequence of
+// characters excluding "/", and "?" matches any single character excluding
+// "/".
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// FilterZipGlob is an iofl.Filter that selects files within a zip source,
+// producing a zip archive containing only the selected files.
+//
+// Pattern is a glob pattern, or list of patterns, that selects files by their
+// path within the archive. Matching is case-insensitive. "**" matches any
+// sequence of characters, "*" matches any sequence of characters excluding "/",
+// and "?" matches any single character excluding "/". Returns an error if no
+// files are selected.
+//
+// If the source does not support random access, then it is buffered entirely
+// within Memory, which may be nil.
+type FilterZipGlob struct {
+	Pattern []string
+	Memory  *MemoryBudget
+
+	r   io.ReadCloser
+	buf *bytes.Reader
+	err error
+}
+
+// NewFilterZipGlob is an iofl.NewFilter that returns a FilterZipGlob.
+func NewFilterZipGlob(params iofl.Params, r io.ReadCloser) (f iofl.Filter, err error) {
+	return &FilterZipGlob{r: r,
+		Pattern: getStrings(params, "Pattern"),
+	}, nil
+}
+
+func (f *FilterZipGlob) SetMemory(m *MemoryBudget) {
+	f.Memory = m
+}
+
+func (f *FilterZipGlob) Source() io.ReadCloser {
+	return f.r
+}
+
+func (f *FilterZipGlob) Failure() error {
+	return failure(f.err)
+}
+
+func (f *FilterZipGlob) Close() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.err = f.r.Close(); f.err == nil {
+		f.err = iofl.Closed
+		return nil
+	}
+	return f.err
+}
+
+// selectZip writes to w each file of r matching one of patterns.
+func selectZip(w io.Writer, r readAtSeeker, patterns []*regexp.Regexp) error {
+	zw := zip.NewWriter(w)
+	n, err := copyZip(zw, r, patterns)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no files in archive match %q", patterns)
+	}
+	return zw.Close()
+}
+
+// copyZip adds to zw each file of r matching one of patterns, or every file if
+// patterns is nil. Returns the number of files added.
+func copyZip(zw *zip.Writer, r readAtSeeker, patterns []*regexp.Regexp) (n int, err error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return 0, err
+	}
+	for _, zf := range zr.File {
+		ok := patterns == nil
+		for _, p := range patterns {
+			if p.MatchString(zf.Name) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		// Copy the compressed data as-is.
+		raw, err := zf.OpenRaw()
+		if err != nil {
+			return n, err
+		}
+		header := zf.FileHeader
+		dw, err := zw.CreateRaw(&header)
+		if err != nil {
+			return n, err
+		}
+		if _, err = io.Copy(dw, raw); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// mergeZips returns a zip archive containing every file of each of archives,
+// in order. Each archive is buffered within memory, which may be nil.
+func mergeZips(archives []io.ReadCloser, memory *MemoryBudget) (rc io.ReadCloser, err error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, archive := range archives {
+		r, err := memory.buffer(archive)
+		if err != nil {
+			return nil, err
+		}
+		_, err = copyZip(zw, r, nil)
+		r.Close()
+		if err != nil {
+			return nil, classify(ErrDecode, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+}
+
+func (f *FilterZipGlob) Read(p []byte) (n int, err error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.buf == nil {
+		patterns := make([]*regexp.Regexp, len(f.Pattern))
+		for i, pattern := range f.Pattern {
+			if patterns[i], err = compileGlob(pattern); err != nil {
+				f.err = err
+				f.r.Close()
+				return 0, err
+			}
+		}
+		var src readAtSeeker
+		switch r := f.r.(type) {
+		case readAtSeeker:
+			src = r
+		default:
+			rc, err := f.Memory.buffer(f.r)
+			if err != nil {
+				f.err = err
+				f.r.Close()
+				return 0, err
+			}
+			defer rc.Close()
+			src = rc
+		}
+		var buf bytes.Buffer
+		if err := selectZip(&buf, src, patterns); err != nil {
+			f.err = classify(ErrDecode, err)
+			f.r.Close()
+			return 0, f.err
+		}
+		f.buf = bytes.NewReader(buf.Bytes())
+	}
+	return f.buf.Read(p)
+}